@@ -0,0 +1,63 @@
+package io
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// CrdExists 通过 discovery 查询集群是否注册了该资源，group/version 不存在时返回 false
+func (c *k8sClient) CrdExists(group, version, resource string) (bool, error) {
+	resources, err := c.clientSet.Discovery().ServerResourcesForGroupVersion(fmt.Sprintf("%s/%s", group, version))
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// VolcanoQueueGet queue 是集群级别的资源
+func (c *k8sClient) VolcanoQueueGet(name string) (*unstructured.Unstructured, error) {
+	return c.dynamic.Resource(model.VolcanoQueueGVR).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// VolcanoPodGroupApply 不存在时创建，存在时替换 spec，PodGroup 不能在 pod 运行中修改 minMember，需要重建集群
+func (c *k8sClient) VolcanoPodGroupApply(yaml map[string]any) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{Object: yaml}
+	if obj.GetName() == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+	client := c.dynamic.Resource(model.VolcanoPodGroupGVR).Namespace(namespace)
+	existing, err := client.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return client.Create(context.TODO(), obj, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	existing.Object["spec"] = yaml["spec"]
+	return client.Update(context.TODO(), existing, metav1.UpdateOptions{})
+}
+
+func (c *k8sClient) VolcanoPodGroupDelete(namespace, name string) error {
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+	return c.dynamic.Resource(model.VolcanoPodGroupGVR).Namespace(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
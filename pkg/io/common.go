@@ -18,6 +18,7 @@ type k8sClient struct {
 	clusterInfo model.ClusterInfo
 	clientSet   *kubernetes.Clientset
 	dynamic     dynamic.Interface
+	config      *rest.Config // exec 等需要升级连接的请求使用
 }
 
 // kubePath or kubeConfig(base64 kubeconfig), kubePath > kubeConfig if both exist
@@ -62,6 +63,7 @@ func NewK8SClient(cfg model.Cluster) (model.K8SIO, error) {
 	return &k8sClient{
 		clientSet: clientset,
 		dynamic:   dynamicClient,
+		config:    config,
 		clusterInfo: model.ClusterInfo{
 			Name:  cfg.Name,
 			Alias: cfg.Alias,
@@ -2,11 +2,13 @@ package io
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func (c *k8sClient) CrdFlinkDeploymentList(filter model.Filter) (*unstructured.UnstructuredList, error) {
@@ -38,6 +40,27 @@ func (c *k8sClient) CrdFlinkDeploymentApply(yaml map[string]any) (any, error) {
 	return result, nil
 }
 
+func (c *k8sClient) CrdFlinkDeploymentGet(namespace, name string) (*unstructured.Unstructured, error) {
+	flinkDeploymentRes := GetGVR("flink.apache.org", "v1beta1", "flinkdeployments")
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+	return c.dynamic.Resource(flinkDeploymentRes).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// CrdFlinkDeploymentPatch 使用 merge patch 更新 FlinkDeployment，只修改 patch 中的字段
+func (c *k8sClient) CrdFlinkDeploymentPatch(namespace, name string, patch map[string]any) (*unstructured.Unstructured, error) {
+	flinkDeploymentRes := GetGVR("flink.apache.org", "v1beta1", "flinkdeployments")
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	return c.dynamic.Resource(flinkDeploymentRes).Namespace(namespace).Patch(context.TODO(), name, types.MergePatchType, data, metav1.PatchOptions{})
+}
+
 func (c *k8sClient) CrdFlinkDeploymentDelete(namespace, name string) error {
 	flinkDeploymentRes := GetGVR("flink.apache.org", "v1beta1", "flinkdeployments")
 	if namespace == "" {
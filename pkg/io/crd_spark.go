@@ -2,12 +2,15 @@ package io
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
 	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func (c *k8sClient) CrdSparkApplicationList(filter model.Filter) (*unstructured.UnstructuredList, error) {
@@ -53,3 +56,79 @@ func (c *k8sClient) CrdSparkApplicationDelete(namespace, name string) error {
 	}
 	return nil
 }
+
+func (c *k8sClient) CrdScheduledSparkApplicationList(filter model.Filter) (*unstructured.UnstructuredList, error) {
+	scheduledRes := GetGVR("sparkoperator.k8s.io", "v1beta2", "scheduledsparkapplications")
+	namespace := apiv1.NamespaceDefault
+	if filter.NameSpace != nil {
+		namespace = *filter.NameSpace
+	}
+	return c.dynamic.Resource(scheduledRes).Namespace(namespace).List(context.TODO(), filter.ToOptions())
+}
+
+func (c *k8sClient) CrdScheduledSparkApplicationGet(namespace, name string) (*unstructured.Unstructured, error) {
+	scheduledRes := GetGVR("sparkoperator.k8s.io", "v1beta2", "scheduledsparkapplications")
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+	return c.dynamic.Resource(scheduledRes).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// CrdScheduledSparkApplicationApply 不存在时创建，存在时整体替换 spec（spec 中没有 suspend 时保留当前值），保留 status
+func (c *k8sClient) CrdScheduledSparkApplicationApply(yaml map[string]any) (*unstructured.Unstructured, error) {
+	scheduledRes := GetGVR("sparkoperator.k8s.io", "v1beta2", "scheduledsparkapplications")
+	obj := &unstructured.Unstructured{Object: yaml}
+	if obj.GetName() == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+	client := c.dynamic.Resource(scheduledRes).Namespace(namespace)
+	existing, err := client.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return client.Create(context.TODO(), obj, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	spec, _ := yaml["spec"].(map[string]any)
+	// 请求中没有 suspend 时保留当前值，避免更新模板时恢复已经暂停的调度
+	if _, ok := spec["suspend"]; !ok && spec != nil {
+		if suspend, found, _ := unstructured.NestedBool(existing.Object, "spec", "suspend"); found {
+			spec["suspend"] = suspend
+		}
+	}
+	existing.Object["spec"] = yaml["spec"]
+	labels := existing.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range obj.GetLabels() {
+		labels[k] = v
+	}
+	existing.SetLabels(labels)
+	return client.Update(context.TODO(), existing, metav1.UpdateOptions{})
+}
+
+// CrdScheduledSparkApplicationPatch 使用 merge patch 更新，只修改 patch 中的字段
+func (c *k8sClient) CrdScheduledSparkApplicationPatch(namespace, name string, patch map[string]any) (*unstructured.Unstructured, error) {
+	scheduledRes := GetGVR("sparkoperator.k8s.io", "v1beta2", "scheduledsparkapplications")
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	return c.dynamic.Resource(scheduledRes).Namespace(namespace).Patch(context.TODO(), name, types.MergePatchType, data, metav1.PatchOptions{})
+}
+
+func (c *k8sClient) CrdScheduledSparkApplicationDelete(namespace, name string) error {
+	scheduledRes := GetGVR("sparkoperator.k8s.io", "v1beta2", "scheduledsparkapplications")
+	if namespace == "" {
+		namespace = apiv1.NamespaceDefault
+	}
+	return c.dynamic.Resource(scheduledRes).Namespace(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
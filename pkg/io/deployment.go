@@ -60,21 +60,25 @@ func (c *k8sClient) DeploymentDelete(namespace, name string) error {
 	return nil
 }
 
-// func (c *k8sClient) DeploymentGet(namespace, name string) (*appv1.Deployment, error) {
-// 	result, err := c.clientSet.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return result, nil
-// }
+func (c *k8sClient) DeploymentGet(namespace, name string) (*appv1.Deployment, error) {
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+	result, err := c.clientSet.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-// func (c *k8sClient) DeploymentUpdate(dep *appv1.Deployment) (any, error) {
-// 	result, err := c.clientSet.AppsV1().Deployments(dep.Namespace).Update(context.TODO(), dep, metav1.UpdateOptions{})
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return result, nil
-// }
+// DeploymentUpdate 需要带上查询到的 resourceVersion，冲突时返回 Conflict 错误
+func (c *k8sClient) DeploymentUpdate(dep *appv1.Deployment) (*appv1.Deployment, error) {
+	result, err := c.clientSet.AppsV1().Deployments(dep.Namespace).Update(context.TODO(), dep, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
 // func (c *k8sClient) DeploymentPatch(namespace, name string, patch []byte) (any, error) {
 // 	result, err := c.clientSet.AppsV1().Deployments(namespace).Patch(context.TODO(), name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
@@ -0,0 +1,48 @@
+package io
+
+import (
+	"context"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (c *k8sClient) IngressList(filter model.Filter) (*networkingv1.IngressList, error) {
+	var namespace string
+	if filter.NameSpace != nil {
+		namespace = *filter.NameSpace
+	} else {
+		namespace = v1.NamespaceDefault
+	}
+	result, err := c.clientSet.NetworkingV1().Ingresses(namespace).List(context.TODO(), filter.ToOptions())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *k8sClient) IngressApply(req model.ApplyIngressRequest) (*networkingv1.Ingress, error) {
+	if req.Namespace == nil {
+		req.Namespace = tea.String(v1.NamespaceDefault)
+	}
+	ingress, err := req.NewIngress()
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.clientSet.NetworkingV1().Ingresses(*req.Namespace).Apply(context.TODO(), ingress, req.ToOptions())
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *k8sClient) IngressDelete(namespace, name string) error {
+	err := c.clientSet.NetworkingV1().Ingresses(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -392,9 +392,7 @@ func TestDeploymentCreate(t *testing.T) {
 		},
 		FlinkConfigRequest: map[string]any{"taskmanager.numberOfTaskSlots": 2},
 	}
-	jobDy := req.NewJobManagerDeployment()
-	// fmt.Println(tea.Prettify(dy))
-	createDeploymentRequest, err := model.NewDeploymentCreateFromMap(jobDy)
+	createDeploymentRequest, err := req.NewJobManagerDeployment()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -2,11 +2,16 @@ package io
 
 import (
 	"context"
+	goio "io"
+	"net/http"
 
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
 	apiv1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
 )
 
 func (c *k8sClient) PodList(filter model.Filter) (*v1.PodList, error) {
@@ -22,3 +27,47 @@ func (c *k8sClient) PodList(filter model.Filter) (*v1.PodList, error) {
 func (c *k8sClient) PodGet(namespace, podName string) (*v1.Pod, error) {
 	return c.clientSet.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
 }
+
+// PodLogs 返回日志流，follow 时直到调用方关闭或者容器退出才结束
+func (c *k8sClient) PodLogs(namespace, podName string, opts model.PodLogOptions) (goio.ReadCloser, error) {
+	return c.clientSet.CoreV1().Pods(namespace).GetLogs(podName, opts.ToOptions()).Stream(context.TODO())
+}
+
+// PodExec 优先使用 WebSocket，apiserver 不支持（1.29 及之前默认关闭）时回退到 SPDY
+// 命令退出码非 0 时返回 k8s.io/client-go/util/exec.CodeExitError
+func (c *k8sClient) PodExec(namespace, podName string, opts model.PodExecOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	url := c.clientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(opts.ToOptions(), scheme.ParameterCodec).
+		URL()
+	websocketExec, err := remotecommand.NewWebSocketExecutor(c.config, http.MethodGet, url.String())
+	if err != nil {
+		return err
+	}
+	spdyExec, err := remotecommand.NewSPDYExecutor(c.config, http.MethodPost, url)
+	if err != nil {
+		return err
+	}
+	exec, err := remotecommand.NewFallbackExecutor(websocketExec, spdyExec, httpstream.IsUpgradeFailure)
+	if err != nil {
+		return err
+	}
+	ctx := context.TODO()
+	if opts.Timeout != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *opts.Timeout)
+		defer cancel()
+	}
+	return exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Stderr: opts.Stderr,
+		Tty:    opts.TTY,
+	})
+}
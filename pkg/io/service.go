@@ -2,6 +2,8 @@ package io
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
@@ -46,3 +48,31 @@ func (io *k8sClient) ServiceDelete(namespace, name string) error {
 	}
 	return nil
 }
+
+// ServiceProxy 通过 apiserver 的 service proxy 访问集群内服务，不依赖 LB 或者 NodePort
+func (io *k8sClient) ServiceProxy(req model.ServiceProxyRequest) ([]byte, error) {
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = v1.NamespaceDefault
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	request := io.clientSet.CoreV1().RESTClient().Verb(method).
+		Namespace(namespace).
+		Resource("services").
+		Name(fmt.Sprintf("%s:%s", req.Name, req.Port)).
+		SubResource("proxy").
+		Suffix(req.Path)
+	for k, v := range req.Params {
+		request = request.Param(k, v)
+	}
+	if req.Body != nil {
+		request = request.Body(req.Body)
+		if req.ContentType != "" {
+			request = request.SetHeader("Content-Type", req.ContentType)
+		}
+	}
+	return request.DoRaw(context.TODO())
+}
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/spf13/cast"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Spark 和 Flink 共用的批调度配置，Spark 由 operator 的 batchScheduler 处理，Flink 通过 pod 模板的 schedulerName 和注解接入
+
+const (
+	BatchSchedulerVolcano  = "volcano"
+	BatchSchedulerYuniKorn = "yunikorn"
+
+	VolcanoPodGroupName = "%s-podgroup" // Flink 集群 gang 调度使用的 PodGroup
+
+	volcanoQueueAnnotation          = "scheduling.volcano.sh/queue-name"
+	volcanoGroupNameAnnotation      = "scheduling.k8s.io/group-name"
+	yunikornQueueAnnotation         = "yunikorn.apache.org/queue"
+	yunikornAppIDAnnotation         = "yunikorn.apache.org/app-id"
+	yunikornTaskGroupNameAnnotation = "yunikorn.apache.org/task-group-name"
+	yunikornTaskGroupsAnnotation    = "yunikorn.apache.org/task-groups"
+)
+
+var (
+	VolcanoQueueGVR    = schema.GroupVersionResource{Group: "scheduling.volcano.sh", Version: "v1beta1", Resource: "queues"}
+	VolcanoPodGroupGVR = schema.GroupVersionResource{Group: "scheduling.volcano.sh", Version: "v1beta1", Resource: "podgroups"}
+)
+
+type BatchScheduler struct {
+	Name              *string         `json:"name" binding:"required"` // volcano、yunikorn
+	Queue             *string         `json:"queue"`                   // volcano 的 Queue 或者 yunikorn 的 root.xxx 队列
+	PriorityClassName *string         `json:"priority_class_name"`     // JM/TM 单独设置的 priority_class_name 优先
+	Gang              *GangScheduling `json:"gang"`                    // 为 nil 时不开启 gang 调度
+}
+
+type GangScheduling struct {
+	MinMember    *int32            `json:"min_member"`    // 只对 Flink 生效，默认 1 个 JM 加上 job.parallelism / taskmanager.numberOfTaskSlots 个 TM
+	MinResources map[string]string `json:"min_resources"` // volcano 最少需要的资源，比如 {"cpu": "4", "memory": "8Gi"}，Spark 为空时由 operator 按 driver 和 executor 计算
+}
+
+func (b *BatchScheduler) Validate() error {
+	if b == nil {
+		return nil
+	}
+	name := tea.StringValue(b.Name)
+	if name != BatchSchedulerVolcano && name != BatchSchedulerYuniKorn {
+		return fmt.Errorf("batch_scheduler.name must be %s or %s", BatchSchedulerVolcano, BatchSchedulerYuniKorn)
+	}
+	if b.Queue != nil && *b.Queue == "" {
+		return fmt.Errorf("batch_scheduler.queue must not be empty")
+	}
+	if b.PriorityClassName != nil && *b.PriorityClassName == "" {
+		return fmt.Errorf("batch_scheduler.priority_class_name must not be empty")
+	}
+	if b.Gang != nil {
+		if b.Gang.MinMember != nil && *b.Gang.MinMember < 1 {
+			return fmt.Errorf("batch_scheduler.gang.min_member must be at least 1")
+		}
+		if len(b.Gang.MinResources) > 0 && name != BatchSchedulerVolcano {
+			return fmt.Errorf("batch_scheduler.gang.min_resources is only supported by %s", BatchSchedulerVolcano)
+		}
+		for k, v := range b.Gang.MinResources {
+			if _, err := resource.ParseQuantity(v); err != nil {
+				return fmt.Errorf("batch_scheduler.gang.min_resources.%s: %v", k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RequiredCRDs 调度器需要安装的 CRD，yunikorn 不依赖 CRD
+func (b *BatchScheduler) RequiredCRDs() []schema.GroupVersionResource {
+	if b != nil && tea.StringValue(b.Name) == BatchSchedulerVolcano {
+		return []schema.GroupVersionResource{VolcanoQueueGVR, VolcanoPodGroupGVR}
+	}
+	return nil
+}
+
+// applyToSparkYaml 生成 spec.batchScheduler 和 spec.batchSchedulerOptions
+func (b *BatchScheduler) applyToSparkYaml(spec map[string]any) {
+	spec["batchScheduler"] = tea.StringValue(b.Name)
+	options := map[string]any{}
+	if b.Queue != nil {
+		options["queue"] = *b.Queue
+	}
+	if b.PriorityClassName != nil {
+		options["priorityClassName"] = *b.PriorityClassName
+	}
+	if b.Gang != nil && len(b.Gang.MinResources) > 0 {
+		options["resources"] = b.Gang.MinResources
+	}
+	if len(options) > 0 {
+		spec["batchSchedulerOptions"] = options
+	}
+}
+
+// flinkMinMember gang 调度最少同时启动的 pod 数，session 集群没有 job 时只能通过 min_member 指定
+func (req *CreateFlinkClusterRequest) flinkMinMember() (int32, error) {
+	gang := req.BatchScheduler.Gang
+	if gang.MinMember != nil {
+		return *gang.MinMember, nil
+	}
+	if req.Job == nil || req.Job.Parallelism == nil {
+		return 0, fmt.Errorf("batch_scheduler.gang.min_member is required when job.parallelism is not set")
+	}
+	slots := int32(2)
+	if raw, ok := req.FlinkConfiguration["taskmanager.numberOfTaskSlots"]; ok {
+		if s := cast.ToInt32(raw); s > 0 {
+			slots = s
+		}
+	}
+	return 1 + (*req.Job.Parallelism+slots-1)/slots, nil
+}
+
+// NewVolcanoPodGroup volcano 开启 gang 调度时 JM 和 TM 共用的 PodGroup，其他情况返回 nil
+func (req *CreateFlinkClusterRequest) NewVolcanoPodGroup() map[string]any {
+	b := req.BatchScheduler
+	if b == nil || tea.StringValue(b.Name) != BatchSchedulerVolcano || b.Gang == nil {
+		return nil
+	}
+	minMember, err := req.flinkMinMember()
+	if err != nil {
+		return nil
+	}
+	spec := map[string]any{"minMember": minMember}
+	if b.Queue != nil {
+		spec["queue"] = *b.Queue
+	}
+	if b.PriorityClassName != nil {
+		spec["priorityClassName"] = *b.PriorityClassName
+	}
+	if len(b.Gang.MinResources) > 0 {
+		spec["minResources"] = b.Gang.MinResources
+	}
+	namespace := "default"
+	if req.NameSpace != nil {
+		namespace = *req.NameSpace
+	}
+	return map[string]any{
+		"apiVersion": VolcanoPodGroupGVR.GroupVersion().String(),
+		"kind":       "PodGroup",
+		"metadata": map[string]any{
+			"name":      fmt.Sprintf(VolcanoPodGroupName, tea.StringValue(req.ClusterName)),
+			"namespace": namespace,
+			"labels":    map[string]any{"sdk": "multi-k8s-client", "app": tea.StringValue(req.ClusterName)},
+		},
+		"spec": spec,
+	}
+}
+
+// yunikornTaskGroups JM 和 TM 两个 task group，资源使用请求中的 JM/TM resource，没有设置时使用默认值
+func (req *CreateFlinkClusterRequest) yunikornTaskGroups() (string, error) {
+	minMember, err := req.flinkMinMember()
+	if err != nil {
+		return "", err
+	}
+	minResource := func(m *Manager) map[string]string {
+		res := map[string]string{"cpu": "100m", "memory": "2048Mi"}
+		if m != nil && m.Resource != nil {
+			if m.Resource.CPU != nil {
+				res["cpu"] = *m.Resource.CPU
+			}
+			if m.Resource.Memory != nil {
+				res["memory"] = *m.Resource.Memory
+			}
+		}
+		return res
+	}
+	groups := []map[string]any{
+		{"name": "jobmanager", "minMember": 1, "minResource": minResource(req.JobManager)},
+	}
+	if minMember > 1 {
+		groups = append(groups, map[string]any{"name": "taskmanager", "minMember": minMember - 1, "minResource": minResource(req.TaskManager)})
+	}
+	data, err := json.Marshal(groups)
+	return string(data), err
+}
+
+// applyBatchScheduler 设置 schedulerName、队列和 gang 调度注解，role 为 jobmanager 或 taskmanager
+func (req *CreateFlinkClusterRequest) applyBatchScheduler(podTemplate map[string]any, role string) {
+	b := req.BatchScheduler
+	metadata := childMap(podTemplate, "metadata")
+	spec := childMap(podTemplate, "spec")
+	annotations := childMap(metadata, "annotations")
+	spec["schedulerName"] = tea.StringValue(b.Name)
+	if _, ok := spec["priorityClassName"]; !ok && b.PriorityClassName != nil {
+		spec["priorityClassName"] = *b.PriorityClassName
+	}
+	switch tea.StringValue(b.Name) {
+	case BatchSchedulerVolcano:
+		if b.Queue != nil {
+			annotations[volcanoQueueAnnotation] = *b.Queue
+		}
+		if b.Gang != nil {
+			annotations[volcanoGroupNameAnnotation] = fmt.Sprintf(VolcanoPodGroupName, tea.StringValue(req.ClusterName))
+		}
+	case BatchSchedulerYuniKorn:
+		// JM 和 TM 属于同一个 application
+		annotations[yunikornAppIDAnnotation] = tea.StringValue(req.ClusterName)
+		if b.Queue != nil {
+			annotations[yunikornQueueAnnotation] = *b.Queue
+		}
+		if b.Gang != nil {
+			if taskGroups, err := req.yunikornTaskGroups(); err == nil {
+				annotations[yunikornTaskGroupNameAnnotation] = role
+				annotations[yunikornTaskGroupsAnnotation] = taskGroups
+			}
+		}
+	}
+}
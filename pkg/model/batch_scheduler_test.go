@@ -0,0 +1,111 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestSparkBatchScheduler(t *testing.T) {
+	req := model.CreateSparkApplicationRequest{
+		Name: tea.String("spark-etl"),
+		BatchScheduler: &model.BatchScheduler{
+			Name:              tea.String(model.BatchSchedulerVolcano),
+			Queue:             tea.String("etl"),
+			PriorityClassName: tea.String("batch-high"),
+			Gang:              &model.GangScheduling{MinResources: map[string]string{"cpu": "4", "memory": "8Gi"}},
+		},
+	}
+	assert.NoError(t, req.Validate())
+	spec := req.ToYaml()["spec"].(map[string]any)
+	assert.Equal(t, "volcano", spec["batchScheduler"])
+	assert.Equal(t, map[string]any{
+		"queue":             "etl",
+		"priorityClassName": "batch-high",
+		"resources":         map[string]string{"cpu": "4", "memory": "8Gi"},
+	}, spec["batchSchedulerOptions"])
+
+	req.BatchScheduler = &model.BatchScheduler{Name: tea.String(model.BatchSchedulerYuniKorn)}
+	spec = req.ToYaml()["spec"].(map[string]any)
+	assert.Equal(t, "yunikorn", spec["batchScheduler"])
+	assert.NotContains(t, spec, "batchSchedulerOptions")
+
+	req.BatchScheduler.Gang = &model.GangScheduling{MinMember: tea.Int32(3), MinResources: map[string]string{"cpu": "4"}}
+	err := req.Validate()
+	assert.ErrorContains(t, err, "batch_scheduler.gang.min_resources is only supported by volcano")
+	assert.ErrorContains(t, err, "batch_scheduler.gang.min_member is not supported for spark application")
+	req.BatchScheduler = &model.BatchScheduler{Name: tea.String("kube-batch")}
+	assert.ErrorContains(t, req.Validate(), "batch_scheduler.name must be volcano or yunikorn")
+	assert.Empty(t, (&model.CreateSparkApplicationRequest{}).ToYaml()["spec"].(map[string]any)["batchScheduler"])
+}
+
+func TestFlinkBatchScheduler(t *testing.T) {
+	podTemplate := func(yaml map[string]any, role string) (map[string]any, map[string]any) {
+		template := yaml["spec"].(map[string]any)[role].(map[string]any)["podTemplate"].(map[string]any)
+		return template["metadata"].(map[string]any)["annotations"].(map[string]any), template["spec"].(map[string]any)
+	}
+	req := model.CreateFlinkClusterRequest{
+		ClusterName:        tea.String("wordcount"),
+		NameSpace:          tea.String("flink"),
+		Submitter:          tea.String("alice"),
+		FlinkConfiguration: map[string]any{"taskmanager.numberOfTaskSlots": "4"},
+		Job:                &model.Job{JarURI: tea.String("local:///opt/flink/job.jar"), Parallelism: tea.Int32(10)},
+		JobManager:         &model.Manager{PriorityClassName: tea.String("flink-jm")},
+		BatchScheduler: &model.BatchScheduler{
+			Name:              tea.String(model.BatchSchedulerVolcano),
+			Queue:             tea.String("streaming"),
+			PriorityClassName: tea.String("batch-high"),
+			Gang:              &model.GangScheduling{},
+		},
+	}
+	assert.NoError(t, req.Validate())
+	yaml := req.ToYaml()
+	annotations, spec := podTemplate(yaml, "jobManager")
+	assert.Equal(t, "volcano", spec["schedulerName"])
+	assert.Equal(t, "flink-jm", spec["priorityClassName"])
+	assert.Equal(t, "streaming", annotations["scheduling.volcano.sh/queue-name"])
+	assert.Equal(t, "wordcount-podgroup", annotations["scheduling.k8s.io/group-name"])
+	annotations, spec = podTemplate(yaml, "taskManager")
+	assert.Equal(t, "volcano", spec["schedulerName"])
+	assert.Equal(t, "batch-high", spec["priorityClassName"])
+	assert.Equal(t, "wordcount-podgroup", annotations["scheduling.k8s.io/group-name"])
+	// TM 的 initContainers 保留
+	assert.Len(t, spec["initContainers"], 1)
+
+	// 1 个 JM 加上 10 / 4 向上取整个 TM
+	podGroup := req.NewVolcanoPodGroup()
+	assert.Equal(t, "wordcount-podgroup", podGroup["metadata"].(map[string]any)["name"])
+	assert.Equal(t, "flink", podGroup["metadata"].(map[string]any)["namespace"])
+	assert.Equal(t, map[string]any{"minMember": int32(4), "queue": "streaming", "priorityClassName": "batch-high"}, podGroup["spec"])
+
+	req.BatchScheduler = &model.BatchScheduler{Name: tea.String(model.BatchSchedulerYuniKorn), Queue: tea.String("root.streaming"), Gang: &model.GangScheduling{MinMember: tea.Int32(3)}}
+	req.TaskManager = &model.Manager{Resource: &model.FlinkResource{CPU: tea.String("2"), Memory: tea.String("4096Mi")}}
+	assert.NoError(t, req.Validate())
+	assert.Nil(t, req.NewVolcanoPodGroup())
+	yaml = req.ToYaml()
+	annotations, spec = podTemplate(yaml, "taskManager")
+	assert.Equal(t, "yunikorn", spec["schedulerName"])
+	assert.Equal(t, "wordcount", annotations["yunikorn.apache.org/app-id"])
+	assert.Equal(t, "root.streaming", annotations["yunikorn.apache.org/queue"])
+	assert.Equal(t, "taskmanager", annotations["yunikorn.apache.org/task-group-name"])
+	var taskGroups []map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(annotations["yunikorn.apache.org/task-groups"].(string)), &taskGroups))
+	assert.Equal(t, []map[string]any{
+		{"name": "jobmanager", "minMember": float64(1), "minResource": map[string]any{"cpu": "100m", "memory": "2048Mi"}},
+		{"name": "taskmanager", "minMember": float64(2), "minResource": map[string]any{"cpu": "2", "memory": "4096Mi"}},
+	}, taskGroups)
+	annotations, _ = podTemplate(yaml, "jobManager")
+	assert.Equal(t, "jobmanager", annotations["yunikorn.apache.org/task-group-name"])
+
+	// session 集群需要指定 min_member
+	req.Job = nil
+	req.BatchScheduler.Gang.MinMember = nil
+	assert.ErrorContains(t, req.Validate(), "batch_scheduler.gang.min_member is required")
+	req.BatchScheduler.Gang = &model.GangScheduling{MinMember: tea.Int32(0)}
+	assert.ErrorContains(t, req.Validate(), "min_member must be at least 1")
+	req.BatchScheduler = &model.BatchScheduler{Name: tea.String(model.BatchSchedulerVolcano), Gang: &model.GangScheduling{MinMember: tea.Int32(2), MinResources: map[string]string{"cpu": "lots"}}}
+	assert.ErrorContains(t, req.Validate(), "batch_scheduler.gang.min_resources.cpu")
+}
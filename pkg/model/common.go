@@ -1,8 +1,13 @@
 package model
 
 import (
+	"fmt"
+	"io"
+	"time"
+
 	appv1 "k8s.io/api/apps/v1"
 	podV1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacV1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -30,11 +35,15 @@ type K8SIO interface {
 	// POD
 	PodList(filter Filter) (*podV1.PodList, error)
 	PodGet(namespace, name string) (*podV1.Pod, error)
+	PodLogs(namespace, name string, opts PodLogOptions) (io.ReadCloser, error)
+	PodExec(namespace, name string, opts PodExecOptions) error
 
 	// DEPLOYMENT
 	DeploymentList(filter Filter) (*appv1.DeploymentList, error)
 	DeploymentApply(req ApplyDeploymentRequest) (any, error)
 	DeploymentCreate(dep *appv1.Deployment) (any, error)
+	DeploymentGet(namespace, name string) (*appv1.Deployment, error)
+	DeploymentUpdate(dep *appv1.Deployment) (*appv1.Deployment, error)
 	DeploymentDelete(namespace, name string) error
 	DeploymentScale(namespace, name string, replicas int32) (any, error)
 	DeploymentRestart(namespace, name string) (any, error)
@@ -43,6 +52,12 @@ type K8SIO interface {
 	ServiceList(filter Filter) (*podV1.ServiceList, error)
 	ServiceApply(req ApplyServiceRequest) (*podV1.Service, error)
 	ServiceDelete(namespace, name string) error
+	ServiceProxy(req ServiceProxyRequest) ([]byte, error) // 通过 apiserver service proxy 请求集群内服务
+
+	// INGRESS
+	IngressList(filter Filter) (*networkingv1.IngressList, error)
+	IngressApply(req ApplyIngressRequest) (*networkingv1.Ingress, error)
+	IngressDelete(namespace, name string) error
 
 	// CONFIGMAP
 	ConfigMapList(filter Filter) (*podV1.ConfigMapList, error)
@@ -59,7 +74,9 @@ type K8SIO interface {
 
 	// CRD Flink
 	CrdFlinkDeploymentList(Filter) (*unstructured.UnstructuredList, error)
+	CrdFlinkDeploymentGet(namespace, name string) (*unstructured.Unstructured, error)
 	CrdFlinkDeploymentApply(yaml map[string]any) (any, error)
+	CrdFlinkDeploymentPatch(namespace, name string, patch map[string]any) (*unstructured.Unstructured, error) // merge patch
 	CrdFlinkDeploymentDelete(namespace, name string) error
 
 	CrdFlinkSessionJobList(Filter) (*unstructured.UnstructuredList, error)
@@ -70,6 +87,17 @@ type K8SIO interface {
 	CrdSparkApplicationList(Filter) (*unstructured.UnstructuredList, error)
 	CrdSparkApplicationApply(yaml map[string]any) (any, error)
 	CrdSparkApplicationDelete(namespace, name string) error
+	CrdScheduledSparkApplicationList(Filter) (*unstructured.UnstructuredList, error)
+	CrdScheduledSparkApplicationGet(namespace, name string) (*unstructured.Unstructured, error)
+	CrdScheduledSparkApplicationApply(yaml map[string]any) (*unstructured.Unstructured, error)                          // 不存在时创建，存在时更新 spec
+	CrdScheduledSparkApplicationPatch(namespace, name string, patch map[string]any) (*unstructured.Unstructured, error) // merge patch
+	CrdScheduledSparkApplicationDelete(namespace, name string) error
+
+	// 批调度
+	CrdExists(group, version, resource string) (bool, error) // 集群是否注册了该 CRD
+	VolcanoQueueGet(name string) (*unstructured.Unstructured, error)
+	VolcanoPodGroupApply(yaml map[string]any) (*unstructured.Unstructured, error) // 不存在时创建，存在时更新 spec
+	VolcanoPodGroupDelete(namespace, name string) error
 }
 
 type K8SContract interface {
@@ -84,18 +112,56 @@ type K8SContract interface {
 	CrdFlinkSessionJobDelete(k8sClusterName string, req DeleteFlinkSessionJobRequest) error
 	CrdFlinkDeploymentRestart(k8sClusterName string, req RestartFlinkClusterRequest) error
 	CrdFlinkTMScale(k8sClusterName string, req CrdFlinkTMScaleRequest) error
+	CrdFlinkAutoscalerStatus(k8sClusterName string, req FlinkAutoscalerStatusRequest) (FlinkAutoscalerStatus, error)
+	FlinkClusterEndpoints(k8sClusterName, namespace, clusterName string) ([]Endpoint, error)             // operator 和 v12 集群的 LB、NodePort、ingress 地址
+	FlinkClusterList(k8sClusterName string, filter FilterFlinkCluster) (FlinkClusterListResponse, error) // 合并 operator 和 v1.12 集群，状态归一化，支持按 owner、名称、状态、版本过滤和排序
 	// FlinkV1.12.7
 	FlinkV12ClusterList(k8sClusterName string, filter FilterFlinkV12) (CrdFlinkDeploymentGetResponse, error)
 	FlinkV12ClusterCreate(k8sClusterName string, req CreateFlinkV12ClusterRequest) (CreateResponse, error)
-	FlinkV12ClusterApply(k8sClusterName, namespace, clusterName string, req ApplyFlinkV12ClusterRequest) error // 注意这里apply是全局替换，不是 batch 请注意
+	FlinkV12ClusterApply(k8sClusterName, namespace, clusterName string, req ApplyFlinkV12ClusterRequest) (ApplyFlinkV12ClusterResponse, error) // 只更新传入的字段，flinkConfiguration 和 env 是全量替换
 	FlinkV12ClusterDelete(k8sClusterName string, req DeleteFlinkClusterRequest) error
-	// FlinkV12ClusterGetConfig(k8sClusterName string) (map[string]string, error)
+	FlinkV12ClusterGetConfig(k8sClusterName, namespace, clusterName string) (FlinkV12Config, error)
+	FlinkV12ConfigRevisionList(k8sClusterName, namespace, clusterName string) ([]FlinkV12ConfigRevision, error)
+	FlinkV12ConfigDiff(k8sClusterName, namespace, clusterName string, from, to int) (FlinkV12ConfigDiff, error) // 版本号 0 表示当前配置
+	FlinkV12ConfigRollback(k8sClusterName, namespace, clusterName string, revision int) (ApplyFlinkV12ClusterResponse, error)
+	FlinkV12MigrateToOperator(k8sClusterName string, req MigrateFlinkV12Request) (MigrateFlinkV12Response, error) // 迁移为 operator 管理的 FlinkDeployment，支持 dry_run、savepoint 和失败回滚
+	FlinkV12JarUpload(k8sClusterName string, req FlinkV12JarUploadRequest) (FlinkJarUploadResponse, error)
+	FlinkV12JarList(k8sClusterName string, req FlinkRestRequest) (FlinkJarList, error)
+	FlinkV12JarRun(k8sClusterName string, req FlinkV12JarRunRequest) (FlinkJarRunResponse, error)
+	FlinkV12JobList(k8sClusterName string, req FlinkRestRequest) (FlinkJobsOverview, error)
+	FlinkV12JobCancel(k8sClusterName string, req FlinkRestRequest) error // job_id 必填
+	FlinkV12JobStop(k8sClusterName string, req FlinkV12JobStopRequest) (FlinkV12JobStopResponse, error)
+	// Flink REST，operator 和 v12 集群都支持
+	FlinkRestClusterOverview(k8sClusterName string, req FlinkRestRequest) (FlinkClusterOverview, error)
+	FlinkRestJobsOverview(k8sClusterName string, req FlinkRestRequest) (FlinkJobsOverview, error)
+	FlinkRestJobDetail(k8sClusterName string, req FlinkRestRequest) (FlinkJobDetail, error)
+	FlinkRestJobExceptions(k8sClusterName string, req FlinkRestRequest) (FlinkJobExceptions, error)
+	FlinkRestJobCheckpoints(k8sClusterName string, req FlinkRestRequest) (FlinkJobCheckpoints, error)
 
 	// Spark
 	CrdSparkApplicationList(k8sClusterName string, filter Filter) (CrdSparkApplicationGetResponse, error)
 	CrdSparkApplicationGet(k8sClusterName, namespace, name string) (CrdResourceDetail, error)
 	CrdSparkApplicationApply(k8sClusterName string, req CreateSparkApplicationRequest) (CreateResponse, error)
 	CrdSparkApplicationDelete(k8sClusterName string, req DeleteSparkApplicationRequest) error
+	CrdSparkApplicationCleanup(k8sClusterName string, req CleanupSparkApplicationRequest) (CleanupSparkApplicationResponse, error)
+	CrdSparkApplicationRerun(k8sClusterName string, req RerunSparkApplicationRequest) (CreateResponse, error)
+	CrdSparkApplicationEndpoints(k8sClusterName, namespace, name string) ([]Endpoint, error)                                // driver UI 地址
+	CrdScheduledSparkApplicationList(k8sClusterName string, filter Filter) (CrdScheduledSparkApplicationGetResponse, error) // 包含最后一次和下一次运行时间
+	CrdScheduledSparkApplicationGet(k8sClusterName, namespace, name string) (CrdScheduledSparkApplication, error)
+	CrdScheduledSparkApplicationApply(k8sClusterName string, req CreateScheduledSparkApplicationRequest) (CreateResponse, error) // 不存在时创建，存在时更新
+	CrdScheduledSparkApplicationDelete(k8sClusterName string, req DeleteSparkApplicationRequest) error
+	CrdScheduledSparkApplicationSuspend(k8sClusterName, namespace, name string) error
+	CrdScheduledSparkApplicationResume(k8sClusterName, namespace, name string) error
+
+	// Logs，返回的 ReadCloser 需要调用方关闭
+	FlinkJobManagerLogs(k8sClusterName, namespace, clusterName string, opts PodLogOptions) (io.ReadCloser, error)
+	FlinkTaskManagerLogs(k8sClusterName, namespace, clusterName, podName string, opts PodLogOptions) (io.ReadCloser, error) // podName 为空时使用第一个 TM
+	CrdSparkApplicationDriverLogs(k8sClusterName, namespace, name string, opts PodLogOptions) (io.ReadCloser, error)
+	FlinkLogSearch(k8sClusterName string, req LogSearchRequest) (LogSearchResponse, error)
+	CrdSparkApplicationLogSearch(k8sClusterName string, req LogSearchRequest) (LogSearchResponse, error)
+
+	// Exec
+	PodExecCommand(k8sClusterName string, req PodExecRequest) (PodExecResponse, error)
 }
 
 type ClusterInfo struct {
@@ -123,3 +189,18 @@ type CrdResourceDetail struct {
 	Spec       any    `json:"spec"`
 	Status     any    `json:"status"`
 }
+
+// parseTimeout 解析 "30s"、"5m" 格式的超时时间，为空时返回 defaultTimeout
+func parseTimeout(name string, timeout *string, defaultTimeout time.Duration) (time.Duration, error) {
+	if timeout == nil {
+		return defaultTimeout, nil
+	}
+	d, err := time.ParseDuration(*timeout)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", name, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return d, nil
+}
@@ -2,7 +2,8 @@ package model
 
 import (
 	"fmt"
-	"math/rand"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -10,7 +11,9 @@ import (
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/spf13/cast"
 	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 type CrdFlinkSessionJobGetResponse struct {
@@ -43,6 +46,7 @@ type CrdFlinkDeployment struct {
 	Status       any                    `json:"status"`       // 集群状态信息
 	Annotation   any                    `json:"annotation"`   // 集群描述信息
 	LoadBalancer map[string]string      `json:"loadBalancer"` // 如果创建的时候带了，这里可以查询信息
+	Endpoints    []Endpoint             `json:"endpoints"`    // LB、NodePort、ClusterIP、ingress 访问地址，LB 未就绪时 ready=false
 	Info         CrdFlinkDeploymentInfo `json:"info"`         // 集群额外信息，比如集群版本，启动时间，副本数量，cpu、内存等信息
 	FlinkConfig  map[string]any         `json:"flink_config"` // flink Operator 的值是可以是数字比如 slot的数量	 低版本在 k8sconfig 是 string方式
 }
@@ -186,14 +190,19 @@ type CreateFlinkClusterRequest struct {
 	Version            *string              `json:"version" default:"v1_17"`
 	ServiceAccount     *string              `json:"service_account" default:"flink"`
 	FlinkConfiguration map[string]any       `json:"flink_configuration"`              // flink配置,键值对的方式比如: {"taskmanager.numberOfTaskSlots": "2"}
-	EnableFluentit     *bool                `json:"enable_fluentbit" default:"false"` // sidecar fluentbit
+	EnableFluentit     *bool                `json:"enable_fluentbit" default:"false"` // sidecar fluentbit，已废弃，请使用 log_collection
+	LogCollection      *FlinkLogCollection  `json:"log_collection"`                   // 日志收集配置，设置后忽略 enable_fluentbit
+	State              *FlinkState          `json:"state"`                            // HA、状态后端、checkpoint/savepoint 配置，生成对应 flinkConfiguration
+	Autoscaler         *FlinkAutoscaler     `json:"autoscaler"`                       // operator autoscaler 配置，生成 job.autoscaler.* flinkConfiguration
 	Env                []Env                `json:"env"`                              // 环境变量,同时给JM和TM设置环境变量
 	TaskManager        *Manager             `json:"task_manager"`
 	JobManager         *Manager             `json:"job_manager"`
-	Job                *Job                 `json:"job"`          // 如果没有该字段则创建 Session集群，如果有该字段则创建Application集群。
-	Submitter          *string              `json:"submitter"`    // 提交人
-	Labels             map[string]string    `json:"labels"`       // 自定义标签
-	LoadBalancer       *LoadBalancerRequest `json:"loadBalancer"` // 配置相关 annotations启用云主机负载均衡,nil不会启用
+	Job                *Job                 `json:"job"`             // 如果没有该字段则创建 Session集群，如果有该字段则创建Application集群。
+	Submitter          *string              `json:"submitter"`       // 提交人
+	Labels             map[string]string    `json:"labels"`          // 自定义标签
+	LoadBalancer       *LoadBalancerRequest `json:"loadBalancer"`    // 配置相关 annotations启用云主机负载均衡,nil不会启用
+	Ingress            *FlinkIngress        `json:"ingress"`         // operator spec.ingress，可以替代 LoadBalancer
+	BatchScheduler     *BatchScheduler      `json:"batch_scheduler"` // volcano 或 yunikorn 调度 JM/TM
 }
 
 func (c *CreateFlinkClusterRequest) Validate() error {
@@ -249,6 +258,54 @@ func (c *CreateFlinkClusterRequest) Validate() error {
 		}
 	}
 
+	// 检查 JM/TM 的 pod 配置，共用 podTemplate 中的卷可以直接挂载
+	sharedVolumes := podTemplateVolumes(c.sharedPodTemplate())
+	if err := c.JobManager.validate("job_manager", sharedVolumes); err != nil {
+		return err
+	}
+	if err := c.TaskManager.validate("task_manager", sharedVolumes); err != nil {
+		return err
+	}
+
+	if err := c.LogCollection.Validate(); err != nil {
+		return err
+	}
+	if err := c.Ingress.Validate(); err != nil {
+		return err
+	}
+	if err := c.LoadBalancer.Validate(); err != nil {
+		return err
+	}
+	if err := c.BatchScheduler.Validate(); err != nil {
+		return err
+	}
+	if c.BatchScheduler != nil && c.BatchScheduler.Gang != nil {
+		if _, err := c.flinkMinMember(); err != nil {
+			return err
+		}
+	}
+
+	// 检查状态配置，flink_configuration 中相同的 key 不允许设置不同的值
+	if err := c.State.Validate(c.Job); err != nil {
+		return err
+	}
+	if err := c.Autoscaler.Validate(c.Job); err != nil {
+		return err
+	}
+	for _, section := range []struct {
+		name   string
+		config map[string]any
+	}{
+		{"state", c.State.ToFlinkConfiguration()},
+		{"autoscaler", c.Autoscaler.ToFlinkConfiguration()},
+	} {
+		for k, v := range section.config {
+			if raw, ok := c.FlinkConfiguration[k]; ok && cast.ToString(raw) != cast.ToString(v) {
+				return fmt.Errorf("flink_configuration.%s conflicts with %s, got %v, %s requires %v", k, section.name, raw, section.name, v)
+			}
+		}
+	}
+
 	// 检查Job配置
 	if c.Job != nil {
 		if c.Job.JarURI == nil || *c.Job.JarURI == "" {
@@ -272,6 +329,47 @@ func (c *CreateFlinkClusterRequest) Validate() error {
 	return nil
 }
 
+// podEnv JM 和 TM 公共的环境变量，包括 state 凭证
+func (c *CreateFlinkClusterRequest) podEnv() []map[string]any {
+	env := make([]map[string]any, 0, len(c.Env))
+	for _, e := range c.Env {
+		env = append(env, map[string]any{
+			"name":  tea.StringValue(e.Name),
+			"value": tea.StringValue(e.Value),
+		})
+	}
+	return append(env, c.State.credentialEnv()...)
+}
+
+// logCollection 未配置 log_collection 时兼容 enable_fluentbit 的 hostPath + fluentbit 方式
+func (c *CreateFlinkClusterRequest) logCollection() *FlinkLogCollection {
+	if c.LogCollection != nil {
+		return c.LogCollection
+	}
+	logCollection := &FlinkLogCollection{VolumeType: LogVolumeHostPath}
+	if tea.BoolValue(c.EnableFluentit) {
+		logCollection.SideCar = &LogSideCar{
+			Name:  tea.String("fluentbit"),
+			Image: tea.String("fluent/fluent-bit:1.8.12-debug"),
+			Command: []string{
+				"sh",
+				"-c",
+				"/fluent-bit/bin/fluent-bit -i tail -p path=/flink-logs/*.log -p multiline.parser=java -o stdout",
+			},
+		}
+	}
+	return logCollection
+}
+
+// NewLogConfigMap 日志 sidecar 的配置，没有配置时返回 nil
+func (c *CreateFlinkClusterRequest) NewLogConfigMap() *ApplyConfigMapRequest {
+	var labels map[string]string
+	if c.Submitter != nil {
+		labels = map[string]string{"owner": *c.Submitter}
+	}
+	return c.LogCollection.NewConfigMap(c.NameSpace, tea.StringValue(c.ClusterName), labels)
+}
+
 // 辅助函数: 检查是否是有效的Kubernetes资源名称
 func isValidK8sName(name string) bool {
 	// Kubernetes资源名称只能包含小写字母、数字和中横线，且不能以中横线开头或结尾
@@ -338,27 +436,14 @@ spec:
 	  component: jobmanager
 	  type: flink-native-kubernetes
 */
-func (c *CreateFlinkClusterRequest) NewLBService() ApplyServiceRequest {
-	// 随机生成30000-32767端口
-	min := 30000
-	max := 32767
-	randPort := rand.Intn(max-min+1) + min
-
+func (c *CreateFlinkClusterRequest) NewLBService(nodePort int32) ApplyServiceRequest {
 	req := ApplyServiceRequest{
 		Name:      tea.String(fmt.Sprintf(JobManagerLBServiceName, *c.ClusterName)),
 		Namespace: c.NameSpace,
 		Spec: &ServiceSpec{
 			Selector: map[string]string{"app": *c.ClusterName, "component": "jobmanager", "type": "flink-native-kubernetes"},
-			Ports: []Port{
-				{
-					Name:       tea.String("rest"),
-					Protocol:   tea.String("TCP"),
-					Port:       tea.Int32(int32(randPort)),
-					TargetPort: tea.Int32(8081),
-					NodePort:   tea.Int32(int32(randPort)),
-				},
-			},
-			Type: tea.String("LoadBalancer"),
+			Ports:    []Port{NewLBPort("rest", nodePort)},
+			Type:     tea.String("LoadBalancer"),
 		},
 	}
 
@@ -450,6 +535,56 @@ spec:
 	  parallelism: 2  # 2 task managers
 	  upgradeMode: stateless # last-state,stateless,savepoint
 */
+// sharedPodTemplate JM 和 TM 共用的 spec.podTemplate，设置了 env、日志收集或 fluentbit 时生成 flink-logs 卷和日志挂载，否则返回 nil
+func (req *CreateFlinkClusterRequest) sharedPodTemplate() map[string]any {
+	env := req.podEnv()
+	if req.EnableFluentit == nil && len(env) == 0 && req.LogCollection == nil {
+		return nil
+	}
+	logCollection := req.logCollection()
+	mainContainer := map[string]any{
+		"name": "flink-main-container",
+		"volumeMounts": []map[string]interface{}{
+			{
+				"mountPath": "/opt/flink/log",
+				"name":      "flink-logs",
+			},
+		},
+	}
+	if len(env) != 0 {
+		mainContainer["env"] = env
+	}
+
+	containers := []map[string]any{
+		mainContainer,
+	}
+	if sideCar := logCollection.sideCarContainer("flink-logs"); sideCar != nil {
+		containers = append(containers, sideCar)
+	}
+	volumes := []map[string]interface{}{
+		logCollection.logVolume("flink-logs", fmt.Sprintf("/mnt/log/%s/", tea.StringValue(req.ClusterName))),
+	}
+	if configVolume := logCollection.configVolume(tea.StringValue(req.ClusterName)); configVolume != nil {
+		volumes = append(volumes, configVolume)
+	}
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name": "pod-template",
+			"labels": map[string]interface{}{
+				"sdk":   "multi-k8s-client",
+				"app":   tea.StringValue(req.ClusterName),
+				"owner": tea.StringValue(req.Submitter),
+			},
+		},
+		"spec": map[string]interface{}{
+			"containers": containers,
+			"volumes":    volumes,
+		},
+	}
+}
+
 func (req *CreateFlinkClusterRequest) ToYaml() map[string]any {
 	yaml := map[string]interface{}{
 		"apiVersion": "flink.apache.org/v1beta1",
@@ -463,10 +598,7 @@ func (req *CreateFlinkClusterRequest) ToYaml() map[string]any {
 			"flinkVersion": "v1_17",
 			"flinkConfiguration": map[string]interface{}{
 				"taskmanager.numberOfTaskSlots": "2",
-				// "state.savepoints.dir":          "file:///flink-data/savepoints",
-				// "state.checkpoints.dir":         "file:///flink-data/checkpoints",
-				// "high-availability":             "org.apache.flink.kubernetes.highavailability.KubernetesHaServicesFactory",
-				// "high-availability.storageDir":  "file:///flink-data/ha",
+				// HA、checkpoint、savepoint 通过 State 生成
 			},
 			"serviceAccount": "flink",
 			"jobManager": map[string]interface{}{
@@ -478,6 +610,7 @@ func (req *CreateFlinkClusterRequest) ToYaml() map[string]any {
 					"apiVersion": "v1",
 					"kind":       "Pod",
 					"metadata": map[string]interface{}{
+						"name": "job-manager-pod-template",
 						"labels": map[string]interface{}{
 							"sdk":   "multi-k8s-client",
 							"owner": tea.StringValue(req.Submitter),
@@ -520,67 +653,12 @@ func (req *CreateFlinkClusterRequest) ToYaml() map[string]any {
 		},
 	} // default
 
-	if req.EnableFluentit != nil || len(req.Env) != 0 {
-
-		mainContainer := map[string]any{
-			"name": "flink-main-container",
-			"volumeMounts": []map[string]interface{}{
-				{
-					"mountPath": "/opt/flink/log",
-					"name":      "flink-logs",
-				},
-			},
-		}
-		fluentitContainer := map[string]any{
-			"name":  "fluentbit",
-			"image": "fluent/fluent-bit:1.8.12-debug",
-			"command": []string{
-				"sh",
-				"-c",
-				"/fluent-bit/bin/fluent-bit -i tail -p path=/flink-logs/*.log -p multiline.parser=java -o stdout",
-			},
-			"volumeMounts": []map[string]interface{}{
-				{
-					"mountPath": "/flink-logs",
-					"name":      "flink-logs",
-				},
-			},
-		}
-		if len(req.Env) != 0 {
-			mainContainer["env"] = req.Env
-		}
-
-		containers := []map[string]any{
-			mainContainer,
-		}
-		if tea.BoolValue(req.EnableFluentit) {
-			containers = append(containers, fluentitContainer)
-		}
-		yaml["spec"].(map[string]interface{})["podTemplate"] = map[string]interface{}{
-			"apiVersion": "v1",
-			"kind":       "Pod",
-			"metadata": map[string]interface{}{
-				"name": "pod-template",
-				"labels": map[string]interface{}{
-					"sdk":   "multi-k8s-client",
-					"app":   tea.StringValue(req.ClusterName),
-					"owner": tea.StringValue(req.Submitter),
-				},
-			},
-			"spec": map[string]interface{}{
-				"containers": containers,
-				"volumes": []map[string]interface{}{
-					{
-						"name": "flink-logs",
-						"hostPath": map[string]interface{}{
-							"path": fmt.Sprintf("/mnt/log/%s/", tea.StringValue(req.ClusterName)),
-							"type": "DirectoryOrCreate",
-						},
-					},
-				},
-			},
-		}
-
+	podTemplate := req.sharedPodTemplate()
+	if podTemplate != nil {
+		yaml["spec"].(map[string]interface{})["podTemplate"] = podTemplate
+	}
+	if logConfiguration := req.LogCollection.logConfiguration(); len(logConfiguration) > 0 {
+		yaml["spec"].(map[string]interface{})["logConfiguration"] = logConfiguration
 	}
 	if req.ClusterName != nil {
 		yaml["metadata"].(map[string]interface{})["name"] = *req.ClusterName
@@ -598,31 +676,32 @@ func (req *CreateFlinkClusterRequest) ToYaml() map[string]any {
 	if req.FlinkConfiguration != nil {
 		yaml["spec"].(map[string]interface{})["flinkConfiguration"] = req.FlinkConfiguration
 	}
+	if req.State != nil || req.Autoscaler != nil {
+		// flink_configuration 中显式设置的值优先
+		flinkConfiguration := yaml["spec"].(map[string]interface{})["flinkConfiguration"].(map[string]interface{})
+		mergedConfiguration := make(map[string]any, len(flinkConfiguration))
+		for k, v := range req.State.ToFlinkConfiguration() {
+			mergedConfiguration[k] = v
+		}
+		for k, v := range req.Autoscaler.ToFlinkConfiguration() {
+			mergedConfiguration[k] = v
+		}
+		for k, v := range flinkConfiguration {
+			mergedConfiguration[k] = v
+		}
+		yaml["spec"].(map[string]interface{})["flinkConfiguration"] = mergedConfiguration
+	}
 	if req.TaskManager != nil {
 		if req.TaskManager.Resource != nil {
 			// 转换cpu 的 str到这里 int类型
-			cpu := cast.ToInt(*req.JobManager.Resource.CPU)
+			cpu := cast.ToInt(*req.TaskManager.Resource.CPU)
 			yaml["spec"].(map[string]interface{})["taskManager"].(map[string]interface{})["resource"] = map[string]interface{}{
-				"memory": req.JobManager.Resource.Memory,
+				"memory": req.TaskManager.Resource.Memory,
 				"cpu":    cpu,
 			}
 		}
-		if req.TaskManager.NodeSelector != nil {
-			yaml["spec"].(map[string]interface{})["taskManager"].(map[string]interface{})["podTemplate"] = map[string]interface{}{
-				"apiVersion": "v1",
-				"kind":       "Pod",
-				"metadata": map[string]interface{}{
-					"name": "task-manager-pod-template",
-					"labels": map[string]interface{}{
-						"sdk":   "multi-k8s-client",
-						"app":   tea.StringValue(req.ClusterName),
-						"owner": tea.StringValue(req.Submitter),
-					},
-				},
-				"spec": map[string]interface{}{},
-			}
-			yaml["spec"].(map[string]interface{})["taskManager"].(map[string]interface{})["podTemplate"].(map[string]interface{})["spec"].(map[string]interface{})["nodeSelector"] = *req.TaskManager.NodeSelector
-		}
+		// nodeSelector、tolerations、volumes 等合并到 TM podTemplate
+		req.TaskManager.applyPodTemplate(yaml["spec"].(map[string]interface{})["taskManager"].(map[string]interface{})["podTemplate"].(map[string]interface{}), podTemplate)
 	}
 	if req.JobManager != nil {
 		if req.JobManager.Resource != nil {
@@ -633,26 +712,19 @@ func (req *CreateFlinkClusterRequest) ToYaml() map[string]any {
 				"cpu":    cpu,
 			}
 		}
-		if req.JobManager.NodeSelector != nil {
-			yaml["spec"].(map[string]interface{})["jobManager"].(map[string]interface{})["podTemplate"] = map[string]interface{}{
-				"apiVersion": "v1",
-				"kind":       "Pod",
-				"metadata": map[string]interface{}{
-					"name": "job-manager-pod-template",
-					"labels": map[string]interface{}{
-						"sdk":   "multi-k8s-client",
-						"app":   tea.StringValue(req.ClusterName),
-						"owner": tea.StringValue(req.Submitter),
-					},
-				},
-				"spec": map[string]interface{}{},
-			}
-			yaml["spec"].(map[string]interface{})["jobManager"].(map[string]interface{})["podTemplate"].(map[string]interface{})["spec"].(map[string]interface{})["nodeSelector"] = *req.JobManager.NodeSelector
-		}
+		// nodeSelector、tolerations、volumes 等合并到 JM podTemplate
+		req.JobManager.applyPodTemplate(yaml["spec"].(map[string]interface{})["jobManager"].(map[string]interface{})["podTemplate"].(map[string]interface{}), podTemplate)
+	}
+	if req.BatchScheduler != nil {
+		req.applyBatchScheduler(yaml["spec"].(map[string]interface{})["jobManager"].(map[string]interface{})["podTemplate"].(map[string]interface{}), "jobmanager")
+		req.applyBatchScheduler(yaml["spec"].(map[string]interface{})["taskManager"].(map[string]interface{})["podTemplate"].(map[string]interface{}), "taskmanager")
 	}
 	if req.Job != nil {
 		yaml["spec"].(map[string]interface{})["job"] = req.Job.ToYaml()
 	}
+	if req.Ingress != nil {
+		yaml["spec"].(map[string]interface{})["ingress"] = req.Ingress.ToYaml()
+	}
 	if req.ServiceAccount != nil {
 		yaml["spec"].(map[string]interface{})["serviceAccount"] = *req.ServiceAccount
 	}
@@ -672,8 +744,233 @@ func (req *CreateFlinkClusterRequest) ToYaml() map[string]any {
 }
 
 type Manager struct {
-	Resource     *FlinkResource     `json:"resource"`
-	NodeSelector *map[string]string `json:"node_selector"`
+	Resource                  *FlinkResource                    `json:"resource"`
+	NodeSelector              *map[string]string                `json:"node_selector"`
+	Tolerations               []corev1.Toleration               `json:"tolerations"`
+	Affinity                  *corev1.Affinity                  `json:"affinity"` // nodeAffinity、podAffinity、podAntiAffinity
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topology_spread_constraints"`
+	Volumes                   []Volume                          `json:"volumes"`       // 额外的卷，支持 pvc、configmap、secret 等
+	VolumeMounts              []VolumeMount                     `json:"volume_mounts"` // 挂载到 flink-main-container
+	ImagePullSecrets          []string                          `json:"image_pull_secrets"`
+	PriorityClassName         *string                           `json:"priority_class_name"`
+	Annotations               map[string]string                 `json:"annotations"` // pod annotations
+	SecurityContext           *corev1.PodSecurityContext        `json:"security_context"`
+}
+
+func (m *Manager) validate(field string, sharedVolumes []string) error {
+	if m == nil {
+		return nil
+	}
+	volumes := map[string]bool{}
+	for _, name := range sharedVolumes {
+		volumes[name] = true
+	}
+	for _, v := range m.Volumes {
+		if _, err := v.ToYaml(); err != nil {
+			return fmt.Errorf("%s.volumes: %v", field, err)
+		}
+		if volumes[*v.Name] {
+			return fmt.Errorf("%s.volumes: duplicate volume name %s", field, *v.Name)
+		}
+		volumes[*v.Name] = true
+	}
+	for _, mount := range m.VolumeMounts {
+		if _, err := mount.ToYaml(); err != nil {
+			return fmt.Errorf("%s.volume_mounts: %v", field, err)
+		}
+		if !volumes[*mount.Name] {
+			return fmt.Errorf("%s.volume_mounts: volume %s is not defined", field, *mount.Name)
+		}
+	}
+	for _, secret := range m.ImagePullSecrets {
+		if secret == "" {
+			return fmt.Errorf("%s.image_pull_secrets must not contain empty name", field)
+		}
+	}
+	if m.PriorityClassName != nil && *m.PriorityClassName == "" {
+		return fmt.Errorf("%s.priority_class_name must not be empty", field)
+	}
+	if err := validatePodScheduling(field, m.Tolerations, m.Affinity); err != nil {
+		return err
+	}
+	for i := range m.TopologySpreadConstraints {
+		if _, err := toUnstructured(&m.TopologySpreadConstraints[i]); err != nil {
+			return fmt.Errorf("%s.topology_spread_constraints[%d]: %v", field, i, err)
+		}
+	}
+	if m.SecurityContext != nil {
+		if _, err := toUnstructured(m.SecurityContext); err != nil {
+			return fmt.Errorf("%s.security_context: %v", field, err)
+		}
+	}
+	return nil
+}
+
+// validatePodScheduling tolerations 的 operator、effect 和 apiserver 的规则一致，并且可以转换为 podTemplate
+func validatePodScheduling(field string, tolerations []corev1.Toleration, affinity *corev1.Affinity) error {
+	for i := range tolerations {
+		t := &tolerations[i]
+		switch t.Operator {
+		case "", corev1.TolerationOpEqual:
+			if t.Key == "" {
+				return fmt.Errorf("%s.tolerations[%d]: key is required unless operator is Exists", field, i)
+			}
+		case corev1.TolerationOpExists:
+			if t.Value != "" {
+				return fmt.Errorf("%s.tolerations[%d]: value must be empty when operator is Exists", field, i)
+			}
+		default:
+			return fmt.Errorf("%s.tolerations[%d]: operator must be Equal or Exists", field, i)
+		}
+		switch t.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("%s.tolerations[%d]: effect must be NoSchedule, PreferNoSchedule or NoExecute", field, i)
+		}
+		if _, err := toUnstructured(t); err != nil {
+			return fmt.Errorf("%s.tolerations[%d]: %v", field, i, err)
+		}
+	}
+	if affinity != nil {
+		if _, err := toUnstructured(affinity); err != nil {
+			return fmt.Errorf("%s.affinity: %v", field, err)
+		}
+	}
+	return nil
+}
+
+// applyPodTemplate 将 Manager 的 pod 配置合并进 JM/TM 的 podTemplate，保留 SDK 已设置的 labels 和容器
+// 转换错误已经在 Validate 中返回，请先调用 Validate 校验
+func (m *Manager) applyPodTemplate(podTemplate, shared map[string]any) {
+	metadata := childMap(podTemplate, "metadata")
+	spec := childMap(podTemplate, "spec")
+	if shared != nil && (len(m.Volumes) > 0 || len(m.VolumeMounts) > 0) {
+		inheritSharedPodTemplate(spec, shared)
+	}
+
+	if len(m.Annotations) > 0 {
+		annotations := childMap(metadata, "annotations")
+		for k, v := range m.Annotations {
+			annotations[k] = v
+		}
+	}
+	if m.NodeSelector != nil {
+		spec["nodeSelector"] = *m.NodeSelector
+	}
+	if len(m.Tolerations) > 0 {
+		tolerations := make([]map[string]any, 0, len(m.Tolerations))
+		for i := range m.Tolerations {
+			if t, err := toUnstructured(&m.Tolerations[i]); err == nil {
+				tolerations = append(tolerations, t)
+			}
+		}
+		spec["tolerations"] = tolerations
+	}
+	if m.Affinity != nil {
+		if affinity, err := toUnstructured(m.Affinity); err == nil {
+			spec["affinity"] = affinity
+		}
+	}
+	if len(m.TopologySpreadConstraints) > 0 {
+		constraints := make([]map[string]any, 0, len(m.TopologySpreadConstraints))
+		for i := range m.TopologySpreadConstraints {
+			if c, err := toUnstructured(&m.TopologySpreadConstraints[i]); err == nil {
+				constraints = append(constraints, c)
+			}
+		}
+		spec["topologySpreadConstraints"] = constraints
+	}
+	if len(m.ImagePullSecrets) > 0 {
+		secrets := make([]map[string]any, 0, len(m.ImagePullSecrets))
+		for _, name := range m.ImagePullSecrets {
+			secrets = append(secrets, map[string]any{"name": name})
+		}
+		spec["imagePullSecrets"] = secrets
+	}
+	if m.PriorityClassName != nil {
+		spec["priorityClassName"] = *m.PriorityClassName
+	}
+	if m.SecurityContext != nil {
+		if securityContext, err := toUnstructured(m.SecurityContext); err == nil {
+			spec["securityContext"] = securityContext
+		}
+	}
+	for _, v := range m.Volumes {
+		if volume, err := v.ToYaml(); err == nil {
+			appendToList(spec, "volumes", volume)
+		}
+	}
+	if len(m.VolumeMounts) > 0 {
+		container := mainContainer(spec)
+		for _, mount := range m.VolumeMounts {
+			if volumeMount, err := mount.ToYaml(); err == nil {
+				appendToList(container, "volumeMounts", volumeMount)
+			}
+		}
+	}
+}
+
+// inheritSharedPodTemplate 先复制共用 podTemplate 的卷和容器，再追加角色的卷和挂载
+// operator 默认按下标合并数组（kubernetes.operator.pod-template.merge-arrays-by-name 为 false），两层的前几项相同才不会覆盖日志卷和挂载
+func inheritSharedPodTemplate(spec, shared map[string]any) {
+	sharedSpec, _ := shared["spec"].(map[string]any)
+	volumes, _ := sharedSpec["volumes"].([]map[string]any)
+	for _, volume := range volumes {
+		appendToList(spec, "volumes", maps.Clone(volume))
+	}
+	containers, _ := sharedSpec["containers"].([]map[string]any)
+	for _, c := range containers {
+		container := maps.Clone(c)
+		if mounts, ok := c["volumeMounts"].([]map[string]any); ok {
+			container["volumeMounts"] = slices.Clone(mounts)
+		}
+		appendToList(spec, "containers", container)
+	}
+}
+
+// podTemplateVolumes podTemplate 中定义的卷名称，podTemplate 为 nil 时返回空
+func podTemplateVolumes(podTemplate map[string]any) []string {
+	spec, _ := podTemplate["spec"].(map[string]any)
+	volumes, _ := spec["volumes"].([]map[string]any)
+	names := make([]string, 0, len(volumes))
+	for _, volume := range volumes {
+		if name, ok := volume["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// mainContainer 返回 podTemplate 中的 flink-main-container，不存在则创建
+func mainContainer(spec map[string]any) map[string]any {
+	containers, _ := spec["containers"].([]map[string]any)
+	for _, c := range containers {
+		if c["name"] == "flink-main-container" {
+			return c
+		}
+	}
+	container := map[string]any{"name": "flink-main-container"} // Do not change the main container name
+	spec["containers"] = append(containers, container)
+	return container
+}
+
+func childMap(parent map[string]any, key string) map[string]any {
+	if child, ok := parent[key].(map[string]any); ok {
+		return child
+	}
+	child := map[string]any{}
+	parent[key] = child
+	return child
+}
+
+func appendToList(parent map[string]any, key string, items ...map[string]any) {
+	list, _ := parent[key].([]map[string]any)
+	parent[key] = append(list, items...)
+}
+
+// toUnstructured 将 k8s 类型转换为 map，保证 yaml 中都是可以直接序列化的基础类型
+func toUnstructured(obj any) (map[string]any, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
 }
 
 type FlinkResource struct {
@@ -688,6 +985,9 @@ type Job struct {
 	UpgradeMode *string  `json:"upgrade_mode"` // stateless or stateful
 	Args        []string `json:"args"`         // 启动参数 --arg1=value1
 	EntryClass  *string  `json:"entry_class"`  // 主类
+
+	InitialSavepointPath  *string `json:"initial_savepoint_path"`   // 首次部署时从该 savepoint 恢复
+	AllowNonRestoredState *bool   `json:"allow_non_restored_state"` // 允许跳过 savepoint 中无法恢复的状态
 }
 
 func (j *Job) ToYaml() map[string]any {
@@ -710,6 +1010,12 @@ func (j *Job) ToYaml() map[string]any {
 	if j.EntryClass != nil {
 		yaml["entryClass"] = *j.EntryClass
 	}
+	if j.InitialSavepointPath != nil {
+		yaml["initialSavepointPath"] = *j.InitialSavepointPath
+	}
+	if j.AllowNonRestoredState != nil {
+		yaml["allowNonRestoredState"] = *j.AllowNonRestoredState
+	}
 	return yaml
 }
 
@@ -786,13 +1092,82 @@ const (
 type RestartFlinkClusterRequest struct {
 	ClusterName *string   `json:"cluster_name" binding:"required"` // flink集群名称
 	NameSpace   *string   `json:"namespace" default:"default"`
-	Type        FlinkType `json:"type" binding:"required"` // JM/TM/ALL
+	Type        FlinkType `json:"type" binding:"required"` // JM/TM/ALL，operator 集群只支持 ALL
 }
 
 type CrdFlinkTMScaleRequest struct {
 	ClusterName *string `json:"cluster_name" binding:"required"` // flink集群名称
 	NameSpace   *string `json:"namespace" default:"default"`
 	Replicas    *int32  `json:"replicas" binding:"required"` // 调整后的 TM 数量
+	Parallelism *int32  `json:"parallelism"`                 // operator native 模式的作业并行度，为空时按 replicas * taskmanager.numberOfTaskSlots 计算
+}
+
+const (
+	FlinkModeNative     = "native"
+	FlinkModeStandalone = "standalone"
+)
+
+// GetFlinkModeFromItem spec.mode，未设置时 operator 默认为 native
+func GetFlinkModeFromItem(item unstructured.Unstructured) string {
+	if mode, ok, _ := unstructured.NestedString(item.Object, "spec", "mode"); ok && mode != "" {
+		return mode
+	}
+	return FlinkModeNative
+}
+
+// ToPatch 生成 FlinkDeployment 的 merge patch。
+// standalone 模式直接调整 spec.taskManager.replicas；native 模式 TM 由 operator 按 parallelism/slots 申请，只能调整 spec.job.parallelism
+func (req *CrdFlinkTMScaleRequest) ToPatch(item unstructured.Unstructured) (map[string]any, error) {
+	if req.Replicas == nil && req.Parallelism == nil {
+		return nil, fmt.Errorf("replicas or parallelism is required")
+	}
+	if req.Replicas != nil && *req.Replicas < 0 {
+		return nil, fmt.Errorf("replicas must not be negative")
+	}
+	if req.Parallelism != nil && *req.Parallelism < 1 {
+		return nil, fmt.Errorf("parallelism must be greater than 0")
+	}
+	_, hasJob, _ := unstructured.NestedMap(item.Object, "spec", "job")
+	spec := map[string]any{}
+	if GetFlinkModeFromItem(item) == FlinkModeStandalone {
+		if req.Replicas != nil {
+			spec["taskManager"] = map[string]any{"replicas": *req.Replicas}
+		}
+		if req.Parallelism != nil {
+			if !hasJob {
+				return nil, fmt.Errorf("parallelism is only supported by application cluster")
+			}
+			spec["job"] = map[string]any{"parallelism": *req.Parallelism}
+		}
+		return map[string]any{"spec": spec}, nil
+	}
+
+	if !hasJob {
+		return nil, fmt.Errorf("native session cluster %s allocates taskmanagers on demand, scale is not supported", item.GetName())
+	}
+	parallelism := tea.Int32Value(req.Parallelism)
+	if req.Parallelism == nil {
+		slots := 1
+		if v, ok := GetFlinkConfigFromItem(item)["taskmanager.numberOfTaskSlots"]; ok && cast.ToInt(v) > 0 {
+			slots = cast.ToInt(v)
+		}
+		parallelism = *req.Replicas * int32(slots)
+		if parallelism < 1 {
+			return nil, fmt.Errorf("native application cluster needs at least 1 taskmanager")
+		}
+	}
+	spec["job"] = map[string]any{"parallelism": parallelism}
+	return map[string]any{"spec": spec}, nil
+}
+
+// NewRestartPatch 递增 spec.restartNonce，operator 会重建 JM 和 TM
+func NewRestartPatch(item unstructured.Unstructured) map[string]any {
+	nonce, _, _ := unstructured.NestedFieldNoCopy(item.Object, "spec", "restartNonce")
+	return map[string]any{
+		"spec": map[string]any{
+			"restartNonce": cast.ToInt64(nonce) + 1,
+		},
+	}
 }
 
 func fmtString(s string) int {
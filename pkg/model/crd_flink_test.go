@@ -1,11 +1,17 @@
 package model_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
 	"testing"
 
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/stretchr/testify/assert"
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestCreateFlinkClusterValidate(t *testing.T) {
@@ -162,3 +168,642 @@ func TestCreateFlinkClusterValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateFlinkClusterPodTemplateOptions(t *testing.T) {
+	req := &model.CreateFlinkClusterRequest{
+		ClusterName: tea.String("test-cluster"),
+		Submitter:   tea.String("admin"),
+		TaskManager: &model.Manager{
+			NodeSelector: &map[string]string{"env": "flink"},
+			Tolerations: []corev1.Toleration{
+				{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "flink", Effect: corev1.TaintEffectNoSchedule},
+			},
+			Volumes: []model.Volume{
+				{Name: tea.String("data"), PersistentVolumeClaim: &model.PersistentVolumeClaim{ClaimName: tea.String("data-pvc")}},
+				{Name: tea.String("cert"), Secret: &model.Secret{SecretName: tea.String("flink-cert")}},
+			},
+			VolumeMounts: []model.VolumeMount{
+				{Name: tea.String("data"), MountPath: tea.String("/data")},
+			},
+			ImagePullSecrets:  []string{"registry"},
+			PriorityClassName: tea.String("high"),
+			Annotations:       map[string]string{"prometheus.io/scrape": "true"},
+		},
+		JobManager: &model.Manager{
+			Affinity: &corev1.Affinity{
+				PodAntiAffinity: &corev1.PodAntiAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+						{Weight: 100, PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: "kubernetes.io/hostname"}},
+					},
+				},
+			},
+		},
+	}
+	assert.NoError(t, req.Validate())
+
+	yaml := req.ToYaml()
+	spec := yaml["spec"].(map[string]any)
+
+	tmTemplate := spec["taskManager"].(map[string]any)["podTemplate"].(map[string]any)
+	tmMetadata := tmTemplate["metadata"].(map[string]any)
+	assert.Equal(t, "test-cluster", tmMetadata["labels"].(map[string]any)["app"])
+	assert.Equal(t, "admin", tmMetadata["labels"].(map[string]any)["owner"])
+	assert.Equal(t, "true", tmMetadata["annotations"].(map[string]any)["prometheus.io/scrape"])
+
+	tmSpec := tmTemplate["spec"].(map[string]any)
+	assert.Equal(t, map[string]string{"env": "flink"}, tmSpec["nodeSelector"])
+	assert.Len(t, tmSpec["initContainers"], 1)
+	assert.Len(t, tmSpec["tolerations"], 1)
+	assert.Len(t, tmSpec["volumes"], 2)
+	assert.Equal(t, "high", tmSpec["priorityClassName"])
+	assert.Equal(t, []map[string]any{{"name": "registry"}}, tmSpec["imagePullSecrets"])
+	containers := tmSpec["containers"].([]map[string]any)
+	assert.Equal(t, "flink-main-container", containers[0]["name"])
+	assert.Equal(t, "/data", containers[0]["volumeMounts"].([]map[string]any)[0]["mountPath"])
+
+	jmSpec := spec["jobManager"].(map[string]any)["podTemplate"].(map[string]any)["spec"].(map[string]any)
+	assert.NotNil(t, jmSpec["affinity"].(map[string]any)["podAntiAffinity"])
+}
+
+func TestCreateFlinkClusterValidatePodOptions(t *testing.T) {
+	req := &model.CreateFlinkClusterRequest{
+		ClusterName: tea.String("test-cluster"),
+		Submitter:   tea.String("admin"),
+		TaskManager: &model.Manager{
+			VolumeMounts: []model.VolumeMount{
+				{Name: tea.String("missing"), MountPath: tea.String("/data")},
+			},
+		},
+	}
+	err := req.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "task_manager.volume_mounts: volume missing is not defined")
+
+	req.TaskManager = &model.Manager{
+		Volumes: []model.Volume{
+			{Name: tea.String("data"), EmptyDir: &model.EmptyDir{}, Secret: &model.Secret{SecretName: tea.String("s")}},
+		},
+	}
+	err = req.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must set exactly one of")
+
+	req.TaskManager = &model.Manager{
+		Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists, Value: "flink"}},
+	}
+	assert.ErrorContains(t, req.Validate(), "task_manager.tolerations[0]: value must be empty when operator is Exists")
+	req.TaskManager = nil
+	req.JobManager = &model.Manager{
+		Tolerations: []corev1.Toleration{{Key: "dedicated", Value: "flink", Effect: "NoRun"}},
+	}
+	assert.ErrorContains(t, req.Validate(), "job_manager.tolerations[0]: effect must be")
+}
+
+// mergePodTemplate 和 operator 默认的合并方式一致：map 递归合并，数组按下标合并，角色的值优先
+func mergePodTemplate(base, role any) any {
+	switch r := role.(type) {
+	case map[string]any:
+		b, ok := base.(map[string]any)
+		if !ok {
+			return r
+		}
+		merged := map[string]any{}
+		for k, v := range b {
+			merged[k] = v
+		}
+		for k, v := range r {
+			merged[k] = mergePodTemplate(b[k], v)
+		}
+		return merged
+	case []any:
+		b, _ := base.([]any)
+		merged := make([]any, max(len(b), len(r)))
+		for i := range merged {
+			switch {
+			case i < len(b) && i < len(r):
+				merged[i] = mergePodTemplate(b[i], r[i])
+			case i < len(r):
+				merged[i] = r[i]
+			default:
+				merged[i] = b[i]
+			}
+		}
+		return merged
+	}
+	return role
+}
+
+// 角色的卷和挂载不能覆盖共用 podTemplate 中的日志卷、日志挂载和 env
+func TestCreateFlinkClusterPodTemplateMerge(t *testing.T) {
+	req := &model.CreateFlinkClusterRequest{
+		ClusterName:   tea.String("test-cluster"),
+		Submitter:     tea.String("admin"),
+		Env:           []model.Env{{Name: tea.String("TZ"), Value: tea.String("Asia/Shanghai")}},
+		LogCollection: &model.FlinkLogCollection{VolumeType: model.LogVolumeEmptyDir},
+		TaskManager: &model.Manager{
+			Volumes:      []model.Volume{{Name: tea.String("data"), PersistentVolumeClaim: &model.PersistentVolumeClaim{ClaimName: tea.String("data-pvc")}}},
+			VolumeMounts: []model.VolumeMount{{Name: tea.String("data"), MountPath: tea.String("/data")}},
+		},
+		// 挂载共用 podTemplate 中的日志卷
+		JobManager: &model.Manager{
+			VolumeMounts: []model.VolumeMount{{Name: tea.String("flink-logs"), MountPath: tea.String("/var/log/flink")}},
+		},
+	}
+	assert.NoError(t, req.Validate())
+
+	var yaml map[string]any
+	data, _ := json.Marshal(req.ToYaml())
+	assert.NoError(t, json.Unmarshal(data, &yaml))
+	spec := yaml["spec"].(map[string]any)
+	shared := spec["podTemplate"].(map[string]any)
+	// 共用 podTemplate 不受角色配置影响
+	assert.Len(t, shared["spec"].(map[string]any)["containers"].([]any)[0].(map[string]any)["volumeMounts"], 1)
+
+	tm := mergePodTemplate(shared, spec["taskManager"].(map[string]any)["podTemplate"]).(map[string]any)["spec"].(map[string]any)
+	assert.Equal(t, []any{
+		map[string]any{"name": "flink-logs", "emptyDir": map[string]any{}},
+		map[string]any{"name": "data", "persistentVolumeClaim": map[string]any{"claimName": "data-pvc", "readOnly": false}},
+	}, tm["volumes"])
+	container := tm["containers"].([]any)[0].(map[string]any)
+	assert.Equal(t, "flink-main-container", container["name"])
+	assert.Equal(t, []any{
+		map[string]any{"name": "flink-logs", "mountPath": "/opt/flink/log"},
+		map[string]any{"name": "data", "mountPath": "/data"},
+	}, container["volumeMounts"])
+	assert.Equal(t, []any{map[string]any{"name": "TZ", "value": "Asia/Shanghai"}}, container["env"])
+	assert.Len(t, tm["initContainers"], 1)
+
+	jm := mergePodTemplate(shared, spec["jobManager"].(map[string]any)["podTemplate"]).(map[string]any)["spec"].(map[string]any)
+	assert.Len(t, jm["volumes"], 1)
+	assert.Equal(t, []any{
+		map[string]any{"name": "flink-logs", "mountPath": "/opt/flink/log"},
+		map[string]any{"name": "flink-logs", "mountPath": "/var/log/flink"},
+	}, jm["containers"].([]any)[0].(map[string]any)["volumeMounts"])
+
+	// 没有共用 podTemplate 时 flink-logs 不存在
+	req.Env, req.LogCollection, req.TaskManager = nil, nil, nil
+	assert.ErrorContains(t, req.Validate(), "job_manager.volume_mounts: volume flink-logs is not defined")
+	_, ok := req.ToYaml()["spec"].(map[string]any)["podTemplate"]
+	assert.False(t, ok)
+	// 和共用 podTemplate 的卷重名
+	req.Env = []model.Env{{Name: tea.String("TZ"), Value: tea.String("UTC")}}
+	req.JobManager = &model.Manager{Volumes: []model.Volume{{Name: tea.String("flink-logs"), EmptyDir: &model.EmptyDir{}}}}
+	assert.ErrorContains(t, req.Validate(), "job_manager.volumes: duplicate volume name flink-logs")
+}
+
+func TestCreateFlinkClusterLogCollection(t *testing.T) {
+	req := &model.CreateFlinkClusterRequest{
+		ClusterName: tea.String("test-cluster"),
+		Submitter:   tea.String("admin"),
+		LogCollection: &model.FlinkLogCollection{
+			VolumeType: model.LogVolumePVC,
+			ClaimName:  tea.String("flink-logs-pvc"),
+			SideCar: &model.LogSideCar{
+				Image:  tea.String("fluent/fluent-bit:2.2.0"),
+				Args:   []string{"-c", "/etc/log-shipper/fluent-bit.conf"},
+				Config: map[string]string{"fluent-bit.conf": "[INPUT]\n    Name tail"},
+			},
+			Log4jConfig: tea.String("rootLogger.level = INFO"),
+		},
+	}
+	assert.NoError(t, req.Validate())
+
+	spec := req.ToYaml()["spec"].(map[string]any)
+	assert.Equal(t, map[string]any{"log4j-console.properties": "rootLogger.level = INFO"}, spec["logConfiguration"])
+
+	podSpec := spec["podTemplate"].(map[string]any)["spec"].(map[string]any)
+	volumes := podSpec["volumes"].([]map[string]any)
+	assert.Len(t, volumes, 2)
+	assert.Equal(t, "flink-logs-pvc", volumes[0]["persistentVolumeClaim"].(map[string]any)["claimName"])
+	assert.Equal(t, "test-cluster-log-config", volumes[1]["configMap"].(map[string]any)["name"])
+
+	containers := podSpec["containers"].([]map[string]any)
+	assert.Len(t, containers, 2)
+	assert.Equal(t, "log-shipper", containers[1]["name"])
+	assert.Equal(t, "fluent/fluent-bit:2.2.0", containers[1]["image"])
+
+	configMap := req.NewLogConfigMap()
+	assert.NotNil(t, configMap)
+	assert.Equal(t, "test-cluster-log-config", *configMap.Name)
+	assert.Equal(t, "admin", configMap.Labels["owner"])
+
+	// 兼容 enable_fluentbit
+	legacy := &model.CreateFlinkClusterRequest{
+		ClusterName:    tea.String("test-cluster"),
+		EnableFluentit: tea.Bool(true),
+	}
+	legacySpec := legacy.ToYaml()["spec"].(map[string]any)["podTemplate"].(map[string]any)["spec"].(map[string]any)
+	assert.Equal(t, "fluentbit", legacySpec["containers"].([]map[string]any)[1]["name"])
+	assert.Equal(t, "/mnt/log/test-cluster/", legacySpec["volumes"].([]map[string]any)[0]["hostPath"].(map[string]any)["path"])
+	assert.Nil(t, legacy.NewLogConfigMap())
+
+	req.LogCollection = &model.FlinkLogCollection{VolumeType: model.LogVolumePVC}
+	assert.Error(t, req.Validate())
+}
+
+func TestCreateFlinkClusterState(t *testing.T) {
+	req := &model.CreateFlinkClusterRequest{
+		ClusterName:        tea.String("test-cluster"),
+		Submitter:          tea.String("admin"),
+		FlinkConfiguration: map[string]any{"taskmanager.numberOfTaskSlots": "2"},
+		State: &model.FlinkState{
+			HighAvailability:          tea.Bool(true),
+			HaStorageDir:              tea.String("s3://bucket/flink/ha"),
+			StateBackend:              tea.String("rocksdb"),
+			Incremental:               tea.Bool(true),
+			CheckpointDir:             tea.String("s3://bucket/flink/checkpoints"),
+			SavepointDir:              tea.String("s3://bucket/flink/savepoints"),
+			CheckpointInterval:        tea.String("60s"),
+			RetainedCheckpoints:       tea.Int(3),
+			PeriodicSavepointInterval: tea.String("6h"),
+			Credentials: &model.StateCredentials{
+				SecretName: tea.String("flink-s3"),
+				Endpoint:   tea.String("cos.ap-shanghai.myqcloud.com"),
+			},
+		},
+		Job: &model.Job{
+			JarURI:      tea.String("local:///opt/flink/examples/streaming/StateMachineExample.jar"),
+			UpgradeMode: tea.String("last-state"),
+		},
+	}
+	assert.NoError(t, req.Validate())
+
+	spec := req.ToYaml()["spec"].(map[string]any)
+	config := spec["flinkConfiguration"].(map[string]any)
+	assert.Equal(t, "2", config["taskmanager.numberOfTaskSlots"])
+	assert.Equal(t, "org.apache.flink.kubernetes.highavailability.KubernetesHaServicesFactory", config["high-availability"])
+	assert.Equal(t, "s3://bucket/flink/ha", config["high-availability.storageDir"])
+	assert.Equal(t, "rocksdb", config["state.backend"])
+	assert.Equal(t, "true", config["state.backend.incremental"])
+	assert.Equal(t, "60s", config["execution.checkpointing.interval"])
+	assert.Equal(t, "3", config["state.checkpoints.num-retained"])
+	assert.Equal(t, "6h", config["kubernetes.operator.periodic.savepoint.interval"])
+	assert.Equal(t, "cos.ap-shanghai.myqcloud.com", config["s3.endpoint"])
+
+	env := spec["podTemplate"].(map[string]any)["spec"].(map[string]any)["containers"].([]map[string]any)[0]["env"].([]map[string]any)
+	assert.Len(t, env, 2)
+	assert.Equal(t, "AWS_ACCESS_KEY_ID", env[0]["name"])
+	assert.Equal(t, "flink-s3", env[0]["valueFrom"].(map[string]any)["secretKeyRef"].(map[string]any)["name"])
+}
+
+func TestFlinkStateValidate(t *testing.T) {
+	jar := tea.String("local:///opt/flink/examples/streaming/StateMachineExample.jar")
+	tests := []struct {
+		name     string
+		state    *model.FlinkState
+		job      *model.Job
+		config   map[string]any
+		errorMsg string
+	}{
+		{
+			name:     "无效的状态后端",
+			state:    &model.FlinkState{StateBackend: tea.String("memory")},
+			errorMsg: "state.state_backend must be one of",
+		},
+		{
+			name:     "hashmap不支持增量",
+			state:    &model.FlinkState{Incremental: tea.Bool(true)},
+			errorMsg: "state.incremental is only supported by rocksdb",
+		},
+		{
+			name:     "HA缺少目录",
+			state:    &model.FlinkState{HighAvailability: tea.Bool(true)},
+			errorMsg: "state.ha_storage_dir is required",
+		},
+		{
+			name:     "不支持的存储",
+			state:    &model.FlinkState{CheckpointDir: tea.String("file:///tmp/checkpoints")},
+			errorMsg: "state.checkpoint_dir must start with",
+		},
+		{
+			name: "凭证对应多种存储",
+			state: &model.FlinkState{
+				CheckpointDir: tea.String("s3://bucket/checkpoints"),
+				SavepointDir:  tea.String("oss://bucket/savepoints"),
+				Credentials:   &model.StateCredentials{SecretName: tea.String("secret")},
+			},
+			errorMsg: "all directories use the same storage",
+		},
+		{
+			name: "hdfs不支持凭证",
+			state: &model.FlinkState{
+				CheckpointDir: tea.String("hdfs:///flink/checkpoints"),
+				Credentials:   &model.StateCredentials{SecretName: tea.String("secret")},
+			},
+			errorMsg: "not supported for hdfs",
+		},
+		{
+			name:     "无效的时间格式",
+			state:    &model.FlinkState{CheckpointDir: tea.String("s3://bucket/checkpoints"), CheckpointInterval: tea.String("1 minute later")},
+			errorMsg: "state.checkpoint_interval must be a valid duration",
+		},
+		{
+			name:     "定期savepoint缺少目录",
+			state:    &model.FlinkState{PeriodicSavepointInterval: tea.String("6h")},
+			job:      &model.Job{JarURI: jar},
+			errorMsg: "state.savepoint_dir is required when periodic savepoint",
+		},
+		{
+			name:     "last-state需要HA",
+			state:    &model.FlinkState{CheckpointDir: tea.String("s3://bucket/checkpoints")},
+			job:      &model.Job{JarURI: jar, UpgradeMode: tea.String("last-state")},
+			errorMsg: "requires state.high_availability",
+		},
+		{
+			name:     "savepoint升级模式需要目录",
+			state:    &model.FlinkState{CheckpointDir: tea.String("s3://bucket/checkpoints")},
+			job:      &model.Job{JarURI: jar, UpgradeMode: tea.String("savepoint")},
+			errorMsg: "requires state.savepoint_dir",
+		},
+		{
+			name:     "与flink_configuration冲突",
+			state:    &model.FlinkState{StateBackend: tea.String("rocksdb")},
+			config:   map[string]any{"state.backend": "hashmap"},
+			errorMsg: "flink_configuration.state.backend conflicts with state",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &model.CreateFlinkClusterRequest{
+				ClusterName:        tea.String("test-cluster"),
+				Submitter:          tea.String("admin"),
+				FlinkConfiguration: tt.config,
+				State:              tt.state,
+				Job:                tt.job,
+			}
+			err := req.Validate()
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestCrdFlinkTMScaleToPatch(t *testing.T) {
+	newItem := func(spec map[string]any) unstructured.Unstructured {
+		item := unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "flink.apache.org/v1beta1",
+			"kind":       "FlinkDeployment",
+			"spec":       spec,
+		}}
+		item.SetName("demo")
+		return item
+	}
+	standalone := newItem(map[string]any{
+		"mode":        "standalone",
+		"taskManager": map[string]any{"replicas": int64(2)},
+	})
+	nativeApp := newItem(map[string]any{
+		"flinkConfiguration": map[string]any{"taskmanager.numberOfTaskSlots": "2"},
+		"job":                map[string]any{"parallelism": int64(2)},
+	})
+	nativeSession := newItem(map[string]any{
+		"flinkConfiguration": map[string]any{"taskmanager.numberOfTaskSlots": "2"},
+	})
+
+	assert.Equal(t, model.FlinkModeStandalone, model.GetFlinkModeFromItem(standalone))
+	assert.Equal(t, model.FlinkModeNative, model.GetFlinkModeFromItem(nativeApp))
+
+	req := model.CrdFlinkTMScaleRequest{ClusterName: tea.String("demo"), Replicas: tea.Int32(4)}
+	patch, err := req.ToPatch(standalone)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"spec": map[string]any{"taskManager": map[string]any{"replicas": int32(4)}}}, patch)
+
+	// native 按 replicas * slots 计算并行度
+	patch, err = req.ToPatch(nativeApp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"spec": map[string]any{"job": map[string]any{"parallelism": int32(8)}}}, patch)
+
+	patch, err = (&model.CrdFlinkTMScaleRequest{ClusterName: tea.String("demo"), Parallelism: tea.Int32(3)}).ToPatch(nativeApp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"spec": map[string]any{"job": map[string]any{"parallelism": int32(3)}}}, patch)
+
+	_, err = req.ToPatch(nativeSession)
+	assert.ErrorContains(t, err, "scale is not supported")
+
+	_, err = (&model.CrdFlinkTMScaleRequest{ClusterName: tea.String("demo"), Parallelism: tea.Int32(3)}).ToPatch(standalone)
+	assert.ErrorContains(t, err, "only supported by application cluster")
+
+	_, err = (&model.CrdFlinkTMScaleRequest{ClusterName: tea.String("demo")}).ToPatch(standalone)
+	assert.ErrorContains(t, err, "replicas or parallelism is required")
+}
+
+func TestNewRestartPatch(t *testing.T) {
+	item := unstructured.Unstructured{Object: map[string]any{"spec": map[string]any{}}}
+	assert.Equal(t, map[string]any{"spec": map[string]any{"restartNonce": int64(1)}}, model.NewRestartPatch(item))
+
+	item.Object["spec"] = map[string]any{"restartNonce": int64(5)}
+	assert.Equal(t, map[string]any{"spec": map[string]any{"restartNonce": int64(6)}}, model.NewRestartPatch(item))
+}
+
+func TestCreateFlinkClusterAutoscaler(t *testing.T) {
+	req := &model.CreateFlinkClusterRequest{
+		ClusterName: tea.String("test-cluster"),
+		Submitter:   tea.String("admin"),
+		Job:         &model.Job{JarURI: tea.String("local:///opt/flink/usrlib/app.jar"), Parallelism: tea.Int32(2)},
+		Autoscaler: &model.FlinkAutoscaler{
+			TargetUtilization:     tea.Float64(0.6),
+			StabilizationInterval: tea.String("5min"),
+			MetricsWindow:         tea.String("10min"),
+			MinParallelism:        tea.Int(1),
+			MaxParallelism:        tea.Int(8),
+		},
+		FlinkConfiguration: map[string]any{"taskmanager.numberOfTaskSlots": "2"},
+	}
+	assert.NoError(t, req.Validate())
+	config := req.ToYaml()["spec"].(map[string]any)["flinkConfiguration"].(map[string]any)
+	assert.Equal(t, "true", config["job.autoscaler.enabled"])
+	assert.Equal(t, "0.6", config["job.autoscaler.target.utilization"])
+	assert.Equal(t, "5min", config["job.autoscaler.stabilization.interval"])
+	assert.Equal(t, "10min", config["job.autoscaler.metrics.window"])
+	assert.Equal(t, "1", config["job.autoscaler.vertex.min-parallelism"])
+	assert.Equal(t, "8", config["job.autoscaler.vertex.max-parallelism"])
+	assert.Equal(t, "2", config["taskmanager.numberOfTaskSlots"])
+
+	for _, tt := range []struct {
+		name       string
+		job        *model.Job
+		autoscaler *model.FlinkAutoscaler
+		config     map[string]any
+		errorMsg   string
+	}{
+		{"session集群不支持", nil, &model.FlinkAutoscaler{}, nil, "only supported by application cluster"},
+		{"利用率范围", req.Job, &model.FlinkAutoscaler{TargetUtilization: tea.Float64(1.2)}, nil, "target_utilization must be in (0, 1]"},
+		{"时间格式", req.Job, &model.FlinkAutoscaler{MetricsWindow: tea.String("ten minutes")}, nil, "metrics_window must be a valid duration"},
+		{"最小大于最大", req.Job, &model.FlinkAutoscaler{MinParallelism: tea.Int(4), MaxParallelism: tea.Int(2)}, nil, "min_parallelism must not be greater than max_parallelism"},
+		{"与flink_configuration冲突", req.Job, &model.FlinkAutoscaler{Enabled: tea.Bool(true)}, map[string]any{"job.autoscaler.enabled": "false"}, "conflicts with autoscaler"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&model.CreateFlinkClusterRequest{
+				ClusterName:        tea.String("test-cluster"),
+				Submitter:          tea.String("admin"),
+				Job:                tt.job,
+				Autoscaler:         tt.autoscaler,
+				FlinkConfiguration: tt.config,
+			}).Validate()
+			assert.ErrorContains(t, err, tt.errorMsg)
+		})
+	}
+}
+
+func TestNewFlinkAutoscalerStatus(t *testing.T) {
+	compress := func(data string) string {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write([]byte(data))
+		w.Close()
+		return base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	item := map[string]any{
+		"metadata": map[string]any{"name": "demo", "namespace": "flink"},
+		"spec": map[string]any{
+			"flinkConfiguration": map[string]any{
+				"job.autoscaler.enabled":         "true",
+				"job.autoscaler.scaling.enabled": "false",
+			},
+		},
+		"status": map[string]any{
+			"lifecycleState": "STABLE",
+			"jobStatus":      map[string]any{"state": "RUNNING"},
+		},
+	}
+	history := `
+v1:
+  "2026-10-01T10:00:00Z":
+    currentParallelism: 2
+    newParallelism: 4
+    metrics:
+      TRUE_PROCESSING_RATE: {average: 100.5, current: NaN}
+  "2026-10-02T10:00:00.5Z":
+    currentParallelism: 4
+    newParallelism: 3
+v2:
+  "2026-10-01T12:00:00Z":
+    currentParallelism: 1
+    newParallelism: 2
+`
+	status, err := model.NewFlinkAutoscalerStatus(item, map[string]string{
+		"scalingHistory":       compress(history),
+		"parallelismOverrides": "v1:3,v2:2",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "demo", status.ClusterName)
+	assert.True(t, status.Enabled)
+	assert.False(t, status.ScalingEnabled)
+	assert.Equal(t, "STABLE", status.LifecycleState)
+	assert.Equal(t, "RUNNING", status.JobState)
+	assert.Equal(t, map[string]int{"v1": 3, "v2": 2}, status.ParallelismOverrides)
+	assert.Len(t, status.ScalingHistory, 3)
+	assert.Equal(t, "v1", status.ScalingHistory[0].VertexID)
+	assert.Equal(t, 3, status.ScalingHistory[0].NewParallelism)
+	assert.Equal(t, "v2", status.ScalingHistory[1].VertexID)
+	assert.Equal(t, 100.5, *status.ScalingHistory[2].Metrics["TRUE_PROCESSING_RATE"].Average)
+	assert.Nil(t, status.ScalingHistory[2].Metrics["TRUE_PROCESSING_RATE"].Current)
+	_, err = json.Marshal(status)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"v1": 3, "v2": 2}, status.Recommendations())
+
+	// 没有 ConfigMap 时兼容老版本写在 flinkConfiguration 中的建议
+	item["spec"].(map[string]any)["flinkConfiguration"].(map[string]any)["pipeline.jobvertex-parallelism-overrides"] = "v1:5"
+	status, err = model.NewFlinkAutoscalerStatus(item, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"v1": 5}, status.Recommendations())
+	assert.Empty(t, status.ScalingHistory)
+}
+
+func TestCreateFlinkClusterIngress(t *testing.T) {
+	req := &model.CreateFlinkClusterRequest{
+		ClusterName: tea.String("test-cluster"),
+		Submitter:   tea.String("admin"),
+		Ingress: &model.FlinkIngress{
+			Template:    tea.String("{{name}}.{{namespace}}.flink.example.com"),
+			ClassName:   tea.String("nginx"),
+			Annotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "100m"},
+		},
+	}
+	assert.NoError(t, req.Validate())
+	ingress := req.ToYaml()["spec"].(map[string]any)["ingress"].(map[string]any)
+	assert.Equal(t, "{{name}}.{{namespace}}.flink.example.com", ingress["template"])
+	assert.Equal(t, "nginx", ingress["className"])
+	assert.Equal(t, map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "100m"}, ingress["annotations"])
+
+	host, path := req.Ingress.Render("flink", "test-cluster")
+	assert.Equal(t, "test-cluster.flink.flink.example.com", host)
+	assert.Equal(t, "/", path)
+
+	req.Ingress.Template = tea.String("flink.example.com/{{namespace}}")
+	assert.ErrorContains(t, req.Validate(), "ingress.template must contain {{name}}")
+}
+
+func TestNewFlinkClusterFromOperator(t *testing.T) {
+	newItem := func(job map[string]any, status map[string]any) unstructured.Unstructured {
+		spec := map[string]any{"flinkVersion": "v1_17", "image": "flink:1.17", "taskManager": map[string]any{"replicas": int64(2)}}
+		if job != nil {
+			spec["job"] = job
+		}
+		return unstructured.Unstructured{Object: map[string]any{
+			"metadata": map[string]any{"name": "demo", "namespace": "flink", "labels": map[string]any{"owner": "alice"}},
+			"spec":     spec,
+			"status":   status,
+		}}
+	}
+	tests := []struct {
+		name   string
+		job    map[string]any
+		status map[string]any
+		want   model.FlinkClusterState
+	}{
+		{"new", nil, nil, model.FlinkClusterDeploying},
+		{"session ready", nil, map[string]any{"jobManagerDeploymentStatus": "READY", "lifecycleState": "STABLE"}, model.FlinkClusterRunning},
+		{"job created", map[string]any{}, map[string]any{"jobManagerDeploymentStatus": "READY", "jobStatus": map[string]any{"state": "CREATED"}}, model.FlinkClusterDeploying},
+		{"job running", map[string]any{}, map[string]any{"jobManagerDeploymentStatus": "READY", "jobStatus": map[string]any{"state": "RUNNING"}}, model.FlinkClusterRunning},
+		{"job failed", map[string]any{}, map[string]any{"jobManagerDeploymentStatus": "READY", "jobStatus": map[string]any{"state": "FAILED"}}, model.FlinkClusterFailed},
+		{"jm error", nil, map[string]any{"jobManagerDeploymentStatus": "ERROR", "error": "crash loop"}, model.FlinkClusterFailed},
+		{"suspended", map[string]any{"state": "suspended"}, map[string]any{"lifecycleState": "SUSPENDED"}, model.FlinkClusterSuspended},
+		{"jm missing", nil, map[string]any{"jobManagerDeploymentStatus": "MISSING", "lifecycleState": "STABLE"}, model.FlinkClusterUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := model.NewFlinkClusterFromOperator(newItem(tt.job, tt.status))
+			assert.Equal(t, tt.want, c.Status.State)
+			assert.Equal(t, model.FlinkFlavorOperator, c.Flavor)
+			assert.Equal(t, "alice", c.Owner)
+			assert.Equal(t, "v1_17", c.Version)
+			assert.Equal(t, int32(2), c.Status.TaskManagerReplicas)
+		})
+	}
+	assert.Equal(t, "crash loop", model.NewFlinkClusterFromOperator(newItem(nil, map[string]any{"jobManagerDeploymentStatus": "ERROR", "error": "crash loop"})).Status.Error)
+	assert.Equal(t, "application", model.NewFlinkClusterFromOperator(newItem(map[string]any{}, nil)).Mode)
+}
+
+func TestFilterFlinkCluster(t *testing.T) {
+	items := []model.FlinkCluster{
+		{Flavor: model.FlinkFlavorOperator, ClusterName: "b", Owner: "alice", Version: "v1_17", Status: model.FlinkClusterStatus{State: model.FlinkClusterRunning}},
+		{Flavor: model.FlinkFlavorV12, ClusterName: "a", Owner: "bob", Version: model.FlinkV12Version, Status: model.FlinkClusterStatus{State: model.FlinkClusterFailed}},
+		{Flavor: model.FlinkFlavorOperator, ClusterName: "c", Owner: "bob", Version: "v1_17", Status: model.FlinkClusterStatus{State: model.FlinkClusterDeploying}},
+	}
+	match := func(filter model.FilterFlinkCluster) []string {
+		var names []string
+		for _, c := range items {
+			if filter.Match(c) {
+				names = append(names, c.ClusterName)
+			}
+		}
+		return names
+	}
+	assert.Equal(t, []string{"b", "a", "c"}, match(model.FilterFlinkCluster{}))
+	assert.Equal(t, []string{"a"}, match(model.FilterFlinkCluster{Flavor: model.FlinkFlavorV12}))
+	assert.Equal(t, []string{"a", "c"}, match(model.FilterFlinkCluster{Owner: tea.String("bob")}))
+	assert.Equal(t, []string{"b", "c"}, match(model.FilterFlinkCluster{Version: tea.String("1.17")}))
+	assert.Equal(t, []string{"b", "a"}, match(model.FilterFlinkCluster{State: tea.String("running, failed")}))
+
+	assert.Error(t, (&model.FilterFlinkCluster{Flavor: "v1_13"}).Validate())
+	assert.Error(t, (&model.FilterFlinkCluster{State: tea.String("STOPPED")}).Validate())
+	assert.Error(t, (&model.FilterFlinkCluster{SortBy: tea.String("cpu")}).Validate())
+
+	filter := model.FilterFlinkCluster{SortBy: tea.String("state"), Desc: tea.Bool(true)}
+	filter.Sort(items)
+	assert.Equal(t, []string{"b", "a", "c"}, []string{items[0].ClusterName, items[1].ClusterName, items[2].ClusterName})
+	(&model.FilterFlinkCluster{}).Sort(items)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{items[0].ClusterName, items[1].ClusterName, items[2].ClusterName})
+}
@@ -1,5 +1,18 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 默认挂载到 driver 和 executor 的 /tmp hostPath 卷
+const sparkDefaultVolume = "test-volume"
+
 // https://github.com/kubeflow/spark-operator/blob/master/docs/quick-start-guide.md
 
 type CrdSparkApplicationGetResponse struct {
@@ -8,13 +21,21 @@ type CrdSparkApplicationGetResponse struct {
 }
 
 type CrdSparkApplication struct {
-	Name       string `json:"name"`
-	Namespace  string `json:"namespace"`
-	Status     string `json:"status"`      // 状态 COMPLETED,
-	Attempts   int64  `json:"attempts"`    // 重试次数
-	StartTime  string `json:"start_time"`  // 启动时间 2024-03-29T05:32:42Z
-	FinishTime string `json:"finish_time"` // 完成时间 2024-03-29T05:32:42Z
-	Age        string `json:"age"`         // 运行时间 1d
+	Name               string            `json:"name"`
+	Namespace          string            `json:"namespace"`
+	Status             string            `json:"status"`                  // 状态 COMPLETED，刚提交还没有状态时为 NEW
+	ErrorMessage       string            `json:"error_message,omitempty"` // 失败原因
+	ApplicationID      string            `json:"application_id"`          // spark-xxx
+	SubmissionID       string            `json:"submission_id"`
+	Attempts           int64             `json:"attempts"`            // 重试次数
+	SubmissionAttempts int64             `json:"submission_attempts"` // 提交次数
+	CreateTime         time.Time         `json:"create_time"`
+	StartTime          *time.Time        `json:"start_time"`  // 最后一次提交时间，没有提交时为 null
+	FinishTime         *time.Time        `json:"finish_time"` // 结束时间，运行中为 null
+	Age                string            `json:"age"`         // 创建到现在的时间 24h0m0s
+	Driver             SparkDriverInfo   `json:"driver"`
+	Executors          map[string]string `json:"executors"`        // executor pod 名称 -> 状态
+	ExecutorSummary    map[string]int    `json:"executor_summary"` // 各状态的 executor 数量
 }
 
 type Driver struct {
@@ -22,6 +43,7 @@ type Driver struct {
 	CoreLimit string            `json:"coreLimit" ` // default:1200m
 	Memory    string            `json:"memory" `    // default:512m
 	Labels    map[string]string `json:"labels" `
+	SparkPodSpec
 }
 
 type Executor struct {
@@ -29,21 +51,150 @@ type Executor struct {
 	Instances int               `json:"instances" ` // default:2
 	Memory    string            `json:"memory" `    // default:512m
 	Labels    map[string]string `json:"labels" `
+	SparkPodSpec
+}
+
+// SparkPodSpec driver 和 executor 共用的 pod 配置
+type SparkPodSpec struct {
+	Image          *string             `json:"image"` // 默认使用 spec.image
+	MemoryOverhead *string             `json:"memoryOverhead"`
+	JavaOptions    *string             `json:"javaOptions"`
+	ServiceAccount *string             `json:"serviceAccount"` // driver 默认 spark，设置为空字符串时使用 namespace 的 default
+	Annotations    map[string]string   `json:"annotations"`
+	Env            []Env               `json:"env"`
+	SecretEnv      []SparkSecretEnv    `json:"secretEnv"`  // 从 Secret 读取的环境变量
+	Secrets        []SparkSecret       `json:"secrets"`    // 挂载 Secret
+	ConfigMaps     []SparkConfigMap    `json:"configMaps"` // 挂载 ConfigMap
+	NodeSelector   map[string]string   `json:"nodeSelector"`
+	Tolerations    []corev1.Toleration `json:"tolerations"`
+	Affinity       *corev1.Affinity    `json:"affinity"`
+	VolumeMounts   []VolumeMount       `json:"volumeMounts"` // 挂载 spec.volumes 中的卷，设置后替换默认的 test-volume 挂载
+}
+
+type SparkSecretEnv struct {
+	Name       *string `json:"name" binding:"required"`       // 环境变量名称
+	SecretName *string `json:"secretName" binding:"required"` // Secret 名称
+	Key        *string `json:"key" binding:"required"`        // Secret 中的 key
+}
+
+type SparkSecret struct {
+	Name       *string `json:"name" binding:"required"`
+	Path       *string `json:"path" binding:"required"` // 挂载路径
+	SecretType *string `json:"secretType"`              // GCPServiceAccount、HadoopDelegationToken、Generic，默认 Generic
+}
+
+type SparkConfigMap struct {
+	Name *string `json:"name" binding:"required"`
+	Path *string `json:"path" binding:"required"` // 挂载路径
+}
+
+// SparkDeps 任务依赖，字段和 SparkApplication 的 spec.deps 一致，支持 local://、http://、s3a:// 等地址，packages 为 maven 坐标
+type SparkDeps struct {
+	Jars            []string `json:"jars"`
+	Files           []string `json:"files"`
+	PyFiles         []string `json:"pyFiles"`
+	Packages        []string `json:"packages"`
+	ExcludePackages []string `json:"excludePackages"`
+	Repositories    []string `json:"repositories"`
 }
 
 type CreateSparkApplicationRequest struct {
-	Name                *string   `json:"name" binding:"required"` // spark-pi-example
-	Namespace           *string   `json:"namespace"`
-	Type                *string   `json:"type" `                 // Scala
-	Mode                *string   `json:"mode" `                 // cluster
-	Image               *string   `json:"image"`                 // apache/spark-py:v3.2.1
-	SparkVersion        *string   `json:"spark_version"`         // 3.2.1
-	MainClass           *string   `json:"main_class"`            // org.apache.spark.examples.SparkPi
-	MainApplicationFile *string   `json:"main_application_file"` // local:///opt/spark/examples/jars/spark-examples_2.12-3.2.1.jar
-	RestartPolicy       *string   `json:"restart_policy"`        // Never
-	Driver              *Driver   `json:"driver"`
-	Executor            *Executor `json:"executor"`
-	EnableMonitoring    bool      `json:"enable_monitoring"` // prometheus port 8090
+	Name                *string                 `json:"name" binding:"required"` // spark-pi-example
+	Namespace           *string                 `json:"namespace"`
+	Type                *string                 `json:"type" `                 // Scala、Java、Python、R
+	PythonVersion       *string                 `json:"python_version"`        // Python 任务使用，2 或者 3，默认 3
+	Mode                *string                 `json:"mode" `                 // cluster
+	Image               *string                 `json:"image"`                 // apache/spark-py:v3.2.1
+	SparkVersion        *string                 `json:"spark_version"`         // 3.2.1，为空时使用镜像 tag 中的版本
+	MainClass           *string                 `json:"main_class"`            // org.apache.spark.examples.SparkPi，Python 和 R 不需要
+	MainApplicationFile *string                 `json:"main_application_file"` // local:///opt/spark/examples/jars/spark-examples_2.12-3.2.1.jar，支持 local、http(s)、s3(a)
+	RestartPolicy       *string                 `json:"restart_policy"`        // Never、OnFailure、Always
+	Retry               *SparkRetry             `json:"retry"`                 // OnFailure、Always 的重试次数和间隔
+	Driver              *Driver                 `json:"driver"`
+	Executor            *Executor               `json:"executor"`
+	EnableMonitoring    bool                    `json:"enable_monitoring"` // prometheus port 8090
+	SparkConf           map[string]string       `json:"spark_conf"`        // spark.* 配置
+	HadoopConf          map[string]string       `json:"hadoop_conf"`       // 不需要 spark.hadoop. 前缀
+	Arguments           []string                `json:"arguments"`         // main 函数的参数
+	Deps                *SparkDeps              `json:"deps"`              // jars、files、pyFiles、packages 依赖
+	Volumes             []Volume                `json:"volumes"`           // 设置后替换默认的 /tmp hostPath test-volume，空数组表示不使用卷
+	ImagePullPolicy     *string                 `json:"image_pull_policy"` // 默认 Always
+	ImagePullSecrets    []string                `json:"image_pull_secrets"`
+	DynamicAllocation   *SparkDynamicAllocation `json:"dynamic_allocation"`
+	TimeToLiveSeconds   *int64                  `json:"time_to_live_seconds"` // 结束后多久由 operator 删除，不设置时一直保留
+	BatchScheduler      *BatchScheduler         `json:"batch_scheduler"`      // volcano 或 yunikorn，gang 只支持 min_resources
+}
+
+// SparkRetry 重试次数只对 OnFailure 生效，间隔单位为秒
+type SparkRetry struct {
+	OnFailureRetries                 *int32 `json:"on_failure_retries"`
+	OnFailureRetryInterval           *int64 `json:"on_failure_retry_interval"`
+	OnSubmissionFailureRetries       *int32 `json:"on_submission_failure_retries"`
+	OnSubmissionFailureRetryInterval *int64 `json:"on_submission_failure_retry_interval"`
+}
+
+// SparkDynamicAllocation spark 3.0 以上通过 shuffle tracking 实现，不需要 external shuffle service
+type SparkDynamicAllocation struct {
+	Enabled                bool   `json:"enabled"`
+	InitialExecutors       *int32 `json:"initial_executors"`
+	MinExecutors           *int32 `json:"min_executors"`
+	MaxExecutors           *int32 `json:"max_executors"`
+	ShuffleTrackingTimeout *int64 `json:"shuffle_tracking_timeout"` // 毫秒，保留 shuffle 数据的 executor 空闲多久后释放
+}
+
+// Validate 返回所有校验错误，多个错误通过 errors.Join 合并
+func (req *CreateSparkApplicationRequest) Validate() error {
+	var errs []error
+	if req.Name == nil || *req.Name == "" {
+		errs = append(errs, fmt.Errorf("name is required"))
+	}
+	errs = append(errs, req.validateApplication()...)
+	restartPolicy := "Never"
+	if req.RestartPolicy != nil {
+		restartPolicy = *req.RestartPolicy
+	}
+	switch restartPolicy {
+	case "Never", "OnFailure", "Always":
+		if req.Retry != nil {
+			errs = append(errs, req.Retry.validate(restartPolicy))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("restart_policy must be one of Never, OnFailure, Always"))
+	}
+	if req.DynamicAllocation != nil {
+		errs = append(errs, req.DynamicAllocation.validate())
+	}
+	if req.TimeToLiveSeconds != nil && *req.TimeToLiveSeconds <= 0 {
+		errs = append(errs, fmt.Errorf("time_to_live_seconds must be positive"))
+	}
+	if req.BatchScheduler != nil {
+		errs = append(errs, req.BatchScheduler.Validate())
+		// operator 按 driver 和 executor 创建 PodGroup，不能指定 pod 数量
+		if req.BatchScheduler.Gang != nil && req.BatchScheduler.Gang.MinMember != nil {
+			errs = append(errs, fmt.Errorf("batch_scheduler.gang.min_member is not supported for spark application"))
+		}
+	}
+	volumes := map[string]bool{}
+	if req.Volumes == nil {
+		volumes[sparkDefaultVolume] = true
+	}
+	for _, v := range req.Volumes {
+		if _, err := v.ToYaml(); err != nil {
+			errs = append(errs, fmt.Errorf("volumes: %v", err))
+			continue
+		}
+		if volumes[*v.Name] {
+			errs = append(errs, fmt.Errorf("volumes: duplicate volume name %s", *v.Name))
+		}
+		volumes[*v.Name] = true
+	}
+	if req.Driver != nil {
+		errs = append(errs, req.Driver.validate("driver", volumes))
+	}
+	if req.Executor != nil {
+		errs = append(errs, req.Executor.validate("executor", volumes))
+	}
+	return errors.Join(errs...)
 }
 
 /*
@@ -108,17 +259,17 @@ func (req *CreateSparkApplicationRequest) ToYaml() map[string]any {
 		"spec": map[string]any{
 			"type":                "Scala",
 			"mode":                "cluster",
-			"image":               "apache/spark-py:v3.2.1",
+			"image":               sparkDefaultImage,
 			"imagePullPolicy":     "Always",
 			"mainClass":           "org.apache.spark.examples.SparkPi",
 			"mainApplicationFile": "local:///opt/spark/examples/jars/spark-examples_2.12-3.2.1.jar",
-			"sparkVersion":        "3.2.1",
+			"sparkVersion":        sparkDefaultVersion,
 			"restartPolicy": map[string]any{
 				"type": "Never",
 			},
 			"volumes": []map[string]any{
 				{
-					"name": "test-volume",
+					"name": sparkDefaultVolume,
 					"hostPath": map[string]any{
 						"path": "/tmp",
 						"type": "Directory",
@@ -129,11 +280,11 @@ func (req *CreateSparkApplicationRequest) ToYaml() map[string]any {
 				"cores":          1,
 				"coreLimit":      "1200m",
 				"memory":         "512m",
-				"labels":         map[string]any{"version": "3.2.1"},
+				"labels":         map[string]any{"version": sparkDefaultVersion},
 				"serviceAccount": "spark",
 				"volumeMounts": []map[string]any{
 					{
-						"name":      "test-volume",
+						"name":      sparkDefaultVolume,
 						"mountPath": "/tmp",
 					},
 				},
@@ -142,10 +293,10 @@ func (req *CreateSparkApplicationRequest) ToYaml() map[string]any {
 				"cores":     2,
 				"instances": 2,
 				"memory":    "512m",
-				"labels":    map[string]any{"version": "3.2.1"},
+				"labels":    map[string]any{"version": sparkDefaultVersion},
 				"volumeMounts": []map[string]any{
 					{
-						"name":      "test-volume",
+						"name":      sparkDefaultVolume,
 						"mountPath": "/tmp",
 					},
 				},
@@ -219,16 +370,336 @@ func (req *CreateSparkApplicationRequest) ToYaml() map[string]any {
 			},
 		}
 	}
-	if req.SparkVersion != nil {
-		yaml["spec"].(map[string]any)["sparkVersion"] = *req.SparkVersion
-		yaml["spec"].(map[string]any)["executor"].(map[string]any)["labels"].(map[string]any)["version"] = *req.SparkVersion
-		yaml["spec"].(map[string]any)["driver"].(map[string]any)["labels"].(map[string]any)["version"] = *req.SparkVersion
+	if version := req.sparkVersion(); version != sparkDefaultVersion {
+		yaml["spec"].(map[string]any)["sparkVersion"] = version
+		yaml["spec"].(map[string]any)["executor"].(map[string]any)["labels"].(map[string]any)["version"] = version
+		yaml["spec"].(map[string]any)["driver"].(map[string]any)["labels"].(map[string]any)["version"] = version
+	}
+
+	spec := yaml["spec"].(map[string]any)
+	switch req.sparkType() {
+	case SparkTypePython:
+		spec["pythonVersion"] = "3"
+		if req.PythonVersion != nil {
+			spec["pythonVersion"] = *req.PythonVersion
+		}
+		fallthrough
+	case SparkTypeR:
+		// 默认的 SparkPi mainClass 只适用于 jar
+		if req.MainClass == nil {
+			delete(spec, "mainClass")
+		}
+	}
+	if len(req.SparkConf) > 0 {
+		spec["sparkConf"] = req.SparkConf
+	}
+	if len(req.HadoopConf) > 0 {
+		spec["hadoopConf"] = req.HadoopConf
+	}
+	if len(req.Arguments) > 0 {
+		spec["arguments"] = req.Arguments
+	}
+	if req.Deps != nil {
+		spec["deps"] = req.Deps.toYaml()
+	}
+	if req.Retry != nil {
+		req.Retry.applyToYaml(spec["restartPolicy"].(map[string]any))
+	}
+	if req.DynamicAllocation != nil {
+		spec["dynamicAllocation"] = req.DynamicAllocation.toYaml()
+	}
+	if req.TimeToLiveSeconds != nil {
+		spec["timeToLiveSeconds"] = *req.TimeToLiveSeconds
+	}
+	if req.BatchScheduler != nil {
+		req.BatchScheduler.applyToSparkYaml(spec)
+	}
+	if req.ImagePullPolicy != nil {
+		spec["imagePullPolicy"] = *req.ImagePullPolicy
+	}
+	if len(req.ImagePullSecrets) > 0 {
+		spec["imagePullSecrets"] = req.ImagePullSecrets
+	}
+	// 自定义卷时去掉默认的 test-volume 和它的挂载
+	if req.Volumes != nil {
+		volumes := make([]map[string]any, 0, len(req.Volumes))
+		for _, v := range req.Volumes {
+			if volume, err := v.ToYaml(); err == nil {
+				volumes = append(volumes, volume)
+			}
+		}
+		spec["volumes"] = volumes
+		delete(spec["driver"].(map[string]any), "volumeMounts")
+		delete(spec["executor"].(map[string]any), "volumeMounts")
+	}
+	if req.Driver != nil {
+		req.Driver.SparkPodSpec.applyToYaml(spec["driver"].(map[string]any))
+	}
+	if req.Executor != nil {
+		req.Executor.SparkPodSpec.applyToYaml(spec["executor"].(map[string]any))
 	}
 
 	return yaml
 }
 
+func (d *SparkDeps) toYaml() map[string]any {
+	deps := map[string]any{}
+	for key, value := range map[string][]string{
+		"jars":            d.Jars,
+		"files":           d.Files,
+		"pyFiles":         d.PyFiles,
+		"packages":        d.Packages,
+		"excludePackages": d.ExcludePackages,
+		"repositories":    d.Repositories,
+	} {
+		if len(value) > 0 {
+			deps[key] = value
+		}
+	}
+	return deps
+}
+
+func (r *SparkRetry) validate(restartPolicy string) error {
+	if restartPolicy == "Never" {
+		return fmt.Errorf("retry requires restart_policy OnFailure or Always")
+	}
+	if restartPolicy != "OnFailure" && (r.OnFailureRetries != nil || r.OnSubmissionFailureRetries != nil) {
+		return fmt.Errorf("retry: retries only take effect with restart_policy OnFailure")
+	}
+	for field, value := range map[string]*int32{
+		"on_failure_retries":            r.OnFailureRetries,
+		"on_submission_failure_retries": r.OnSubmissionFailureRetries,
+	} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("retry.%s must not be negative", field)
+		}
+	}
+	for field, value := range map[string]*int64{
+		"on_failure_retry_interval":            r.OnFailureRetryInterval,
+		"on_submission_failure_retry_interval": r.OnSubmissionFailureRetryInterval,
+	} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("retry.%s must not be negative", field)
+		}
+	}
+	return nil
+}
+
+func (r *SparkRetry) applyToYaml(restartPolicy map[string]any) {
+	if r.OnFailureRetries != nil {
+		restartPolicy["onFailureRetries"] = *r.OnFailureRetries
+	}
+	if r.OnFailureRetryInterval != nil {
+		restartPolicy["onFailureRetryInterval"] = *r.OnFailureRetryInterval
+	}
+	if r.OnSubmissionFailureRetries != nil {
+		restartPolicy["onSubmissionFailureRetries"] = *r.OnSubmissionFailureRetries
+	}
+	if r.OnSubmissionFailureRetryInterval != nil {
+		restartPolicy["onSubmissionFailureRetryInterval"] = *r.OnSubmissionFailureRetryInterval
+	}
+}
+
+func (d *SparkDynamicAllocation) validate() error {
+	for field, value := range map[string]*int32{
+		"initial_executors": d.InitialExecutors,
+		"min_executors":     d.MinExecutors,
+		"max_executors":     d.MaxExecutors,
+	} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("dynamic_allocation.%s must not be negative", field)
+		}
+	}
+	if d.ShuffleTrackingTimeout != nil && *d.ShuffleTrackingTimeout < 0 {
+		return fmt.Errorf("dynamic_allocation.shuffle_tracking_timeout must not be negative")
+	}
+	if d.MinExecutors != nil && d.MaxExecutors != nil && *d.MinExecutors > *d.MaxExecutors {
+		return fmt.Errorf("dynamic_allocation.min_executors must not be greater than max_executors")
+	}
+	if d.InitialExecutors != nil {
+		if d.MinExecutors != nil && *d.InitialExecutors < *d.MinExecutors {
+			return fmt.Errorf("dynamic_allocation.initial_executors must not be less than min_executors")
+		}
+		if d.MaxExecutors != nil && *d.InitialExecutors > *d.MaxExecutors {
+			return fmt.Errorf("dynamic_allocation.initial_executors must not be greater than max_executors")
+		}
+	}
+	return nil
+}
+
+func (d *SparkDynamicAllocation) toYaml() map[string]any {
+	allocation := map[string]any{"enabled": d.Enabled}
+	if d.InitialExecutors != nil {
+		allocation["initialExecutors"] = *d.InitialExecutors
+	}
+	if d.MinExecutors != nil {
+		allocation["minExecutors"] = *d.MinExecutors
+	}
+	if d.MaxExecutors != nil {
+		allocation["maxExecutors"] = *d.MaxExecutors
+	}
+	if d.ShuffleTrackingTimeout != nil {
+		allocation["shuffleTrackingTimeout"] = *d.ShuffleTrackingTimeout
+	}
+	return allocation
+}
+
+func (p *SparkPodSpec) validate(field string, volumes map[string]bool) error {
+	for _, mount := range p.VolumeMounts {
+		if _, err := mount.ToYaml(); err != nil {
+			return fmt.Errorf("%s.volumeMounts: %v", field, err)
+		}
+		if !volumes[*mount.Name] {
+			return fmt.Errorf("%s.volumeMounts: volume %s is not defined", field, *mount.Name)
+		}
+	}
+	for _, env := range p.SecretEnv {
+		if env.Name == nil || env.SecretName == nil || env.Key == nil {
+			return fmt.Errorf("%s.secretEnv: name, secretName and key are required", field)
+		}
+	}
+	for _, secret := range p.Secrets {
+		if secret.Name == nil || secret.Path == nil {
+			return fmt.Errorf("%s.secrets: name and path are required", field)
+		}
+	}
+	for _, configMap := range p.ConfigMaps {
+		if configMap.Name == nil || configMap.Path == nil {
+			return fmt.Errorf("%s.configMaps: name and path are required", field)
+		}
+	}
+	return validatePodScheduling(field, p.Tolerations, p.Affinity)
+}
+
+// applyToYaml 将 pod 配置合并进 driver 或 executor，转换错误已经在 Validate 中返回，请先调用 Validate 校验
+func (p *SparkPodSpec) applyToYaml(pod map[string]any) {
+	if p.Image != nil {
+		pod["image"] = *p.Image
+	}
+	if p.MemoryOverhead != nil {
+		pod["memoryOverhead"] = *p.MemoryOverhead
+	}
+	if p.JavaOptions != nil {
+		pod["javaOptions"] = *p.JavaOptions
+	}
+	if p.ServiceAccount != nil {
+		if *p.ServiceAccount == "" {
+			delete(pod, "serviceAccount")
+		} else {
+			pod["serviceAccount"] = *p.ServiceAccount
+		}
+	}
+	if len(p.Annotations) > 0 {
+		pod["annotations"] = p.Annotations
+	}
+	for _, env := range p.Env {
+		appendToList(pod, "env", map[string]any{"name": tea.StringValue(env.Name), "value": tea.StringValue(env.Value)})
+	}
+	for _, env := range p.SecretEnv {
+		appendToList(pod, "env", map[string]any{
+			"name": tea.StringValue(env.Name),
+			"valueFrom": map[string]any{
+				"secretKeyRef": map[string]any{"name": tea.StringValue(env.SecretName), "key": tea.StringValue(env.Key)},
+			},
+		})
+	}
+	for _, secret := range p.Secrets {
+		secretType := "Generic"
+		if secret.SecretType != nil {
+			secretType = *secret.SecretType
+		}
+		appendToList(pod, "secrets", map[string]any{"name": tea.StringValue(secret.Name), "path": tea.StringValue(secret.Path), "secretType": secretType})
+	}
+	for _, configMap := range p.ConfigMaps {
+		appendToList(pod, "configMaps", map[string]any{"name": tea.StringValue(configMap.Name), "path": tea.StringValue(configMap.Path)})
+	}
+	if len(p.NodeSelector) > 0 {
+		pod["nodeSelector"] = p.NodeSelector
+	}
+	if len(p.Tolerations) > 0 {
+		tolerations := make([]map[string]any, 0, len(p.Tolerations))
+		for i := range p.Tolerations {
+			if t, err := toUnstructured(&p.Tolerations[i]); err == nil {
+				tolerations = append(tolerations, t)
+			}
+		}
+		pod["tolerations"] = tolerations
+	}
+	if p.Affinity != nil {
+		if affinity, err := toUnstructured(p.Affinity); err == nil {
+			pod["affinity"] = affinity
+		}
+	}
+	if p.VolumeMounts != nil {
+		mounts := make([]map[string]any, 0, len(p.VolumeMounts))
+		for _, mount := range p.VolumeMounts {
+			if volumeMount, err := mount.ToYaml(); err == nil {
+				mounts = append(mounts, volumeMount)
+			}
+		}
+		pod["volumeMounts"] = mounts
+	}
+}
+
 type DeleteSparkApplicationRequest struct {
 	Namespace *string `json:"namespace" binding:"required"`
 	Name      *string `json:"name" binding:"required"`
 }
+
+// CleanupSparkApplicationRequest 批量删除结束超过 older_than 的 SparkApplication
+type CleanupSparkApplicationRequest struct {
+	Namespace     *string  `json:"namespace" binding:"required"`
+	LabelSelector *string  `json:"label_selector"`                // 比如 team=data
+	OlderThan     *string  `json:"older_than" binding:"required"` // 结束时间距离现在超过多久，比如 24h、168h
+	States        []string `json:"states"`                        // 默认 COMPLETED、FAILED、SUBMISSION_FAILED，只能是结束状态
+	DryRun        bool     `json:"dry_run"`                       // 只返回会被删除的任务
+}
+
+type CleanupSparkApplicationResponse struct {
+	DryRun  bool              `json:"dry_run"`
+	Deleted []string          `json:"deleted"`
+	Failed  map[string]string `json:"failed"` // 名称 -> 删除失败的原因
+}
+
+var sparkTerminalStates = []string{SparkStateCompleted, SparkStateFailed, SparkStateSubmissionFailed}
+
+func (req *CleanupSparkApplicationRequest) Validate() error {
+	if req.Namespace == nil || *req.Namespace == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	if req.OlderThan == nil {
+		return fmt.Errorf("older_than is required")
+	}
+	olderThan, err := time.ParseDuration(*req.OlderThan)
+	if err != nil {
+		return fmt.Errorf("older_than: %v", err)
+	}
+	if olderThan <= 0 {
+		return fmt.Errorf("older_than must be positive")
+	}
+	for _, state := range req.States {
+		if !slices.Contains(sparkTerminalStates, state) {
+			return fmt.Errorf("states: %s is not a terminal state, must be one of %v", state, sparkTerminalStates)
+		}
+	}
+	return nil
+}
+
+// Expired 状态匹配且结束时间早于 now - older_than，没有结束时间时使用最后一次提交时间或创建时间，请先调用 Validate 校验
+func (req *CleanupSparkApplicationRequest) Expired(app CrdSparkApplication, now time.Time) bool {
+	states := req.States
+	if len(states) == 0 {
+		states = sparkTerminalStates
+	}
+	if !slices.Contains(states, app.Status) {
+		return false
+	}
+	olderThan, _ := time.ParseDuration(tea.StringValue(req.OlderThan))
+	finished := app.CreateTime
+	if app.FinishTime != nil {
+		finished = *app.FinishTime
+	} else if app.StartTime != nil {
+		finished = *app.StartTime
+	}
+	return finished.Before(now.Add(-olderThan))
+}
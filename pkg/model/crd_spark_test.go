@@ -0,0 +1,399 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNewCrdSparkApplication(t *testing.T) {
+	newItem := func(status any) unstructured.Unstructured {
+		obj := map[string]any{
+			"apiVersion": "sparkoperator.k8s.io/v1beta2",
+			"kind":       "SparkApplication",
+			"metadata":   map[string]any{"name": "spark-pi", "namespace": "spark", "creationTimestamp": "2026-10-01T00:00:00Z"},
+		}
+		if status != nil {
+			obj["status"] = status
+		}
+		return unstructured.Unstructured{Object: obj}
+	}
+
+	// 刚提交，operator 还没有写 status
+	app := model.NewCrdSparkApplication(newItem(nil))
+	assert.Equal(t, model.SparkStateNew, app.Status)
+	assert.Nil(t, app.StartTime)
+	assert.Nil(t, app.FinishTime)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), app.CreateTime.UTC())
+
+	// 运行中，没有 terminationTime
+	app = model.NewCrdSparkApplication(newItem(map[string]any{
+		"applicationState":          map[string]any{"state": "RUNNING"},
+		"lastSubmissionAttemptTime": "2026-10-01T00:01:00Z",
+		"terminationTime":           nil,
+		"executionAttempts":         int64(1),
+		"sparkApplicationId":        "spark-123",
+		"driverInfo": map[string]any{
+			"podName":             "spark-pi-driver",
+			"webUIServiceName":    "spark-pi-ui-svc",
+			"webUIAddress":        "10.0.0.1:4040",
+			"webUIPort":           int64(4040),
+			"webUIIngressName":    "spark-pi-ui-ingress",
+			"webUIIngressAddress": "spark.example.com/spark/spark-pi",
+		},
+		"executorState": map[string]any{"spark-pi-exec-1": "RUNNING", "spark-pi-exec-2": "RUNNING", "spark-pi-exec-3": "PENDING"},
+	}))
+	assert.Equal(t, model.SparkStateRunning, app.Status)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 1, 0, 0, time.UTC), *app.StartTime)
+	assert.Nil(t, app.FinishTime)
+	assert.Equal(t, int64(1), app.Attempts)
+	assert.Equal(t, "spark-123", app.ApplicationID)
+	assert.Equal(t, "spark-pi-driver", app.Driver.PodName)
+	assert.Equal(t, int32(4040), app.Driver.WebUIPort)
+	assert.Equal(t, "spark.example.com/spark/spark-pi", app.Driver.WebUIIngressAddress)
+	driver, _ := json.Marshal(app.Driver)
+	assert.Contains(t, string(driver), `"pod_name":"spark-pi-driver"`)
+	assert.Contains(t, string(driver), `"web_ui_port":4040`)
+	assert.Equal(t, map[string]int{"RUNNING": 2, "PENDING": 1}, app.ExecutorSummary)
+	assert.Len(t, app.Executors, 3)
+
+	// 失败，时间为空字符串和零值
+	app = model.NewCrdSparkApplication(newItem(map[string]any{
+		"applicationState":          map[string]any{"state": "FAILED", "errorMessage": "driver container failed with ExitCode: 1"},
+		"lastSubmissionAttemptTime": "",
+		"terminationTime":           "1970-01-01T00:00:00Z",
+		"submissionAttempts":        int64(2),
+	}))
+	assert.Equal(t, model.SparkStateFailed, app.Status)
+	assert.Equal(t, "driver container failed with ExitCode: 1", app.ErrorMessage)
+	assert.Equal(t, int64(2), app.SubmissionAttempts)
+	assert.Nil(t, app.StartTime)
+	assert.Nil(t, app.FinishTime)
+
+	// 类型错误的字段不影响其他字段
+	app = model.NewCrdSparkApplication(newItem(map[string]any{
+		"applicationState":  map[string]any{"state": "COMPLETED"},
+		"executionAttempts": "x",
+		"terminationTime":   "2026-10-01T00:02:00Z",
+	}))
+	assert.Equal(t, model.SparkStateCompleted, app.Status)
+	assert.Equal(t, int64(0), app.Attempts)
+	assert.NotNil(t, app.FinishTime)
+}
+
+func TestCreateSparkApplicationSpec(t *testing.T) {
+	// 默认配置保持不变
+	yaml := (&model.CreateSparkApplicationRequest{Name: tea.String("spark-pi")}).ToYaml()
+	spec := yaml["spec"].(map[string]any)
+	assert.Equal(t, "test-volume", spec["volumes"].([]map[string]any)[0]["name"])
+	assert.Equal(t, "spark", spec["driver"].(map[string]any)["serviceAccount"])
+	assert.Len(t, spec["executor"].(map[string]any)["volumeMounts"], 1)
+
+	req := model.CreateSparkApplicationRequest{
+		Name:       tea.String("spark-etl"),
+		SparkConf:  map[string]string{"spark.sql.shuffle.partitions": "200"},
+		HadoopConf: map[string]string{"fs.s3a.endpoint": "s3.example.com"},
+		Arguments:  []string{"--date", "2026-10-01"},
+		Deps:       &model.SparkDeps{Jars: []string{"s3a://bucket/udf.jar"}, Packages: []string{"org.apache.hadoop:hadoop-aws:3.3.4"}},
+		Volumes: []model.Volume{
+			{Name: tea.String("data"), PersistentVolumeClaim: &model.PersistentVolumeClaim{ClaimName: tea.String("spark-data")}},
+		},
+		ImagePullSecrets: []string{"registry"},
+		Driver: &model.Driver{SparkPodSpec: model.SparkPodSpec{
+			ServiceAccount: tea.String("spark-etl"),
+			Env:            []model.Env{{Name: tea.String("TZ"), Value: tea.String("Asia/Shanghai")}},
+			SecretEnv:      []model.SparkSecretEnv{{Name: tea.String("AWS_ACCESS_KEY_ID"), SecretName: tea.String("s3"), Key: tea.String("access-key")}},
+			ConfigMaps:     []model.SparkConfigMap{{Name: tea.String("etl-conf"), Path: tea.String("/etc/etl")}},
+			VolumeMounts:   []model.VolumeMount{{Name: tea.String("data"), MountPath: tea.String("/data")}},
+		}},
+		Executor: &model.Executor{Instances: 4, SparkPodSpec: model.SparkPodSpec{
+			NodeSelector: map[string]string{"pool": "spark"},
+			Tolerations:  []corev1.Toleration{{Key: "spark", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+			Secrets:      []model.SparkSecret{{Name: tea.String("kerberos"), Path: tea.String("/etc/kerberos")}},
+		}},
+	}
+	assert.NoError(t, req.Validate())
+	spec = req.ToYaml()["spec"].(map[string]any)
+	assert.Equal(t, req.SparkConf, spec["sparkConf"])
+	assert.Equal(t, req.HadoopConf, spec["hadoopConf"])
+	assert.Equal(t, req.Arguments, spec["arguments"])
+	assert.Equal(t, map[string]any{"jars": req.Deps.Jars, "packages": req.Deps.Packages}, spec["deps"])
+	assert.Equal(t, []map[string]any{{"name": "data", "persistentVolumeClaim": map[string]any{"claimName": "spark-data", "readOnly": false}}}, spec["volumes"])
+
+	driver := spec["driver"].(map[string]any)
+	assert.Equal(t, "spark-etl", driver["serviceAccount"])
+	assert.Equal(t, []map[string]any{{"name": "data", "mountPath": "/data"}}, driver["volumeMounts"])
+	assert.Len(t, driver["env"], 2)
+	assert.Equal(t, map[string]any{"secretKeyRef": map[string]any{"name": "s3", "key": "access-key"}}, driver["env"].([]map[string]any)[1]["valueFrom"])
+	assert.Equal(t, []map[string]any{{"name": "etl-conf", "path": "/etc/etl"}}, driver["configMaps"])
+
+	executor := spec["executor"].(map[string]any)
+	assert.Equal(t, 4, executor["instances"])
+	assert.NotContains(t, executor, "volumeMounts")
+	assert.Equal(t, map[string]string{"pool": "spark"}, executor["nodeSelector"])
+	assert.Equal(t, "Exists", executor["tolerations"].([]map[string]any)[0]["operator"])
+	assert.Equal(t, "Generic", executor["secrets"].([]map[string]any)[0]["secretType"])
+
+	// 空数组不使用任何卷，去掉默认 serviceAccount
+	spec = (&model.CreateSparkApplicationRequest{
+		Name:    tea.String("spark-pi"),
+		Volumes: []model.Volume{},
+		Driver:  &model.Driver{SparkPodSpec: model.SparkPodSpec{ServiceAccount: tea.String("")}},
+	}).ToYaml()["spec"].(map[string]any)
+	assert.Empty(t, spec["volumes"])
+	assert.NotContains(t, spec["driver"], "serviceAccount")
+	assert.NotContains(t, spec["driver"], "volumeMounts")
+
+	// 挂载未定义的卷
+	req.Executor.VolumeMounts = []model.VolumeMount{{Name: tea.String("test-volume"), MountPath: tea.String("/tmp")}}
+	assert.ErrorContains(t, req.Validate(), "executor.volumeMounts: volume test-volume is not defined")
+	req.Executor.VolumeMounts = nil
+	req.Executor.Tolerations = []corev1.Toleration{{Operator: "In", Value: "spark"}}
+	assert.ErrorContains(t, req.Validate(), "executor.tolerations[0]: operator must be Equal or Exists")
+}
+
+// driver、executor 和 deps 的字段名和 SparkApplication 一致，使用驼峰
+func TestCreateSparkApplicationJSON(t *testing.T) {
+	var req model.CreateSparkApplicationRequest
+	err := json.Unmarshal([]byte(`{
+		"name": "spark-pi",
+		"deps": {"pyFiles": ["s3a://bucket/lib.zip"], "excludePackages": ["log4j:log4j"]},
+		"driver": {
+			"memoryOverhead": "512m",
+			"secretEnv": [{"name": "AWS_ACCESS_KEY_ID", "secretName": "s3", "key": "access-key"}],
+			"secrets": [{"name": "kerberos", "path": "/etc/kerberos", "secretType": "HadoopDelegationToken"}]
+		}
+	}`), &req)
+	assert.NoError(t, err)
+	assert.NoError(t, req.Validate())
+	assert.Equal(t, []string{"s3a://bucket/lib.zip"}, req.Deps.PyFiles)
+	assert.Equal(t, []string{"log4j:log4j"}, req.Deps.ExcludePackages)
+	assert.Equal(t, "s3", *req.Driver.SecretEnv[0].SecretName)
+	assert.Equal(t, "HadoopDelegationToken", *req.Driver.Secrets[0].SecretType)
+}
+
+func TestScheduledSparkApplication(t *testing.T) {
+	req := model.CreateScheduledSparkApplicationRequest{
+		CreateSparkApplicationRequest: model.CreateSparkApplicationRequest{Name: tea.String("nightly"), Namespace: tea.String("spark")},
+		Schedule:                      tea.String("0 2 * * *"),
+		ConcurrencyPolicy:             tea.String(model.ConcurrencyReplace),
+		SuccessfulRunHistoryLimit:     tea.Int32(3),
+	}
+	assert.NoError(t, req.Validate())
+	yaml := req.ToYaml()
+	assert.Equal(t, "ScheduledSparkApplication", yaml["kind"])
+	assert.Equal(t, "nightly", yaml["metadata"].(map[string]any)["name"])
+	spec := yaml["spec"].(map[string]any)
+	assert.Equal(t, "0 2 * * *", spec["schedule"])
+	assert.Equal(t, "Replace", spec["concurrencyPolicy"])
+	assert.Equal(t, int32(3), spec["successfulRunHistoryLimit"])
+	assert.Equal(t, 1, spec["failedRunHistoryLimit"])
+	// 没有指定时不写 suspend，更新时保留当前的暂停状态
+	assert.NotContains(t, spec, "suspend")
+	assert.Equal(t, "spark", spec["template"].(map[string]any)["driver"].(map[string]any)["serviceAccount"])
+
+	for _, schedule := range []string{"", "0 2 * *", "every day"} {
+		req.Schedule = tea.String(schedule)
+		assert.Error(t, req.Validate(), schedule)
+	}
+	req.Schedule = tea.String("@every 1h")
+	assert.NoError(t, req.Validate())
+	req.ConcurrencyPolicy = tea.String("Skip")
+	assert.Error(t, req.Validate())
+
+	app := model.NewCrdScheduledSparkApplication(unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{"name": "nightly", "namespace": "spark"},
+		"spec":     map[string]any{"schedule": "0 2 * * *", "concurrencyPolicy": "Forbid", "suspend": false},
+		"status": map[string]any{
+			"scheduleState":          "Scheduled",
+			"lastRun":                "2026-10-01T02:00:00Z",
+			"nextRun":                "2026-10-02T02:00:00Z",
+			"lastRunName":            "nightly-1759284000000000000",
+			"pastSuccessfulRunNames": []any{"nightly-1759284000000000000"},
+		},
+	}})
+	assert.Equal(t, "Scheduled", app.State)
+	assert.Equal(t, time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC), *app.LastRun)
+	assert.Equal(t, time.Date(2026, 10, 2, 2, 0, 0, 0, time.UTC), *app.NextRun)
+	assert.Equal(t, []string{"nightly-1759284000000000000"}, app.PastSuccessfulRunNames)
+
+	// 刚创建还没有 status
+	app = model.NewCrdScheduledSparkApplication(unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{"name": "nightly"},
+		"spec":     map[string]any{"schedule": "@daily", "suspend": true},
+	}})
+	assert.Equal(t, "New", app.State)
+	assert.True(t, app.Suspend)
+	assert.Nil(t, app.LastRun)
+	assert.Nil(t, app.NextRun)
+}
+
+func TestSparkRetryAndDynamicAllocation(t *testing.T) {
+	req := model.CreateSparkApplicationRequest{
+		Name:          tea.String("spark-etl"),
+		RestartPolicy: tea.String("OnFailure"),
+		Retry: &model.SparkRetry{
+			OnFailureRetries:           tea.Int32(3),
+			OnFailureRetryInterval:     tea.Int64(10),
+			OnSubmissionFailureRetries: tea.Int32(5),
+		},
+		DynamicAllocation: &model.SparkDynamicAllocation{
+			Enabled:          true,
+			InitialExecutors: tea.Int32(2),
+			MinExecutors:     tea.Int32(1),
+			MaxExecutors:     tea.Int32(10),
+		},
+		TimeToLiveSeconds: tea.Int64(3600),
+	}
+	assert.NoError(t, req.Validate())
+	spec := req.ToYaml()["spec"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"type":                       "OnFailure",
+		"onFailureRetries":           int32(3),
+		"onFailureRetryInterval":     int64(10),
+		"onSubmissionFailureRetries": int32(5),
+	}, spec["restartPolicy"])
+	assert.Equal(t, map[string]any{
+		"enabled":          true,
+		"initialExecutors": int32(2),
+		"minExecutors":     int32(1),
+		"maxExecutors":     int32(10),
+	}, spec["dynamicAllocation"])
+	assert.Equal(t, int64(3600), spec["timeToLiveSeconds"])
+
+	// 默认不设置
+	spec = (&model.CreateSparkApplicationRequest{Name: tea.String("spark-pi")}).ToYaml()["spec"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "Never"}, spec["restartPolicy"])
+	assert.NotContains(t, spec, "dynamicAllocation")
+	assert.NotContains(t, spec, "timeToLiveSeconds")
+
+	req.RestartPolicy = tea.String("Always")
+	assert.ErrorContains(t, req.Validate(), "retries only take effect with restart_policy OnFailure")
+	req.RestartPolicy = nil
+	assert.ErrorContains(t, req.Validate(), "retry requires restart_policy OnFailure or Always")
+	req.RestartPolicy = tea.String("Sometimes")
+	assert.ErrorContains(t, req.Validate(), "restart_policy must be one of")
+	req.RestartPolicy, req.Retry = nil, nil
+	req.DynamicAllocation.MinExecutors = tea.Int32(3)
+	assert.ErrorContains(t, req.Validate(), "initial_executors must not be less than min_executors")
+	req.DynamicAllocation.MinExecutors = tea.Int32(20)
+	assert.ErrorContains(t, req.Validate(), "min_executors must not be greater than max_executors")
+	req.DynamicAllocation = nil
+	req.TimeToLiveSeconds = tea.Int64(0)
+	assert.ErrorContains(t, req.Validate(), "time_to_live_seconds must be positive")
+}
+
+func TestCleanupSparkApplicationRequest(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	finished := func(state string, ago time.Duration) model.CrdSparkApplication {
+		finish := now.Add(-ago)
+		return model.CrdSparkApplication{Status: state, CreateTime: finish.Add(-time.Hour), FinishTime: &finish}
+	}
+	req := model.CleanupSparkApplicationRequest{Namespace: tea.String("spark"), OlderThan: tea.String("24h")}
+	assert.NoError(t, req.Validate())
+	assert.True(t, req.Expired(finished(model.SparkStateCompleted, 48*time.Hour), now))
+	assert.True(t, req.Expired(finished(model.SparkStateSubmissionFailed, 48*time.Hour), now))
+	assert.False(t, req.Expired(finished(model.SparkStateCompleted, time.Hour), now))
+	assert.False(t, req.Expired(finished(model.SparkStateRunning, 48*time.Hour), now))
+	// 没有结束时间时使用创建时间
+	assert.True(t, req.Expired(model.CrdSparkApplication{Status: model.SparkStateFailed, CreateTime: now.Add(-48 * time.Hour)}, now))
+
+	req.States = []string{model.SparkStateFailed}
+	assert.False(t, req.Expired(finished(model.SparkStateCompleted, 48*time.Hour), now))
+	assert.True(t, req.Expired(finished(model.SparkStateFailed, 48*time.Hour), now))
+
+	req.States = []string{model.SparkStateRunning}
+	assert.ErrorContains(t, req.Validate(), "RUNNING is not a terminal state")
+	req.States = nil
+	req.OlderThan = tea.String("1d")
+	assert.ErrorContains(t, req.Validate(), "older_than")
+	req.OlderThan = tea.String("-1h")
+	assert.ErrorContains(t, req.Validate(), "older_than must be positive")
+}
+
+func TestSparkApplicationType(t *testing.T) {
+	// PySpark 不生成默认的 mainClass，pythonVersion 默认 3，sparkVersion 使用镜像 tag 中的版本
+	req := model.CreateSparkApplicationRequest{
+		Name:                tea.String("pyspark-etl"),
+		Type:                tea.String(model.SparkTypePython),
+		Image:               tea.String("apache/spark:3.5.0-scala2.12-java11-python3-ubuntu"),
+		MainApplicationFile: tea.String("s3a://bucket/jobs/etl.py"),
+	}
+	assert.NoError(t, req.Validate())
+	spec := req.ToYaml()["spec"].(map[string]any)
+	assert.Equal(t, "Python", spec["type"])
+	assert.Equal(t, "3", spec["pythonVersion"])
+	assert.Equal(t, "3.5.0", spec["sparkVersion"])
+	assert.Equal(t, "3.5.0", spec["driver"].(map[string]any)["labels"].(map[string]any)["version"])
+	assert.NotContains(t, spec, "mainClass")
+
+	req = model.CreateSparkApplicationRequest{
+		Name:                tea.String("r-report"),
+		Type:                tea.String(model.SparkTypeR),
+		MainApplicationFile: tea.String("https://example.com/report.R"),
+	}
+	assert.NoError(t, req.Validate())
+	spec = req.ToYaml()["spec"].(map[string]any)
+	assert.NotContains(t, spec, "mainClass")
+	assert.NotContains(t, spec, "pythonVersion")
+
+	req = model.CreateSparkApplicationRequest{
+		Name:                tea.String("java-etl"),
+		Type:                tea.String(model.SparkTypeJava),
+		MainClass:           tea.String("com.example.Etl"),
+		MainApplicationFile: tea.String("local:///opt/app/etl.jar"),
+		SparkVersion:        tea.String("3.2.1"),
+	}
+	assert.NoError(t, req.Validate())
+	spec = req.ToYaml()["spec"].(map[string]any)
+	assert.Equal(t, "com.example.Etl", spec["mainClass"])
+	assert.Equal(t, "apache/spark-py:v3.2.1", spec["image"])
+
+	// 一次返回所有错误
+	err := (&model.CreateSparkApplicationRequest{
+		Name:                tea.String("pyspark-etl"),
+		Type:                tea.String(model.SparkTypePython),
+		MainClass:           tea.String("org.apache.spark.examples.SparkPi"),
+		MainApplicationFile: tea.String("/opt/jobs/etl.jar"),
+		PythonVersion:       tea.String("2"),
+		Image:               tea.String("apache/spark-py:v3.2.1"),
+		SparkVersion:        tea.String("3.5.0"),
+		Executor:            &model.Executor{SparkPodSpec: model.SparkPodSpec{Image: tea.String("apache/spark:3.4.1")}},
+	}).Validate()
+	for _, msg := range []string{
+		"main_class is not supported for Python application",
+		"main_application_file /opt/jobs/etl.jar must start with one of",
+		"main_application_file /opt/jobs/etl.jar must end with one of [.py .zip .egg]",
+		"python_version 2 is not supported by spark 3.5.0",
+		"image apache/spark-py:v3.2.1 does not match spark_version 3.5.0",
+		"executor.image apache/spark:3.4.1 does not match spark_version 3.5.0",
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+
+	for _, c := range []struct {
+		req model.CreateSparkApplicationRequest
+		msg string
+	}{
+		{model.CreateSparkApplicationRequest{Type: tea.String("Go")}, "type must be one of Scala, Java, Python, R"},
+		{model.CreateSparkApplicationRequest{Type: tea.String(model.SparkTypePython)}, "main_application_file is required for Python application"},
+		{model.CreateSparkApplicationRequest{MainApplicationFile: tea.String("local:///opt/app/etl.jar")}, "main_class is required when main_application_file is set"},
+		{model.CreateSparkApplicationRequest{PythonVersion: tea.String("3")}, "python_version is only supported for Python application"},
+		{model.CreateSparkApplicationRequest{SparkVersion: tea.String("3.5")}, "spark_version must be like 3.5.0"},
+		// 默认镜像是 3.2.1
+		{model.CreateSparkApplicationRequest{SparkVersion: tea.String("3.5.0")}, "image apache/spark-py:v3.2.1 does not match spark_version 3.5.0"},
+		{model.CreateSparkApplicationRequest{}, "name is required"},
+	} {
+		assert.ErrorContains(t, c.req.Validate(), c.msg)
+	}
+
+	// 镜像 tag 不是版本号时不检查
+	req = model.CreateSparkApplicationRequest{Name: tea.String("spark-pi"), Image: tea.String("registry:5000/spark:latest"), SparkVersion: tea.String("3.5.0")}
+	assert.NoError(t, req.Validate())
+}
@@ -32,12 +32,118 @@ type ConfigMap struct {
 	Items []Item  `json:"items"`
 }
 
+type Secret struct {
+	SecretName *string `json:"secret_name"`
+	Items      []Item  `json:"items"`
+}
+
 type Volume struct {
 	Name                  *string                `json:"name" binding:"required"`
 	EmptyDir              *EmptyDir              `json:"empty_dir"`
 	HostPath              *HostPath              `json:"host_path"`
 	PersistentVolumeClaim *PersistentVolumeClaim `json:"persistent_volume_claim"`
 	ConfigMap             *ConfigMap             `json:"config_map"`
+	Secret                *Secret                `json:"secret"`
+}
+
+// ToYaml 转换为 pod spec.volumes 中的一项，只允许设置一种卷类型
+func (v *Volume) ToYaml() (map[string]any, error) {
+	if v.Name == nil || *v.Name == "" {
+		return nil, fmt.Errorf("volume name is required")
+	}
+	yaml := map[string]any{
+		"name": *v.Name,
+	}
+	sources := 0
+	if v.EmptyDir != nil {
+		yaml["emptyDir"] = map[string]any{}
+		sources++
+	}
+	if v.HostPath != nil {
+		if v.HostPath.Path == nil {
+			return nil, fmt.Errorf("volume %s host_path.path is required", *v.Name)
+		}
+		yaml["hostPath"] = map[string]any{
+			"path": *v.HostPath.Path,
+			"type": "DirectoryOrCreate",
+		}
+		sources++
+	}
+	if v.PersistentVolumeClaim != nil {
+		if v.PersistentVolumeClaim.ClaimName == nil {
+			return nil, fmt.Errorf("volume %s persistent_volume_claim.claim_name is required", *v.Name)
+		}
+		yaml["persistentVolumeClaim"] = map[string]any{
+			"claimName": *v.PersistentVolumeClaim.ClaimName,
+			"readOnly":  tea.BoolValue(v.PersistentVolumeClaim.ReadOnly),
+		}
+		sources++
+	}
+	if v.ConfigMap != nil {
+		if v.ConfigMap.Name == nil {
+			return nil, fmt.Errorf("volume %s config_map.name is required", *v.Name)
+		}
+		configMap := map[string]any{
+			"name": *v.ConfigMap.Name,
+		}
+		if len(v.ConfigMap.Items) > 0 {
+			configMap["items"] = itemsToYaml(v.ConfigMap.Items)
+		}
+		yaml["configMap"] = configMap
+		sources++
+	}
+	if v.Secret != nil {
+		if v.Secret.SecretName == nil {
+			return nil, fmt.Errorf("volume %s secret.secret_name is required", *v.Name)
+		}
+		secret := map[string]any{
+			"secretName": *v.Secret.SecretName,
+		}
+		if len(v.Secret.Items) > 0 {
+			secret["items"] = itemsToYaml(v.Secret.Items)
+		}
+		yaml["secret"] = secret
+		sources++
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("volume %s must set exactly one of empty_dir, host_path, persistent_volume_claim, config_map, secret", *v.Name)
+	}
+	return yaml, nil
+}
+
+func itemsToYaml(items []Item) []map[string]any {
+	result := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		result = append(result, map[string]any{
+			"key":  tea.StringValue(item.Key),
+			"path": tea.StringValue(item.Path),
+		})
+	}
+	return result
+}
+
+type VolumeMount struct {
+	Name      *string `json:"name" binding:"required"`
+	MountPath *string `json:"mount_path" binding:"required"`
+	SubPath   *string `json:"sub_path"`
+	ReadOnly  *bool   `json:"read_only"`
+}
+
+func (m *VolumeMount) ToYaml() (map[string]any, error) {
+	if m.Name == nil || m.MountPath == nil {
+		return nil, fmt.Errorf("volume mount name and mount_path are required")
+	}
+	yaml := map[string]any{
+		"name":      *m.Name,
+		"mountPath": *m.MountPath,
+	}
+	if m.SubPath != nil {
+		yaml["subPath"] = *m.SubPath
+	}
+	if m.ReadOnly != nil {
+		yaml["readOnly"] = *m.ReadOnly
+	}
+	return yaml, nil
 }
 
 type ResourceList v1.ResourceList
@@ -145,3 +251,29 @@ func (req *ApplyDeploymentRequest) ToCreateOptions() metav1.CreateOptions {
 		FieldManager: "multi-k8s-client",
 	}
 }
+
+func (p *LivenessProbe) ToProbe() *v1.Probe {
+	if p == nil {
+		return nil
+	}
+	probe := &v1.Probe{
+		InitialDelaySeconds: tea.Int32Value(p.InitialDelaySeconds),
+		TimeoutSeconds:      tea.Int32Value(p.TimeoutSeconds),
+		PeriodSeconds:       tea.Int32Value(p.PeriodSeconds),
+		SuccessThreshold:    tea.Int32Value(p.SuccessThreshold),
+		FailureThreshold:    tea.Int32Value(p.FailureThreshold),
+	}
+	if p.Exec != nil {
+		probe.Exec = (*v1.ExecAction)(p.Exec)
+	}
+	if p.HTTPGet != nil {
+		probe.HTTPGet = (*v1.HTTPGetAction)(p.HTTPGet)
+	}
+	if p.TCPSocket != nil {
+		probe.TCPSocket = (*v1.TCPSocketAction)(p.TCPSocket)
+	}
+	if p.GRPC != nil {
+		probe.GRPC = (*v1.GRPCAction)(p.GRPC)
+	}
+	return probe
+}
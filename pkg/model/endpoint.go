@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+type EndpointType string
+
+const (
+	EndpointLoadBalancer EndpointType = "LoadBalancer"
+	EndpointNodePort     EndpointType = "NodePort"
+	EndpointClusterIP    EndpointType = "ClusterIP"
+	EndpointIngress      EndpointType = "Ingress"
+)
+
+// Endpoint 集群的访问地址，来自 service 或 ingress
+type Endpoint struct {
+	Type   EndpointType `json:"type"`
+	Name   string       `json:"name"`   // service 或 ingress 名称
+	Host   string       `json:"host"`   // NodePort 为空，表示任意节点 IP
+	Port   int32        `json:"port"`   // 对外端口，NodePort 类型为节点端口
+	Scheme string       `json:"scheme"` // http, https
+	Path   string       `json:"path"`   // ingress 路径
+	Ready  bool         `json:"ready"`  // LB 或 ingress 还没有分配地址时为 false
+}
+
+// Address host:port，用于兼容 LoadBalancer 字段
+func (e Endpoint) Address() string {
+	return fmt.Sprintf("%s:%d", e.Host, e.Port)
+}
+
+func (e Endpoint) URL() string {
+	if e.Host == "" {
+		return ""
+	}
+	host := e.Host
+	if !(e.Scheme == "http" && e.Port == 80) && !(e.Scheme == "https" && e.Port == 443) && e.Port != 0 {
+		host = e.Address()
+	}
+	path := e.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("%s://%s%s", e.Scheme, host, path)
+}
+
+// IngressEndpoints rule 没有 host 时使用 ingress controller 分配的地址，还没有分配时 Ready=false
+func IngressEndpoints(ingress networkingv1.Ingress) []Endpoint {
+	tlsHosts := map[string]bool{}
+	for _, tls := range ingress.Spec.TLS {
+		for _, host := range tls.Hosts {
+			tlsHosts[host] = true
+		}
+	}
+	var address string
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.Hostname != "" {
+			address = lb.Hostname
+		} else {
+			address = lb.IP
+		}
+		if address != "" {
+			break
+		}
+	}
+	var endpoints []Endpoint
+	for _, rule := range ingress.Spec.Rules {
+		endpoint := Endpoint{
+			Type:   EndpointIngress,
+			Name:   ingress.Name,
+			Host:   rule.Host,
+			Port:   80,
+			Scheme: "http",
+			Path:   "/",
+		}
+		if rule.Host != "" && tlsHosts[rule.Host] {
+			endpoint.Port, endpoint.Scheme = 443, "https"
+		}
+		if endpoint.Host == "" {
+			endpoint.Host = address
+		}
+		endpoint.Ready = endpoint.Host != "" && address != ""
+		if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+			endpoint.Path = strings.TrimSuffix(rule.HTTP.Paths[0].Path, ingressPathRegexSuffix)
+			if !strings.HasSuffix(endpoint.Path, "/") {
+				endpoint.Path += "/"
+			}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
@@ -0,0 +1,36 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestIngressEndpoints(t *testing.T) {
+	ingress := networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			TLS: []networkingv1.IngressTLS{{Hosts: []string{"demo.flink.example.com"}}},
+			Rules: []networkingv1.IngressRule{
+				{Host: "demo.flink.example.com"},
+				{IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{Path: "/flink/demo(/|$)(.*)"}},
+				}}},
+			},
+		},
+	}
+	// controller 还没分配地址
+	endpoints := model.IngressEndpoints(ingress)
+	assert.Len(t, endpoints, 2)
+	assert.False(t, endpoints[0].Ready)
+	assert.Equal(t, "https://demo.flink.example.com/", endpoints[0].URL())
+	assert.False(t, endpoints[1].Ready)
+	assert.Equal(t, "", endpoints[1].URL())
+
+	ingress.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{Hostname: "lb.example.com"}}
+	endpoints = model.IngressEndpoints(ingress)
+	assert.True(t, endpoints[0].Ready)
+	assert.True(t, endpoints[1].Ready)
+	assert.Equal(t, "http://lb.example.com/flink/demo/", endpoints[1].URL())
+}
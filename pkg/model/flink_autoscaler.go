@@ -0,0 +1,305 @@
+package model
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+// https://nightlies.apache.org/flink/flink-kubernetes-operator-docs-stable/docs/custom-resource/autoscaler/
+
+const (
+	// operator 保存 autoscaler 状态的 ConfigMap
+	AutoscalerConfigMapName = "autoscaler-%s"
+	// 老版本 operator 将并行度建议写入 flinkConfiguration
+	parallelismOverridesKey = "pipeline.jobvertex-parallelism-overrides"
+)
+
+// FlinkAutoscaler 渲染为 job.autoscaler.* 配置，只支持 Application 集群
+type FlinkAutoscaler struct {
+	Enabled                   *bool    `json:"enabled" default:"true"`
+	ScalingEnabled            *bool    `json:"scaling_enabled" default:"true"` // false 时只计算建议并行度不执行扩缩
+	TargetUtilization         *float64 `json:"target_utilization"`             // 目标利用率，(0, 1]，默认 0.7
+	TargetUtilizationBoundary *float64 `json:"target_utilization_boundary"`    // 利用率容忍范围，[0, 1)，默认 0.3
+	StabilizationInterval     *string  `json:"stabilization_interval"`         // 扩缩后的稳定期，比如 5min
+	MetricsWindow             *string  `json:"metrics_window"`                 // 指标采集窗口，比如 10min
+	CatchUpDuration           *string  `json:"catch_up_duration"`              // 扩缩后追上积压数据的预期时间，比如 15min
+	RestartTime               *string  `json:"restart_time"`                   // 预期重启时间，比如 3min
+	MinParallelism            *int     `json:"min_parallelism"`                // 单个算子的最小并行度
+	MaxParallelism            *int     `json:"max_parallelism"`                // 单个算子的最大并行度
+}
+
+func (a *FlinkAutoscaler) Validate(job *Job) error {
+	if a == nil {
+		return nil
+	}
+	if job == nil {
+		return fmt.Errorf("autoscaler is only supported by application cluster")
+	}
+	if a.TargetUtilization != nil && (*a.TargetUtilization <= 0 || *a.TargetUtilization > 1) {
+		return fmt.Errorf("autoscaler.target_utilization must be in (0, 1]")
+	}
+	if a.TargetUtilizationBoundary != nil && (*a.TargetUtilizationBoundary < 0 || *a.TargetUtilizationBoundary >= 1) {
+		return fmt.Errorf("autoscaler.target_utilization_boundary must be in [0, 1)")
+	}
+	for _, duration := range []stateField{
+		{"stabilization_interval", a.StabilizationInterval},
+		{"metrics_window", a.MetricsWindow},
+		{"catch_up_duration", a.CatchUpDuration},
+		{"restart_time", a.RestartTime},
+	} {
+		if duration.value != nil && !flinkDurationRegexp.MatchString(*duration.value) {
+			return fmt.Errorf("autoscaler.%s must be a valid duration (e.g. '60s', '10min', '6h')", duration.name)
+		}
+	}
+	if a.MinParallelism != nil && *a.MinParallelism < 1 {
+		return fmt.Errorf("autoscaler.min_parallelism must be greater than 0")
+	}
+	if a.MaxParallelism != nil && *a.MaxParallelism < 1 {
+		return fmt.Errorf("autoscaler.max_parallelism must be greater than 0")
+	}
+	if a.MinParallelism != nil && a.MaxParallelism != nil && *a.MinParallelism > *a.MaxParallelism {
+		return fmt.Errorf("autoscaler.min_parallelism must not be greater than max_parallelism")
+	}
+	if job.Parallelism != nil && a.MaxParallelism != nil && int(*job.Parallelism) > *a.MaxParallelism {
+		return fmt.Errorf("job.parallelism must not be greater than autoscaler.max_parallelism")
+	}
+	return nil
+}
+
+// ToFlinkConfiguration 渲染为 flinkConfiguration 的键值
+func (a *FlinkAutoscaler) ToFlinkConfiguration() map[string]any {
+	config := map[string]any{}
+	if a == nil {
+		return config
+	}
+	enabled := true
+	if a.Enabled != nil {
+		enabled = *a.Enabled
+	}
+	config["job.autoscaler.enabled"] = fmt.Sprintf("%t", enabled)
+	if a.ScalingEnabled != nil {
+		config["job.autoscaler.scaling.enabled"] = fmt.Sprintf("%t", *a.ScalingEnabled)
+	}
+	if a.TargetUtilization != nil {
+		config["job.autoscaler.target.utilization"] = cast.ToString(*a.TargetUtilization)
+	}
+	if a.TargetUtilizationBoundary != nil {
+		config["job.autoscaler.target.utilization.boundary"] = cast.ToString(*a.TargetUtilizationBoundary)
+	}
+	if a.StabilizationInterval != nil {
+		config["job.autoscaler.stabilization.interval"] = *a.StabilizationInterval
+	}
+	if a.MetricsWindow != nil {
+		config["job.autoscaler.metrics.window"] = *a.MetricsWindow
+	}
+	if a.CatchUpDuration != nil {
+		config["job.autoscaler.catch-up.duration"] = *a.CatchUpDuration
+	}
+	if a.RestartTime != nil {
+		config["job.autoscaler.restart.time"] = *a.RestartTime
+	}
+	if a.MinParallelism != nil {
+		config["job.autoscaler.vertex.min-parallelism"] = fmt.Sprintf("%d", *a.MinParallelism)
+	}
+	if a.MaxParallelism != nil {
+		config["job.autoscaler.vertex.max-parallelism"] = fmt.Sprintf("%d", *a.MaxParallelism)
+	}
+	return config
+}
+
+type FlinkAutoscalerStatusRequest struct {
+	NameSpace   *string `json:"namespace" default:"default"`
+	ClusterName *string `json:"cluster_name" binding:"required"`
+}
+
+func (r *FlinkAutoscalerStatusRequest) Validate() error {
+	if r.ClusterName == nil || tea.StringValue(r.ClusterName) == "" {
+		return fmt.Errorf("cluster_name is required")
+	}
+	return nil
+}
+
+type FlinkAutoscalerStatus struct {
+	ClusterName          string              `json:"cluster_name"`
+	NameSpace            string              `json:"namespace"`
+	Enabled              bool                `json:"enabled"`
+	ScalingEnabled       bool                `json:"scaling_enabled"` // false 表示只给建议
+	LifecycleState       string              `json:"lifecycle_state"` // status.lifecycleState
+	JobState             string              `json:"job_state"`       // status.jobStatus.state
+	ParallelismOverrides map[string]int      `json:"parallelism_overrides"`
+	ScalingHistory       []FlinkScalingEvent `json:"scaling_history"` // 按时间倒序
+}
+
+type FlinkScalingEvent struct {
+	VertexID           string                        `json:"vertex_id"`
+	Time               time.Time                     `json:"time"`
+	CurrentParallelism int                           `json:"current_parallelism"`
+	NewParallelism     int                           `json:"new_parallelism"`
+	Metrics            map[string]FlinkScalingMetric `json:"metrics"` // 比如 TRUE_PROCESSING_RATE、LAG
+}
+
+// FlinkScalingMetric 指标为 NaN 时为 nil
+type FlinkScalingMetric struct {
+	Current *float64 `json:"current"`
+	Average *float64 `json:"average"`
+}
+
+type scalingSummary struct {
+	CurrentParallelism any                       `yaml:"currentParallelism"`
+	NewParallelism     any                       `yaml:"newParallelism"`
+	Metrics            map[string]map[string]any `yaml:"metrics"`
+}
+
+func scalingMetricValue(v any) *float64 {
+	if v == nil {
+		return nil
+	}
+	f, err := cast.ToFloat64E(v)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
+
+// NewFlinkAutoscalerStatus 从 FlinkDeployment 的配置和状态，以及 autoscaler ConfigMap 的 data 中解析，configMap 可以为 nil
+func NewFlinkAutoscalerStatus(item map[string]any, configMap map[string]string) (FlinkAutoscalerStatus, error) {
+	status := FlinkAutoscalerStatus{
+		ScalingEnabled:       true,
+		ParallelismOverrides: map[string]int{},
+		ScalingHistory:       []FlinkScalingEvent{},
+	}
+	metadata, _ := item["metadata"].(map[string]any)
+	status.ClusterName = cast.ToString(metadata["name"])
+	status.NameSpace = cast.ToString(metadata["namespace"])
+
+	spec, _ := item["spec"].(map[string]any)
+	config, _ := spec["flinkConfiguration"].(map[string]any)
+	status.Enabled = cast.ToBool(config["job.autoscaler.enabled"])
+	if v, ok := config["job.autoscaler.scaling.enabled"]; ok {
+		status.ScalingEnabled = cast.ToBool(v)
+	}
+	if v, ok := config[parallelismOverridesKey]; ok {
+		status.ParallelismOverrides = parseParallelismOverrides(cast.ToString(v))
+	}
+
+	if s, ok := item["status"].(map[string]any); ok {
+		status.LifecycleState = cast.ToString(s["lifecycleState"])
+		if jobStatus, ok := s["jobStatus"].(map[string]any); ok {
+			status.JobState = cast.ToString(jobStatus["state"])
+		}
+	}
+
+	if raw, ok := configMap["parallelismOverrides"]; ok {
+		data, err := decodeAutoscalerData(raw)
+		if err != nil {
+			return status, fmt.Errorf("decode parallelismOverrides failed: %v", err)
+		}
+		status.ParallelismOverrides = parseParallelismOverrides(data)
+	}
+	if raw, ok := configMap["scalingHistory"]; ok {
+		data, err := decodeAutoscalerData(raw)
+		if err != nil {
+			return status, fmt.Errorf("decode scalingHistory failed: %v", err)
+		}
+		history, err := parseScalingHistory(data)
+		if err != nil {
+			return status, err
+		}
+		status.ScalingHistory = history
+	}
+	return status, nil
+}
+
+// decodeAutoscalerData 新版本 operator 会 gzip 后 base64，老版本是明文 yaml
+func decodeAutoscalerData(raw string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return raw, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		return raw, nil
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseParallelismOverrides 格式为 vertex1:2,vertex2:4
+func parseParallelismOverrides(data string) map[string]int {
+	overrides := map[string]int{}
+	for _, item := range strings.Split(data, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		overrides[kv[0]] = cast.ToInt(strings.TrimSpace(kv[1]))
+	}
+	return overrides
+}
+
+// parseScalingHistory 格式为 vertex -> 时间 -> ScalingSummary
+func parseScalingHistory(data string) ([]FlinkScalingEvent, error) {
+	history := map[string]map[string]scalingSummary{}
+	if err := yaml.Unmarshal([]byte(data), &history); err != nil {
+		return nil, fmt.Errorf("parse scalingHistory failed: %v", err)
+	}
+	events := []FlinkScalingEvent{}
+	for vertexID, summaries := range history {
+		for ts, summary := range summaries {
+			t, err := time.Parse(time.RFC3339Nano, ts)
+			if err != nil {
+				return nil, fmt.Errorf("parse scalingHistory time %s failed: %v", ts, err)
+			}
+			event := FlinkScalingEvent{
+				VertexID:           vertexID,
+				Time:               t,
+				CurrentParallelism: cast.ToInt(summary.CurrentParallelism),
+				NewParallelism:     cast.ToInt(summary.NewParallelism),
+				Metrics:            map[string]FlinkScalingMetric{},
+			}
+			for name, metric := range summary.Metrics {
+				event.Metrics[name] = FlinkScalingMetric{
+					Current: scalingMetricValue(metric["current"]),
+					Average: scalingMetricValue(metric["average"]),
+				}
+			}
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Time.Equal(events[j].Time) {
+			return events[i].VertexID < events[j].VertexID
+		}
+		return events[i].Time.After(events[j].Time)
+	})
+	return events, nil
+}
+
+// Recommendations 最近一次扩缩每个算子的建议并行度
+func (s *FlinkAutoscalerStatus) Recommendations() map[string]int {
+	recommendations := map[string]int{}
+	for _, event := range s.ScalingHistory {
+		if _, ok := recommendations[event.VertexID]; !ok {
+			recommendations[event.VertexID] = event.NewParallelism
+		}
+	}
+	for k, v := range s.ParallelismOverrides {
+		recommendations[k] = v
+	}
+	return recommendations
+}
@@ -0,0 +1,267 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	appv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// operator 和 v1.12 两种集群统一的列表，状态归一化为 FlinkClusterState
+
+type FlinkClusterState string
+
+const (
+	FlinkClusterRunning   FlinkClusterState = "RUNNING"   // JM 就绪，作业运行中，session 集群只看 JM
+	FlinkClusterDeploying FlinkClusterState = "DEPLOYING" // 创建、升级或者重启中
+	FlinkClusterFailed    FlinkClusterState = "FAILED"
+	FlinkClusterSuspended FlinkClusterState = "SUSPENDED" // operator job.state=suspended，v1.12 副本数为 0
+	FlinkClusterUnknown   FlinkClusterState = "UNKNOWN"
+)
+
+// v1.12 集群固定的版本
+const FlinkV12Version = "v1_12"
+
+type FlinkClusterStatus struct {
+	State               FlinkClusterState `json:"state"`
+	JobState            string            `json:"job_state,omitempty"` // operator jobStatus.state，v1.12 没有
+	JobManagerReady     bool              `json:"job_manager_ready"`
+	TaskManagerReplicas int32             `json:"task_manager_replicas"`
+	TaskManagerReady    int32             `json:"task_manager_ready"`
+	Error               string            `json:"error,omitempty"`
+}
+
+type FlinkCluster struct {
+	Flavor      FlinkFlavor        `json:"flavor"`
+	ClusterName string             `json:"cluster_name"`
+	NameSpace   string             `json:"namespace"`
+	Owner       string             `json:"owner"`
+	Version     string             `json:"version"` // v1_17 格式，v1.12 集群为 v1_12
+	Image       string             `json:"image"`
+	Mode        string             `json:"mode"` // application 或者 session
+	CreateTime  time.Time          `json:"create_time"`
+	Labels      map[string]string  `json:"labels"`
+	Status      FlinkClusterStatus `json:"status"`
+	Endpoints   []Endpoint         `json:"endpoints"`
+}
+
+type FlinkClusterListResponse struct {
+	Total int            `json:"total"`
+	Items []FlinkCluster `json:"items"`
+}
+
+type FilterFlinkCluster struct {
+	NameSpace *string     `json:"namespace" default:"default"`
+	Flavor    FlinkFlavor `json:"flavor"`                 // operator 或 v12，为空时查询两种
+	Owner     *string     `json:"owner"`                  // labels.owner
+	Name      *string     `json:"name"`                   // 名称包含
+	State     *string     `json:"state"`                  // RUNNING、DEPLOYING、FAILED、SUSPENDED、UNKNOWN，多个用逗号分隔
+	Version   *string     `json:"version"`                // 支持 v1_17 和 1.17
+	SortBy    *string     `json:"sort_by" default:"name"` // name、create_time、state、owner、version
+	Desc      *bool       `json:"desc"`
+}
+
+func (f *FilterFlinkCluster) Validate() error {
+	if f.Flavor != "" && f.Flavor != FlinkFlavorOperator && f.Flavor != FlinkFlavorV12 {
+		return fmt.Errorf("flavor must be %s or %s", FlinkFlavorOperator, FlinkFlavorV12)
+	}
+	if f.State != nil {
+		for _, state := range strings.Split(*f.State, ",") {
+			switch FlinkClusterState(strings.ToUpper(strings.TrimSpace(state))) {
+			case FlinkClusterRunning, FlinkClusterDeploying, FlinkClusterFailed, FlinkClusterSuspended, FlinkClusterUnknown:
+			default:
+				return fmt.Errorf("unknown state %s", state)
+			}
+		}
+	}
+	switch tea.StringValue(f.SortBy) {
+	case "", "name", "create_time", "state", "owner", "version":
+	default:
+		return fmt.Errorf("unknown sort_by %s", *f.SortBy)
+	}
+	return nil
+}
+
+// HasFlavor 是否需要查询该类型的集群
+func (f *FilterFlinkCluster) HasFlavor(flavor FlinkFlavor) bool {
+	return f.Flavor == "" || f.Flavor == flavor
+}
+
+// LabelSelector owner 在 k8s 侧过滤，其他条件在 Match 中过滤
+func (f *FilterFlinkCluster) LabelSelector() *string {
+	if f.Owner != nil && *f.Owner != "" {
+		return tea.String(fmt.Sprintf("owner=%s", *f.Owner))
+	}
+	return nil
+}
+
+func (f *FilterFlinkCluster) Match(c FlinkCluster) bool {
+	if !f.HasFlavor(c.Flavor) {
+		return false
+	}
+	if f.Owner != nil && *f.Owner != "" && c.Owner != *f.Owner {
+		return false
+	}
+	if f.Name != nil && !strings.Contains(c.ClusterName, *f.Name) {
+		return false
+	}
+	if f.Version != nil && *f.Version != "" && NormalizeFlinkVersion(*f.Version) != c.Version {
+		return false
+	}
+	if f.State != nil && *f.State != "" {
+		matched := false
+		for _, state := range strings.Split(*f.State, ",") {
+			if FlinkClusterState(strings.ToUpper(strings.TrimSpace(state))) == c.Status.State {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Sort 按 sort_by 排序，相同时按 namespace、名称和类型
+func (f *FilterFlinkCluster) Sort(items []FlinkCluster) {
+	key := func(c FlinkCluster) string {
+		switch tea.StringValue(f.SortBy) {
+		case "create_time":
+			return c.CreateTime.UTC().Format(time.RFC3339)
+		case "state":
+			return string(c.Status.State)
+		case "owner":
+			return c.Owner
+		case "version":
+			return c.Version
+		}
+		return c.ClusterName
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if ka, kb := key(a), key(b); ka != kb {
+			if tea.BoolValue(f.Desc) {
+				return ka > kb
+			}
+			return ka < kb
+		}
+		if a.NameSpace != b.NameSpace {
+			return a.NameSpace < b.NameSpace
+		}
+		if a.ClusterName != b.ClusterName {
+			return a.ClusterName < b.ClusterName
+		}
+		return a.Flavor < b.Flavor
+	})
+}
+
+// NormalizeFlinkVersion 1.17、v1.17、v1_17 统一为 v1_17
+func NormalizeFlinkVersion(version string) string {
+	version = strings.ReplaceAll(strings.TrimPrefix(strings.ToLower(version), "v"), ".", "_")
+	return "v" + version
+}
+
+// NewFlinkClusterFromOperator 从 FlinkDeployment 生成，TM 数量使用 status.taskManager.replicas
+func NewFlinkClusterFromOperator(item unstructured.Unstructured) FlinkCluster {
+	c := FlinkCluster{
+		Flavor:      FlinkFlavorOperator,
+		ClusterName: item.GetName(),
+		NameSpace:   item.GetNamespace(),
+		Owner:       item.GetLabels()["owner"],
+		CreateTime:  item.GetCreationTimestamp().Time,
+		Labels:      item.GetLabels(),
+		Mode:        "session",
+	}
+	c.Version, _, _ = unstructured.NestedString(item.Object, "spec", "flinkVersion")
+	c.Image, _, _ = unstructured.NestedString(item.Object, "spec", "image")
+	_, hasJob, _ := unstructured.NestedMap(item.Object, "spec", "job")
+	if hasJob {
+		c.Mode = "application"
+	}
+
+	status := &c.Status
+	status.JobState, _, _ = unstructured.NestedString(item.Object, "status", "jobStatus", "state")
+	status.Error, _, _ = unstructured.NestedString(item.Object, "status", "error")
+	jmStatus, _, _ := unstructured.NestedString(item.Object, "status", "jobManagerDeploymentStatus")
+	status.JobManagerReady = jmStatus == "READY"
+	if replicas, ok, _ := unstructured.NestedInt64(item.Object, "status", "taskManager", "replicas"); ok {
+		status.TaskManagerReplicas = int32(replicas)
+	} else if replicas, ok, _ := unstructured.NestedInt64(item.Object, "spec", "taskManager", "replicas"); ok {
+		status.TaskManagerReplicas = int32(replicas)
+	}
+	if status.JobManagerReady {
+		status.TaskManagerReady = status.TaskManagerReplicas
+	}
+
+	lifecycle, _, _ := unstructured.NestedString(item.Object, "status", "lifecycleState")
+	jobState, _, _ := unstructured.NestedString(item.Object, "spec", "job", "state")
+	switch {
+	case lifecycle == "FAILED" || jmStatus == "ERROR" || status.JobState == "FAILED":
+		status.State = FlinkClusterFailed
+	case lifecycle == "SUSPENDED" || jobState == "suspended":
+		status.State = FlinkClusterSuspended
+	case status.JobManagerReady && (!hasJob || status.JobState == "RUNNING"):
+		status.State = FlinkClusterRunning
+	case jmStatus == "MISSING" && lifecycle == "STABLE":
+		// JM deployment 被删除
+		status.State = FlinkClusterUnknown
+	default:
+		// 包括 operator 还没有处理的情况
+		status.State = FlinkClusterDeploying
+	}
+	return c
+}
+
+// NewFlinkClusterFromV12 从 JM 和 TM deployment 生成，tm 可以为 nil
+func NewFlinkClusterFromV12(clusterName string, jm, tm *appv1.Deployment) FlinkCluster {
+	c := FlinkCluster{
+		Flavor:      FlinkFlavorV12,
+		ClusterName: clusterName,
+		NameSpace:   jm.Namespace,
+		Owner:       jm.Labels["owner"],
+		Version:     FlinkV12Version,
+		Mode:        "session",
+		CreateTime:  jm.CreationTimestamp.Time,
+		Labels:      jm.Labels,
+	}
+	if container, err := findContainer(jm, "jobmanager"); err == nil {
+		c.Image = container.Image
+	}
+
+	status := &c.Status
+	jmReplicas := tea.Int32Value(jm.Spec.Replicas)
+	status.JobManagerReady = jmReplicas > 0 && jm.Status.ReadyReplicas >= jmReplicas
+	if tm != nil {
+		status.TaskManagerReplicas = tea.Int32Value(tm.Spec.Replicas)
+		status.TaskManagerReady = tm.Status.ReadyReplicas
+	}
+	for _, dep := range []*appv1.Deployment{jm, tm} {
+		if dep == nil {
+			continue
+		}
+		for _, condition := range dep.Status.Conditions {
+			if condition.Type == appv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+				status.Error = fmt.Sprintf("%s: %s", dep.Name, condition.Message)
+			}
+		}
+	}
+	switch {
+	case status.Error != "":
+		status.State = FlinkClusterFailed
+	case tm == nil:
+		status.State = FlinkClusterUnknown
+		status.Error = fmt.Sprintf("deployment %s not found", fmt.Sprintf(TaskManagerDeploymentName, clusterName))
+	case jmReplicas == 0:
+		status.State = FlinkClusterSuspended
+	case status.JobManagerReady && status.TaskManagerReady >= status.TaskManagerReplicas:
+		status.State = FlinkClusterRunning
+	default:
+		status.State = FlinkClusterDeploying
+	}
+	return c
+}
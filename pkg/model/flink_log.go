@@ -0,0 +1,191 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+type LogVolumeType string
+
+const (
+	LogVolumeEmptyDir LogVolumeType = "emptyDir"
+	LogVolumeHostPath LogVolumeType = "hostPath"
+	LogVolumePVC      LogVolumeType = "pvc"
+
+	// sidecar 中日志目录的挂载路径
+	LogSideCarMountPath = "/flink-logs"
+	// sidecar 配置文件默认挂载路径
+	LogSideCarConfigMountPath = "/etc/log-shipper"
+)
+
+// FlinkLogCollection 日志收集配置，同时适用于 Operator 和 v1.12 集群
+type FlinkLogCollection struct {
+	VolumeType    LogVolumeType `json:"volume_type" default:"emptyDir"` // emptyDir, hostPath, pvc
+	HostPath      *string       `json:"host_path"`                      // hostPath 模式下的宿主机目录，默认 /mnt/log/<cluster>/
+	ClaimName     *string       `json:"claim_name"`                     // pvc 模式下使用的 pvc，多个 TM 共用时需要 ReadWriteMany
+	SideCar       *LogSideCar   `json:"side_car"`                       // 日志采集 sidecar，nil 不启用
+	Log4jConfig   *string       `json:"log4j_config"`                   // 自定义 log4j-console.properties 内容
+	LogbackConfig *string       `json:"logback_config"`                 // 自定义 logback-console.xml 内容
+}
+
+type LogSideCar struct {
+	Name            *string           `json:"name" default:"log-shipper"`
+	Image           *string           `json:"image" binding:"required"` // 比如 fluent/fluent-bit:2.2.0
+	Command         []string          `json:"command"`
+	Args            []string          `json:"args"`
+	Env             []Env             `json:"env"`
+	Resource        *FlinkResource    `json:"resource"`
+	Config          map[string]string `json:"config"`            // 文件名 -> 内容，渲染到 <cluster>-log-config ConfigMap
+	ConfigMountPath *string           `json:"config_mount_path"` // 默认 /etc/log-shipper
+}
+
+func (l *FlinkLogCollection) Validate() error {
+	if l == nil {
+		return nil
+	}
+	switch l.VolumeType {
+	case "", LogVolumeEmptyDir, LogVolumeHostPath:
+	case LogVolumePVC:
+		if l.ClaimName == nil || *l.ClaimName == "" {
+			return fmt.Errorf("log_collection.claim_name is required when volume_type is pvc")
+		}
+	default:
+		return fmt.Errorf("log_collection.volume_type must be one of: emptyDir, hostPath, pvc")
+	}
+	if l.SideCar != nil {
+		if l.SideCar.Image == nil || *l.SideCar.Image == "" {
+			return fmt.Errorf("log_collection.side_car.image is required")
+		}
+		for k := range l.SideCar.Config {
+			if k == "" {
+				return fmt.Errorf("log_collection.side_car.config key must not be empty")
+			}
+		}
+	}
+	return nil
+}
+
+// logVolume 日志卷，name 为卷名称，defaultHostPath 为未指定 host_path 时使用的目录
+func (l *FlinkLogCollection) logVolume(name, defaultHostPath string) map[string]any {
+	volume := map[string]any{"name": name}
+	switch l.VolumeType {
+	case "", LogVolumeEmptyDir:
+		volume["emptyDir"] = map[string]any{}
+	case LogVolumePVC:
+		volume["persistentVolumeClaim"] = map[string]any{
+			"claimName": tea.StringValue(l.ClaimName),
+		}
+	default:
+		path := defaultHostPath
+		if l.HostPath != nil {
+			path = *l.HostPath
+		}
+		volume["hostPath"] = map[string]any{
+			"path": path,
+			"type": "DirectoryOrCreate",
+		}
+	}
+	return volume
+}
+
+func (l *FlinkLogCollection) hasSideCarConfig() bool {
+	return l.SideCar != nil && len(l.SideCar.Config) > 0
+}
+
+// sideCarContainer 日志采集容器，logVolumeName 为日志卷名称
+func (l *FlinkLogCollection) sideCarContainer(logVolumeName string) map[string]any {
+	if l.SideCar == nil {
+		return nil
+	}
+	name := "log-shipper"
+	if l.SideCar.Name != nil {
+		name = *l.SideCar.Name
+	}
+	container := map[string]any{
+		"name":  name,
+		"image": tea.StringValue(l.SideCar.Image),
+	}
+	if len(l.SideCar.Command) > 0 {
+		container["command"] = l.SideCar.Command
+	}
+	if len(l.SideCar.Args) > 0 {
+		container["args"] = l.SideCar.Args
+	}
+	if len(l.SideCar.Env) > 0 {
+		container["env"] = l.SideCar.Env
+	}
+	if l.SideCar.Resource != nil {
+		limits := map[string]any{}
+		if l.SideCar.Resource.CPU != nil {
+			limits["cpu"] = *l.SideCar.Resource.CPU
+		}
+		if l.SideCar.Resource.Memory != nil {
+			limits["memory"] = *l.SideCar.Resource.Memory
+		}
+		container["resources"] = map[string]any{"limits": limits}
+	}
+	volumeMounts := []map[string]any{
+		{
+			"mountPath": LogSideCarMountPath,
+			"name":      logVolumeName,
+		},
+	}
+	if l.hasSideCarConfig() {
+		mountPath := LogSideCarConfigMountPath
+		if l.SideCar.ConfigMountPath != nil {
+			mountPath = *l.SideCar.ConfigMountPath
+		}
+		volumeMounts = append(volumeMounts, map[string]any{
+			"mountPath": mountPath,
+			"name":      "log-config",
+		})
+	}
+	container["volumeMounts"] = volumeMounts
+	return container
+}
+
+// configVolume sidecar 配置卷，没有配置时返回 nil
+func (l *FlinkLogCollection) configVolume(clusterName string) map[string]any {
+	if !l.hasSideCarConfig() {
+		return nil
+	}
+	return map[string]any{
+		"name": "log-config",
+		"configMap": map[string]any{
+			"name": fmt.Sprintf(LogConfigMapName, clusterName),
+		},
+	}
+}
+
+// NewConfigMap 渲染 sidecar 配置的 ConfigMap，没有配置时返回 nil
+func (l *FlinkLogCollection) NewConfigMap(namespace *string, clusterName string, labels map[string]string) *ApplyConfigMapRequest {
+	if l == nil || !l.hasSideCarConfig() {
+		return nil
+	}
+	_labels := map[string]string{"app": clusterName}
+	for k, v := range labels {
+		_labels[k] = v
+	}
+	return &ApplyConfigMapRequest{
+		Namespace: namespace,
+		Name:      tea.String(fmt.Sprintf(LogConfigMapName, clusterName)),
+		Labels:    _labels,
+		Data:      l.SideCar.Config,
+	}
+}
+
+// logConfiguration 对应 FlinkDeployment spec.logConfiguration
+func (l *FlinkLogCollection) logConfiguration() map[string]any {
+	config := map[string]any{}
+	if l == nil {
+		return config
+	}
+	if l.Log4jConfig != nil {
+		config["log4j-console.properties"] = *l.Log4jConfig
+	}
+	if l.LogbackConfig != nil {
+		config["logback-console.xml"] = *l.LogbackConfig
+	}
+	return config
+}
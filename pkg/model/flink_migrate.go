@@ -0,0 +1,240 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// MigrateFlinkV12Request 把 v1.12 集群迁移为 operator 管理的 FlinkDeployment
+type MigrateFlinkV12Request struct {
+	NameSpace          *string `json:"namespace" default:"default"`
+	ClusterName        *string `json:"cluster_name" binding:"required"` // v1.12 集群名称
+	TargetName         *string `json:"target_name"`                     // FlinkDeployment 名称，默认和 v1.12 集群相同，LB 会被接管保持地址不变
+	Image              *string `json:"image"`                           // operator 最低支持 1.13，默认沿用 v1.12 的镜像
+	Version            *string `json:"version" default:"v1_13"`
+	Submitter          *string `json:"submitter"`           // 默认使用 v1.12 集群的 owner
+	Job                *Job    `json:"job"`                 // application 模式的作业，为空时创建 session 集群
+	Savepoint          *bool   `json:"savepoint"`           // 先 stop-with-savepoint，新集群从 savepoint 恢复，需要 job
+	JobID              *string `json:"job_id"`              // 需要 savepoint 的作业，为空时使用唯一运行中的作业
+	SavepointDirectory *string `json:"savepoint_directory"` // 为空时使用 state.savepoints.dir
+	JarID              *string `json:"jar_id"`              // 作业在 v1.12 集群上的 jar id，回滚时从 savepoint 重新提交，为空时按 job.jar_url 的文件名匹配
+	DryRun             *bool   `json:"dry_run"`             // 只返回生成的 FlinkDeployment，不做任何修改
+	Timeout            *string `json:"timeout"`             // 等待 FlinkDeployment 就绪的时间，比如 30m，默认 10 分钟，超时回滚
+}
+
+func (req *MigrateFlinkV12Request) Validate() error {
+	if req.ClusterName == nil || *req.ClusterName == "" {
+		return fmt.Errorf("cluster_name is required")
+	}
+	if tea.BoolValue(req.Savepoint) && req.Job == nil {
+		return fmt.Errorf("job is required when savepoint is true, session cluster can not restore from savepoint")
+	}
+	if _, err := parseTimeout("timeout", req.Timeout, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetTimeout 请先调用 Validate 校验
+func (req *MigrateFlinkV12Request) GetTimeout() time.Duration {
+	timeout, _ := parseTimeout("timeout", req.Timeout, 10*time.Minute)
+	return timeout
+}
+
+func (req *MigrateFlinkV12Request) GetTargetName() string {
+	if req.TargetName != nil && *req.TargetName != "" {
+		return *req.TargetName
+	}
+	return *req.ClusterName
+}
+
+type MigrateFlinkV12Response struct {
+	DryRun          bool                      `json:"dry_run"`
+	Request         CreateFlinkClusterRequest `json:"request"`          // 生成的 operator 创建请求
+	FlinkDeployment map[string]any            `json:"flink_deployment"` // 生成的 FlinkDeployment yaml
+	JobID           string                    `json:"job_id,omitempty"`
+	SavepointPath   string                    `json:"savepoint_path,omitempty"`
+	Steps           []string                  `json:"steps"`    // 已经执行的步骤
+	Warnings        []string                  `json:"warnings"` // 没有迁移的配置，需要人工确认
+	RolledBack      bool                      `json:"rolled_back"`
+}
+
+// FlinkV12Snapshot 迁移时读取的 v1.12 集群资源
+type FlinkV12Snapshot struct {
+	JobManager  *appv1.Deployment
+	TaskManager *appv1.Deployment
+	ConfigMap   *v1.ConfigMap
+	LBService   *v1.Service // 没有 LB 时为 nil
+	PvcExists   bool
+}
+
+// v1.12 集群固定的端口和地址配置，operator 会自己生成
+var v12OnlyConfiguration = []string{
+	"jobmanager.rpc.address",
+	"jobmanager.rpc.port",
+	"taskmanager.rpc.port",
+	"blob.server.port",
+	"queryable-state.proxy.ports",
+	"web.upload.dir",
+}
+
+// ToCreateRequest 按 v1.12 集群的配置生成 operator 创建请求，无法迁移的配置记录在 warnings
+func (s *FlinkV12Snapshot) ToCreateRequest(req MigrateFlinkV12Request) (CreateFlinkClusterRequest, []string, error) {
+	var warnings []string
+	jm, tm := s.JobManager, s.TaskManager
+	jmContainer, err := findContainer(jm, "jobmanager")
+	if err != nil {
+		return CreateFlinkClusterRequest{}, nil, err
+	}
+	tmContainer, err := findContainer(tm, "taskmanager")
+	if err != nil {
+		return CreateFlinkClusterRequest{}, nil, err
+	}
+
+	create := CreateFlinkClusterRequest{
+		NameSpace:   tea.String(jm.Namespace),
+		ClusterName: tea.String(req.GetTargetName()),
+		Image:       tea.String(jmContainer.Image),
+		Version:     tea.String("v1_13"),
+		Job:         req.Job,
+	}
+	if req.Image != nil {
+		create.Image = req.Image
+	} else {
+		warnings = append(warnings, fmt.Sprintf("image %s is kept, operator requires flink 1.13 or later", jmContainer.Image))
+	}
+	if req.Version != nil {
+		create.Version = req.Version
+	}
+	if req.Submitter != nil {
+		create.Submitter = req.Submitter
+	} else if owner, ok := jm.Labels["owner"]; ok && owner != "" {
+		create.Submitter = tea.String(owner)
+	}
+	for k, v := range jm.Labels {
+		if k == "app" || k == "owner" || k == "component" {
+			continue
+		}
+		if create.Labels == nil {
+			create.Labels = map[string]string{}
+		}
+		create.Labels[k] = v
+	}
+	for _, env := range jmContainer.Env {
+		create.Env = append(create.Env, Env{Name: tea.String(env.Name), Value: tea.String(env.Value)})
+	}
+
+	// flink-conf.yaml
+	if s.ConfigMap != nil {
+		flinkConfiguration, err := ConvertYamlToMap(s.ConfigMap.Data["flink-conf.yaml"])
+		if err != nil {
+			return CreateFlinkClusterRequest{}, nil, err
+		}
+		for _, k := range v12OnlyConfiguration {
+			delete(flinkConfiguration, k)
+		}
+		create.FlinkConfiguration = flinkConfiguration
+		if logback := s.ConfigMap.Data["logback-console.xml"]; logback != "" && logback != LogbackConsole {
+			create.LogCollection = &FlinkLogCollection{LogbackConfig: tea.String(logback)}
+		}
+	}
+
+	create.JobManager, warnings = migrateManager(jm, jmContainer, warnings)
+	create.TaskManager, warnings = migrateManager(tm, tmContainer, warnings)
+	// native 模式 TM 数量由 parallelism / slots 决定
+	if create.Job != nil && create.Job.Parallelism == nil && tm.Spec.Replicas != nil {
+		slots := 1
+		if raw, ok := create.FlinkConfiguration["taskmanager.numberOfTaskSlots"]; ok {
+			if n, ok := raw.(int); ok && n > 0 {
+				slots = n
+			}
+		}
+		job := *create.Job
+		job.Parallelism = tea.Int32(*tm.Spec.Replicas * int32(slots))
+		create.Job = &job
+	}
+	if len(jm.Spec.Template.Spec.Containers) > 1 {
+		warnings = append(warnings, "jobmanager sidecars are not migrated")
+	}
+	if !s.PvcExists {
+		warnings = append(warnings, fmt.Sprintf("pvc %s not found", fmt.Sprintf(PvcName, tea.StringValue(req.ClusterName))))
+	} else {
+		warnings = append(warnings, "uploaded jars in pvc are not migrated, use job.jar_url")
+	}
+
+	if s.LBService != nil {
+		lb := &LoadBalancerRequest{Annotations: s.LBService.Annotations}
+		// 同名时接管原来的 LB，保持端口不变
+		if req.GetTargetName() == tea.StringValue(req.ClusterName) {
+			for _, port := range s.LBService.Spec.Ports {
+				if port.NodePort != 0 {
+					lb.Port = tea.Int32(port.NodePort)
+					break
+				}
+			}
+		}
+		create.LoadBalancer = lb
+	}
+	return create, warnings, nil
+}
+
+func findContainer(dep *appv1.Deployment, name string) (*v1.Container, error) {
+	for i := range dep.Spec.Template.Spec.Containers {
+		if dep.Spec.Template.Spec.Containers[i].Name == name {
+			return &dep.Spec.Template.Spec.Containers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("container %s not found in deployment %s", name, dep.Name)
+}
+
+// migrateManager operator 的 cpu 是整数，memory 使用 Mi
+func migrateManager(dep *appv1.Deployment, container *v1.Container, warnings []string) (*Manager, []string) {
+	manager := &Manager{}
+	if nodeSelector := dep.Spec.Template.Spec.NodeSelector; len(nodeSelector) > 0 {
+		manager.NodeSelector = &nodeSelector
+	}
+	cpu, memory := container.Resources.Limits.Cpu(), container.Resources.Limits.Memory()
+	if !cpu.IsZero() && !memory.IsZero() {
+		cores := (cpu.MilliValue() + 999) / 1000
+		if cores*1000 != cpu.MilliValue() {
+			warnings = append(warnings, fmt.Sprintf("%s cpu %s is rounded up to %d", container.Name, cpu, cores))
+		}
+		manager.Resource = &FlinkResource{
+			CPU:    tea.String(fmt.Sprint(cores)),
+			Memory: tea.String(fmt.Sprintf("%dMi", memory.Value()/(1024*1024))),
+		}
+	}
+	return manager, warnings
+}
+
+// FlinkDeploymentHealth JM 就绪并且作业运行中（session 集群只看 JM）时返回 true，失败时返回 error
+func FlinkDeploymentHealth(item unstructured.Unstructured) (bool, error) {
+	if lifecycle, _, _ := unstructured.NestedString(item.Object, "status", "lifecycleState"); lifecycle == "FAILED" {
+		message, _, _ := unstructured.NestedString(item.Object, "status", "error")
+		return false, fmt.Errorf("flinkdeployment %s failed: %s", item.GetName(), message)
+	}
+	jmStatus, _, _ := unstructured.NestedString(item.Object, "status", "jobManagerDeploymentStatus")
+	if jmStatus == "ERROR" {
+		message, _, _ := unstructured.NestedString(item.Object, "status", "error")
+		return false, fmt.Errorf("flinkdeployment %s jobmanager error: %s", item.GetName(), message)
+	}
+	if jmStatus != "READY" {
+		return false, nil
+	}
+	if _, hasJob, _ := unstructured.NestedMap(item.Object, "spec", "job"); !hasJob {
+		return true, nil
+	}
+	state, _, _ := unstructured.NestedString(item.Object, "status", "jobStatus", "state")
+	switch state {
+	case "RUNNING":
+		return true, nil
+	case "FAILED":
+		return false, fmt.Errorf("flinkdeployment %s job failed", item.GetName())
+	}
+	return false, nil
+}
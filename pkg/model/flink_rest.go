@@ -0,0 +1,170 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// https://nightlies.apache.org/flink/flink-docs-stable/docs/ops/rest_api/
+
+type FlinkFlavor string
+
+const (
+	FlinkFlavorOperator FlinkFlavor = "operator" // flink-kubernetes-operator 管理的 FlinkDeployment
+	FlinkFlavorV12      FlinkFlavor = "v12"      // FlinkV12ClusterCreate 创建的 Deployment 集群
+
+	// operator 为 JM 创建的 rest service
+	RestServiceName = "%s-rest"
+)
+
+type FlinkRestRequest struct {
+	NameSpace   *string     `json:"namespace" default:"default"`
+	ClusterName *string     `json:"cluster_name" binding:"required"`
+	Flavor      FlinkFlavor `json:"flavor"` // operator 或 v12，为空时自动识别
+	JobID       *string     `json:"job_id"` // 查询 job 相关接口时必填
+}
+
+// RestService 返回 JM rest 接口所在的 service 名称和端口名称
+func (r *FlinkRestRequest) RestService(flavor FlinkFlavor) (string, string) {
+	if flavor == FlinkFlavorV12 {
+		return fmt.Sprintf(JobManagerServiceName, tea.StringValue(r.ClusterName)), "webui"
+	}
+	return fmt.Sprintf(RestServiceName, tea.StringValue(r.ClusterName)), "rest"
+}
+
+func (r *FlinkRestRequest) Validate() error {
+	if r.ClusterName == nil || *r.ClusterName == "" {
+		return fmt.Errorf("cluster_name is required")
+	}
+	if r.Flavor != "" && r.Flavor != FlinkFlavorOperator && r.Flavor != FlinkFlavorV12 {
+		return fmt.Errorf("flavor must be one of: operator, v12")
+	}
+	return nil
+}
+
+// GET /overview
+type FlinkClusterOverview struct {
+	TaskManagers   int    `json:"taskmanagers"`
+	SlotsTotal     int    `json:"slots-total"`
+	SlotsAvailable int    `json:"slots-available"`
+	JobsRunning    int    `json:"jobs-running"`
+	JobsFinished   int    `json:"jobs-finished"`
+	JobsCancelled  int    `json:"jobs-cancelled"`
+	JobsFailed     int    `json:"jobs-failed"`
+	FlinkVersion   string `json:"flink-version"`
+	FlinkCommit    string `json:"flink-commit"`
+}
+
+// GET /jobs/overview
+type FlinkJobsOverview struct {
+	Jobs []FlinkJobOverview `json:"jobs"`
+}
+
+type FlinkJobOverview struct {
+	Jid              string         `json:"jid"`
+	Name             string         `json:"name"`
+	State            string         `json:"state"` // RUNNING, FINISHED, CANCELED, FAILED ...
+	StartTime        int64          `json:"start-time"`
+	EndTime          int64          `json:"end-time"`
+	Duration         int64          `json:"duration"`
+	LastModification int64          `json:"last-modification"`
+	Tasks            map[string]int `json:"tasks"`
+}
+
+// GET /jobs/:jobid
+type FlinkJobDetail struct {
+	Jid            string           `json:"jid"`
+	Name           string           `json:"name"`
+	IsStoppable    bool             `json:"isStoppable"`
+	State          string           `json:"state"`
+	StartTime      int64            `json:"start-time"`
+	EndTime        int64            `json:"end-time"`
+	Duration       int64            `json:"duration"`
+	MaxParallelism int              `json:"maxParallelism"`
+	Now            int64            `json:"now"`
+	Timestamps     map[string]int64 `json:"timestamps"`
+	Vertices       []FlinkJobVertex `json:"vertices"`
+	StatusCounts   map[string]int   `json:"status-counts"`
+	Plan           map[string]any   `json:"plan"`
+}
+
+type FlinkJobVertex struct {
+	ID             string         `json:"id"`
+	Name           string         `json:"name"`
+	MaxParallelism int            `json:"maxParallelism"`
+	Parallelism    int            `json:"parallelism"`
+	Status         string         `json:"status"`
+	StartTime      int64          `json:"start-time"`
+	EndTime        int64          `json:"end-time"`
+	Duration       int64          `json:"duration"`
+	Tasks          map[string]int `json:"tasks"`
+	Metrics        map[string]any `json:"metrics"`
+}
+
+// GET /jobs/:jobid/exceptions
+type FlinkJobExceptions struct {
+	RootException    string                  `json:"root-exception"`
+	Timestamp        int64                   `json:"timestamp"`
+	Truncated        bool                    `json:"truncated"`
+	AllExceptions    []FlinkJobTaskException `json:"all-exceptions"`
+	ExceptionHistory *FlinkExceptionHistory  `json:"exceptionHistory"` // 1.13 之后才有
+}
+
+type FlinkJobTaskException struct {
+	Exception string `json:"exception"`
+	Task      string `json:"task"`
+	Location  string `json:"location"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type FlinkExceptionHistory struct {
+	Entries   []FlinkExceptionHistoryEntry `json:"entries"`
+	Truncated bool                         `json:"truncated"`
+}
+
+type FlinkExceptionHistoryEntry struct {
+	ExceptionName string `json:"exceptionName"`
+	Stacktrace    string `json:"stacktrace"`
+	Timestamp     int64  `json:"timestamp"`
+	TaskName      string `json:"taskName"`
+	Location      string `json:"location"`
+	TaskManagerID string `json:"taskManagerId"`
+}
+
+// GET /jobs/:jobid/checkpoints
+type FlinkJobCheckpoints struct {
+	Counts  FlinkCheckpointCounts    `json:"counts"`
+	Summary map[string]any           `json:"summary"`
+	Latest  FlinkLatestCheckpoints   `json:"latest"`
+	History []FlinkCheckpointHistory `json:"history"`
+}
+
+type FlinkCheckpointCounts struct {
+	Restored   int `json:"restored"`
+	Total      int `json:"total"`
+	InProgress int `json:"in_progress"`
+	Completed  int `json:"completed"`
+	Failed     int `json:"failed"`
+}
+
+type FlinkLatestCheckpoints struct {
+	Completed *FlinkCheckpointHistory `json:"completed"`
+	Savepoint *FlinkCheckpointHistory `json:"savepoint"`
+	Failed    *FlinkCheckpointHistory `json:"failed"`
+	Restored  *FlinkCheckpointHistory `json:"restored"`
+}
+
+type FlinkCheckpointHistory struct {
+	ID                 int64  `json:"id"`
+	Status             string `json:"status"`
+	IsSavepoint        bool   `json:"is_savepoint"`
+	TriggerTimestamp   int64  `json:"trigger_timestamp"`
+	LatestAckTimestamp int64  `json:"latest_ack_timestamp"`
+	StateSize          int64  `json:"state_size"`
+	EndToEndDuration   int64  `json:"end_to_end_duration"`
+	ExternalPath       string `json:"external_path"`
+	FailureTimestamp   int64  `json:"failure_timestamp"`
+	FailureMessage     string `json:"failure_message"`
+	RestoreTimestamp   int64  `json:"restore_timestamp"`
+}
@@ -0,0 +1,266 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+const (
+	StateBackendHashMap = "hashmap"
+	StateBackendRocksDB = "rocksdb"
+
+	kubernetesHaServicesFactory = "org.apache.flink.kubernetes.highavailability.KubernetesHaServicesFactory"
+)
+
+// flink 时间配置，比如 500ms、60s、10min、6h、1d
+var flinkDurationRegexp = regexp.MustCompile(`^\d+\s*(ms|s|min|m|h|d)?$`)
+
+// FlinkState 高可用、状态后端、checkpoint 和 savepoint 配置，最终渲染为 flinkConfiguration
+type FlinkState struct {
+	HighAvailability          *bool             `json:"high_availability"`               // 启用 Kubernetes HA，last-state 升级模式必须开启
+	HaStorageDir              *string           `json:"ha_storage_dir"`                  // HA 元数据目录，开启 HA 时必填
+	StateBackend              *string           `json:"state_backend" default:"hashmap"` // hashmap, rocksdb
+	Incremental               *bool             `json:"incremental"`                     // 增量 checkpoint，仅 rocksdb 支持
+	CheckpointDir             *string           `json:"checkpoint_dir"`                  // s3://bucket/path, oss://bucket/path, hdfs:///path
+	SavepointDir              *string           `json:"savepoint_dir"`                   // 同上，savepoint 升级模式必填
+	CheckpointInterval        *string           `json:"checkpoint_interval"`             // 比如 60s
+	CheckpointTimeout         *string           `json:"checkpoint_timeout"`              // 比如 10min
+	RetainedCheckpoints       *int              `json:"retained_checkpoints"`            // 保留的 checkpoint 数量
+	ExternalizedRetention     *string           `json:"externalized_retention"`          // RETAIN_ON_CANCELLATION, DELETE_ON_CANCELLATION
+	PeriodicSavepointInterval *string           `json:"periodic_savepoint_interval"`     // operator 定期触发 savepoint，比如 6h
+	SavepointHistoryMaxCount  *int              `json:"savepoint_history_max_count"`     // operator 保留的 savepoint 历史数量
+	Credentials               *StateCredentials `json:"credentials"`                     // 对象存储的访问凭证，hdfs 不需要
+}
+
+// StateCredentials 从 Secret 中读取 AK/SK，以环境变量的方式注入 JM/TM
+type StateCredentials struct {
+	SecretName   *string `json:"secret_name" binding:"required"`
+	AccessKeyKey *string `json:"access_key_key" default:"access-key"` // secret 中 AK 的 key
+	SecretKeyKey *string `json:"secret_key_key" default:"secret-key"` // secret 中 SK 的 key
+	Endpoint     *string `json:"endpoint"`                            // 比如 cos.ap-shanghai.myqcloud.com, oss-cn-hangzhou.aliyuncs.com
+}
+
+// storageScheme 返回存储类型 s3、oss、hdfs，不支持的返回空
+func storageScheme(dir string) string {
+	switch {
+	case strings.HasPrefix(dir, "s3://"), strings.HasPrefix(dir, "s3a://"), strings.HasPrefix(dir, "s3p://"):
+		return "s3"
+	case strings.HasPrefix(dir, "oss://"):
+		return "oss"
+	case strings.HasPrefix(dir, "hdfs://"):
+		return "hdfs"
+	}
+	return ""
+}
+
+type stateField struct {
+	name  string
+	value *string
+}
+
+func (s *FlinkState) dirs() []stateField {
+	return []stateField{
+		{"checkpoint_dir", s.CheckpointDir},
+		{"savepoint_dir", s.SavepointDir},
+		{"ha_storage_dir", s.HaStorageDir},
+	}
+}
+
+// scheme 所有目录使用的存储类型，Validate 保证一致
+func (s *FlinkState) scheme() string {
+	for _, dir := range s.dirs() {
+		if dir.value != nil {
+			return storageScheme(*dir.value)
+		}
+	}
+	return ""
+}
+
+// Validate 校验配置组合是否合法，job 为 nil 时表示 session 集群
+func (s *FlinkState) Validate(job *Job) error {
+	if s == nil {
+		return nil
+	}
+	backend := StateBackendHashMap
+	if s.StateBackend != nil {
+		backend = *s.StateBackend
+	}
+	if backend != StateBackendHashMap && backend != StateBackendRocksDB {
+		return fmt.Errorf("state.state_backend must be one of: hashmap, rocksdb")
+	}
+	if tea.BoolValue(s.Incremental) && backend != StateBackendRocksDB {
+		return fmt.Errorf("state.incremental is only supported by rocksdb state backend")
+	}
+
+	schemes := map[string]bool{}
+	for _, dir := range s.dirs() {
+		if dir.value == nil {
+			continue
+		}
+		scheme := storageScheme(*dir.value)
+		if scheme == "" {
+			return fmt.Errorf("state.%s must start with s3://, s3a://, s3p://, oss:// or hdfs://", dir.name)
+		}
+		schemes[scheme] = true
+	}
+	if tea.BoolValue(s.HighAvailability) && s.HaStorageDir == nil {
+		return fmt.Errorf("state.ha_storage_dir is required when high_availability is enabled")
+	}
+	if !tea.BoolValue(s.HighAvailability) && s.HaStorageDir != nil {
+		return fmt.Errorf("state.ha_storage_dir is set but high_availability is not enabled")
+	}
+	if s.Credentials != nil {
+		if s.Credentials.SecretName == nil || *s.Credentials.SecretName == "" {
+			return fmt.Errorf("state.credentials.secret_name is required")
+		}
+		if len(schemes) == 0 {
+			return fmt.Errorf("state.credentials requires at least one of checkpoint_dir, savepoint_dir, ha_storage_dir")
+		}
+		if len(schemes) > 1 {
+			return fmt.Errorf("state.credentials can only be used when all directories use the same storage")
+		}
+		if schemes["hdfs"] {
+			return fmt.Errorf("state.credentials is not supported for hdfs")
+		}
+	}
+
+	for _, duration := range []stateField{
+		{"checkpoint_interval", s.CheckpointInterval},
+		{"checkpoint_timeout", s.CheckpointTimeout},
+		{"periodic_savepoint_interval", s.PeriodicSavepointInterval},
+	} {
+		if duration.value != nil && !flinkDurationRegexp.MatchString(*duration.value) {
+			return fmt.Errorf("state.%s must be a valid duration (e.g. '60s', '10min', '6h')", duration.name)
+		}
+	}
+	if s.CheckpointInterval != nil && s.CheckpointDir == nil {
+		return fmt.Errorf("state.checkpoint_dir is required when checkpoint_interval is set")
+	}
+	if s.RetainedCheckpoints != nil && *s.RetainedCheckpoints < 1 {
+		return fmt.Errorf("state.retained_checkpoints must be greater than 0")
+	}
+	if s.ExternalizedRetention != nil {
+		if *s.ExternalizedRetention != "RETAIN_ON_CANCELLATION" && *s.ExternalizedRetention != "DELETE_ON_CANCELLATION" {
+			return fmt.Errorf("state.externalized_retention must be one of: RETAIN_ON_CANCELLATION, DELETE_ON_CANCELLATION")
+		}
+	}
+	if (s.PeriodicSavepointInterval != nil || s.SavepointHistoryMaxCount != nil) && s.SavepointDir == nil {
+		return fmt.Errorf("state.savepoint_dir is required when periodic savepoint is configured")
+	}
+	if s.PeriodicSavepointInterval != nil && job == nil {
+		return fmt.Errorf("state.periodic_savepoint_interval is only supported by application cluster")
+	}
+
+	if job != nil && job.UpgradeMode != nil {
+		switch *job.UpgradeMode {
+		case "last-state":
+			if !tea.BoolValue(s.HighAvailability) {
+				return fmt.Errorf("job.upgrade_mode last-state requires state.high_availability")
+			}
+		case "savepoint":
+			if s.SavepointDir == nil {
+				return fmt.Errorf("job.upgrade_mode savepoint requires state.savepoint_dir")
+			}
+		}
+	}
+	return nil
+}
+
+// ToFlinkConfiguration 渲染为 flinkConfiguration 的键值
+func (s *FlinkState) ToFlinkConfiguration() map[string]any {
+	config := map[string]any{}
+	if s == nil {
+		return config
+	}
+	if tea.BoolValue(s.HighAvailability) {
+		config["high-availability"] = kubernetesHaServicesFactory
+		config["high-availability.storageDir"] = tea.StringValue(s.HaStorageDir)
+	}
+	if s.StateBackend != nil {
+		config["state.backend"] = *s.StateBackend
+	}
+	if s.Incremental != nil {
+		config["state.backend.incremental"] = fmt.Sprintf("%t", *s.Incremental)
+	}
+	if s.CheckpointDir != nil {
+		config["state.checkpoints.dir"] = *s.CheckpointDir
+	}
+	if s.SavepointDir != nil {
+		config["state.savepoints.dir"] = *s.SavepointDir
+	}
+	if s.CheckpointInterval != nil {
+		config["execution.checkpointing.interval"] = *s.CheckpointInterval
+	}
+	if s.CheckpointTimeout != nil {
+		config["execution.checkpointing.timeout"] = *s.CheckpointTimeout
+	}
+	if s.RetainedCheckpoints != nil {
+		config["state.checkpoints.num-retained"] = fmt.Sprintf("%d", *s.RetainedCheckpoints)
+	}
+	if s.ExternalizedRetention != nil {
+		config["execution.checkpointing.externalized-checkpoint-retention"] = *s.ExternalizedRetention
+	}
+	if s.PeriodicSavepointInterval != nil {
+		config["kubernetes.operator.periodic.savepoint.interval"] = *s.PeriodicSavepointInterval
+	}
+	if s.SavepointHistoryMaxCount != nil {
+		config["kubernetes.operator.savepoint.history.max.count"] = fmt.Sprintf("%d", *s.SavepointHistoryMaxCount)
+	}
+	if s.Credentials != nil {
+		// 凭证通过环境变量注入，这里只指定读取环境变量的 provider
+		switch s.scheme() {
+		case "s3":
+			config["fs.s3a.aws.credentials.provider"] = "com.amazonaws.auth.EnvironmentVariableCredentialsProvider"
+			if s.Credentials.Endpoint != nil {
+				config["s3.endpoint"] = *s.Credentials.Endpoint
+			}
+		case "oss":
+			config["fs.oss.credentials.provider"] = "com.aliyun.oss.common.auth.EnvironmentVariableCredentialsProvider"
+			if s.Credentials.Endpoint != nil {
+				config["fs.oss.endpoint"] = *s.Credentials.Endpoint
+			}
+		}
+	}
+	return config
+}
+
+// credentialEnv 从 Secret 读取 AK/SK 的环境变量
+func (s *FlinkState) credentialEnv() []map[string]any {
+	if s == nil || s.Credentials == nil {
+		return nil
+	}
+	accessKeyKey, secretKeyKey := "access-key", "secret-key"
+	if s.Credentials.AccessKeyKey != nil {
+		accessKeyKey = *s.Credentials.AccessKeyKey
+	}
+	if s.Credentials.SecretKeyKey != nil {
+		secretKeyKey = *s.Credentials.SecretKeyKey
+	}
+	var accessKeyEnv, secretKeyEnv string
+	switch s.scheme() {
+	case "s3":
+		accessKeyEnv, secretKeyEnv = "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"
+	case "oss":
+		accessKeyEnv, secretKeyEnv = "OSS_ACCESS_KEY_ID", "OSS_ACCESS_KEY_SECRET"
+	default:
+		return nil
+	}
+	secretEnv := func(name, key string) map[string]any {
+		return map[string]any{
+			"name": name,
+			"valueFrom": map[string]any{
+				"secretKeyRef": map[string]any{
+					"name": tea.StringValue(s.Credentials.SecretName),
+					"key":  key,
+				},
+			},
+		}
+	}
+	return []map[string]any{
+		secretEnv(accessKeyEnv, accessKeyKey),
+		secretEnv(secretKeyEnv, secretKeyKey),
+	}
+}
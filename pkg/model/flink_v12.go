@@ -2,14 +2,11 @@ package model
 
 import (
 	"fmt"
-	"math/rand"
 	"strconv"
 	"strings"
 
 	"github.com/alibabacloud-go/tea/tea"
 	"gopkg.in/yaml.v2"
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 /*
@@ -132,67 +129,10 @@ var (
 	TaskManagerDeploymentName = "%s-taskmanager"
 	ConfigMapV12Name          = "%s-configmap"
 	PvcName                   = "%s-pvc"
+	LogConfigMapName          = "%s-log-config"
+	JobManagerIngressName     = "%s-jobmanager-ingress"
 
-	hostLogPath                                = "/mnt/log/%s-flink/"
-	rerouceJobManagerDeployment map[string]any = map[string]any{
-		"apiVersion": "apps/v1",
-		"kind":       "Deployment",
-		"metadata": map[string]any{
-			// "name":      "jobmanager",
-			"namespace": "default",
-		},
-		"spec": map[string]any{
-			"replicas": 1,
-			"selector": map[string]any{
-				"matchLabels": map[string]any{
-					"component": "jobmanager",
-					// "app":       "xxx",
-				},
-			},
-			"template": map[string]any{
-				"metadata": map[string]any{
-					"labels": map[string]any{
-						"component": "jobmanager",
-						// "app":       "xxx",
-					},
-				},
-				"spec": map[string]any{
-					"restartPolicy": "Always",
-				},
-			},
-			"strategy": map[string]any{
-				"type": "Recreate",
-			},
-		},
-	}
-	resourceTaskManagerDeployment = map[string]any{
-		"apiVersion": "apps/v1",
-		"kind":       "Deployment",
-		"metadata": map[string]any{
-			// "name":      "taskmanager",
-			"namespace": "default",
-		},
-		"spec": map[string]any{
-			"replicas": 1,
-			"selector": map[string]any{
-				"matchLabels": map[string]any{
-					"component": "taskmanager",
-					// "app":       "xxx",
-				},
-			},
-			"template": map[string]any{
-				"metadata": map[string]any{
-					"labels": map[string]any{
-						"component": "taskmanager",
-						// "app":       "xxx",
-					},
-				},
-				"spec": map[string]any{
-					"restartPolicy": "Always",
-				},
-			},
-		},
-	}
+	hostLogPath = "/mnt/log/%s-flink/"
 )
 
 type FilterFlinkV12 struct {
@@ -202,8 +142,23 @@ type FilterFlinkV12 struct {
 }
 
 type LoadBalancerRequest struct {
-	Annotations map[string]string `json:"annotations"`
-	Labels      map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	Labels       map[string]string `json:"labels"`
+	Port         *int32            `json:"port"`           // 固定端口，同时作为 service 端口和 NodePort，范围 30000-32767
+	AutoNodePort *bool             `json:"auto_node_port"` // 不指定 NodePort 由 k8s 分配，service 端口使用 8081
+}
+
+func (lb *LoadBalancerRequest) Validate() error {
+	if lb == nil || lb.Port == nil {
+		return nil
+	}
+	if *lb.Port < NodePortMin || *lb.Port > NodePortMax {
+		return fmt.Errorf("loadBalancer.port %d out of range %d-%d", *lb.Port, NodePortMin, NodePortMax)
+	}
+	if tea.BoolValue(lb.AutoNodePort) {
+		return fmt.Errorf("loadBalancer.port conflicts with auto_node_port")
+	}
+	return nil
 }
 
 type JobManagerV12 struct {
@@ -239,13 +194,31 @@ type CreateFlinkV12ClusterRequest struct {
 	JobManager         *JobManagerV12       `json:"jobManager"`
 	StorageClassName   *string              `json:"storageClassName"`
 	FlinkConfigRequest map[string]any       `json:"flinkConfigRequest"` // flink-conf.yaml 的具体配置，example：{"key":"key","value":"value"}
+	LogCollection      *FlinkLogCollection  `json:"logCollection"`      // 日志收集配置，默认 hostPath /mnt/log/<name>-flink/
+	Ingress            *FlinkIngress        `json:"ingress"`            // 通过 ingress 暴露 webui，设置后不配置 loadBalancer 则不再创建 LB
 	// NodeSelector       map[string]any       `json:"nodeSelector"`       // {"env":"flink"}
 }
 
+// ApplyFlinkV12ClusterRequest 只更新传入的字段，pod 模板或者 flink-conf.yaml 有变化时 JM/TM 会滚动重启
 type ApplyFlinkV12ClusterRequest struct {
-	Labels map[string]string `json:"labels"`
-	// Image              *string           `json:"image"`
-	FlinkConfiguration map[string]any `json:"flinkConfiguration"`
+	Labels             map[string]string `json:"labels"`
+	Image              *string           `json:"image"`
+	FlinkConfiguration map[string]any    `json:"flinkConfiguration"` // 全量替换 flink-conf.yaml
+	Env                map[string]string `json:"env"`                // 全量替换 JM/TM 的环境变量
+	JobManager         *JobManagerV12    `json:"jobManager"`         // 支持 resource、node_selector、side_cars，不支持修改 pvc_size
+	TaskManager        *TaskManagerV12   `json:"taskManager"`        // 支持 resource、node_selector、nu
+}
+
+type FlinkV12Change struct {
+	Resource string `json:"resource"` // deployment 或 configmap 名称
+	Field    string `json:"field"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+type ApplyFlinkV12ClusterResponse struct {
+	Changes   []FlinkV12Change `json:"changes"`
+	Restarted []string         `json:"restarted"` // pod 模板有变化，会滚动重启的 deployment
 }
 
 // 主要组装 Name和 Size
@@ -298,12 +271,8 @@ func (c *CreateFlinkV12ClusterRequest) NewService() ApplyServiceRequest {
 	return req
 }
 
-// Port 不指定自动分配
-func (c *CreateFlinkV12ClusterRequest) NewLBService() ApplyServiceRequest {
-	// 随机生成30000-32767端口
-	min := 30000
-	max := 32767
-	randPort := rand.Intn(max-min+1) + min
+// NewLBService nodePort 为 0 时不指定 NodePort，由 k8s 分配，端口见 service.applyLBService
+func (c *CreateFlinkV12ClusterRequest) NewLBService(nodePort int32) ApplyServiceRequest {
 	clusterName := *c.Name
 	req := ApplyServiceRequest{
 		Name:      tea.String(fmt.Sprintf(JobManagerLBServiceName, clusterName)),
@@ -313,16 +282,8 @@ func (c *CreateFlinkV12ClusterRequest) NewLBService() ApplyServiceRequest {
 		},
 		Spec: &ServiceSpec{
 			Selector: map[string]string{"app": clusterName, "component": "jobmanager"},
-			Ports: []Port{
-				{
-					Name:       tea.String("webui"),
-					Protocol:   tea.String("TCP"),
-					Port:       tea.Int32(int32(randPort)),
-					NodePort:   tea.Int32(int32(randPort)),
-					TargetPort: tea.Int32(8081),
-				},
-			},
-			Type: tea.String("LoadBalancer"),
+			Ports:    []Port{NewLBPort("webui", nodePort)},
+			Type:     tea.String("LoadBalancer"),
 		},
 	}
 	if c.LoadBalancer != nil {
@@ -339,6 +300,39 @@ func (c *CreateFlinkV12ClusterRequest) NewLBService() ApplyServiceRequest {
 	return req
 }
 
+// NeedLBService 兼容之前默认创建 LB，只配置了 ingress 时不再创建
+func (c *CreateFlinkV12ClusterRequest) NeedLBService() bool {
+	return c.LoadBalancer != nil || c.Ingress == nil
+}
+
+// NewIngress 指向 -jobmanager-service 的 webui 端口，没有配置 ingress 时返回 nil
+func (c *CreateFlinkV12ClusterRequest) NewIngress() *ApplyIngressRequest {
+	if c.Ingress == nil {
+		return nil
+	}
+	clusterName := *c.Name
+	namespace := "default"
+	if c.NameSpace != nil {
+		namespace = *c.NameSpace
+	}
+	host, path := c.Ingress.Render(namespace, clusterName)
+	req := &ApplyIngressRequest{
+		Name:        tea.String(fmt.Sprintf(JobManagerIngressName, clusterName)),
+		Namespace:   c.NameSpace,
+		Labels:      map[string]string{"app": clusterName},
+		Annotations: c.Ingress.Annotations,
+		ClassName:   c.Ingress.ClassName,
+		Host:        tea.String(host),
+		Path:        tea.String(path),
+		ServiceName: tea.String(fmt.Sprintf(JobManagerServiceName, clusterName)),
+		ServicePort: tea.Int32(8081),
+	}
+	if c.Owner != nil {
+		req.Labels["owner"] = *c.Owner
+	}
+	return req
+}
+
 func (c *CreateFlinkV12ClusterRequest) NewConfigMap() ApplyConfigMapRequest {
 	req := ApplyConfigMapRequest{
 		Namespace: c.NameSpace,
@@ -372,323 +366,73 @@ func (c *CreateFlinkV12ClusterRequest) NewConfigMap() ApplyConfigMapRequest {
 		"flink-conf.yaml":     ToString(defaultConfig),
 		"logback-console.xml": LogbackConsole,
 	}
-	return req
-}
-
-func ToString(value map[string]any) string {
-	b, err := yaml.Marshal(value)
-	if err != nil {
-		return ""
-	}
-	return string(b)
-}
-
-func (c *CreateFlinkV12ClusterRequest) NewJobManagerDeployment() map[string]any {
-	yaml := rerouceJobManagerDeployment
-	if yaml["metadata"].(map[string]any)["labels"] == nil {
-		yaml["metadata"].(map[string]any)["labels"] = map[string]string{}
-	}
-	yaml["metadata"].(map[string]any)["labels"].(map[string]string)["app"] = *c.Name
-	yaml["metadata"].(map[string]any)["name"] = fmt.Sprintf(JobManagerDeploymentName, *c.Name)
-
-	if c.Owner != nil {
-		yaml["metadata"].(map[string]any)["labels"].(map[string]string)["owner"] = *c.Owner
-	}
-
-	if c.NameSpace != nil {
-		yaml["metadata"].(map[string]any)["namespace"] = *c.NameSpace
-	}
-
-	// app
-	yaml["spec"].(map[string]any)["selector"].(map[string]any)["matchLabels"].(map[string]any)["app"] = *c.Name
-	yaml["spec"].(map[string]any)["template"].(map[string]any)["metadata"].(map[string]any)["labels"].(map[string]any)["app"] = *c.Name
-	// volumes 组装
-	yaml["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["volumes"] = []map[string]any{
-		{
-			"name": "flink-log",
-			"hostPath": map[string]any{
-				"path": fmt.Sprintf(hostLogPath, *c.Name),
-			},
-		},
-		{
-			"name": "flink-target-pvc",
-			"persistentVolumeClaim": map[string]any{
-				"claimName": fmt.Sprintf(PvcName, *c.Name),
-			},
-		},
-		{
-			"name": "flink-config",
-			"configMap": map[string]any{
-				"name": fmt.Sprintf(ConfigMapV12Name, *c.Name),
-				"items": []map[string]any{
-					{
-						"key":  "flink-conf.yaml",
-						"path": "flink-conf.yaml",
-					}, {
-						"key":  "logback-console.xml",
-						"path": "logback-console.xml",
-					},
-				},
-			},
-		},
-	}
-
-	jobContainer := map[string]any{
-		"name":  "jobmanager",
-		"image": FlinkVersion,
-		"args": []string{
-			"jobmanager",
-		},
-		"env": []map[string]string{},
-		"ports": []map[string]any{
-			{
-				"containerPort": 6123,
-				"name":          "rpc",
-			},
-			{
-				"containerPort": 8081,
-				"name":          "webui",
-			},
-			{
-				"containerPort": 6124,
-				"name":          "blob-service",
-			},
-		},
-		"livenessProbe": map[string]any{
-			"tcpSocket": map[string]any{
-				"port": 6123,
-			},
-			"initialDelaySeconds": 30,
-			"periodSeconds":       60,
-		},
-		"securityContext": map[string]any{
-			"runAsUser": 0,
-		},
-		"lifecycle": map[string]any{
-			"postStart": map[string]any{
-				"exec": map[string]any{
-					"command": []string{
-						"/bin/sh",
-						"-c",
-						"mkdir -p /opt/flink/target/flink-web-upload&&chown -R flink:flink /opt/flink/target/",
-					},
-				},
-			},
-		},
-		"volumeMounts": []map[string]any{
-			{
-				"mountPath": "/opt/flink/log",
-				"name":      "flink-log",
-			},
-			{
-				"mountPath": "/opt/flink/target",
-				"name":      "flink-target-pvc",
-			},
-			{
-				"mountPath": "/opt/flink/conf",
-				"name":      "flink-config",
-			},
-		},
-	}
-	if c.Image != nil {
-		jobContainer["image"] = *c.Image
-	}
-	if c.JobManager != nil && c.JobManager.Resource != nil {
-		// 解析 CPU 和内存资源
-		cpuLimit := resource.MustParse(*c.JobManager.Resource.CPU)
-		memLimit := resource.MustParse(*c.JobManager.Resource.Memory)
-
-		// 计算一半资源值
-		memRequest := resource.NewQuantity(memLimit.Value()/2, memLimit.Format)
-
-		jobContainer["resources"] = map[string]any{
-			"requests": v1.ResourceList(v1.ResourceList{
-				v1.ResourceCPU:    resource.MustParse("100m"),
-				v1.ResourceMemory: *memRequest,
-			}),
-			"limits": v1.ResourceList(v1.ResourceList{
-				v1.ResourceCPU:    cpuLimit,
-				v1.ResourceMemory: memLimit,
-			}),
+	if c.LogCollection != nil {
+		if c.LogCollection.LogbackConfig != nil {
+			req.Data["logback-console.xml"] = *c.LogCollection.LogbackConfig
 		}
-	}
-
-	if c.Env != nil {
-		for k, v := range c.Env {
-			jobContainer["env"] = append(jobContainer["env"].([]map[string]string), map[string]string{
-				"name":  k,
-				"value": v,
-			})
-		}
-	}
-
-	yaml["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"] = []map[string]any{jobContainer}
-
-	// nodeSelector 组装
-	if c.JobManager.NodeSelector != nil {
-		yaml["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["nodeSelector"] = c.JobManager.NodeSelector
-	}
-
-	// support sidecar
-	if c.JobManager != nil && len(c.JobManager.SideCars) > 0 {
-		for _, sideCar := range c.JobManager.SideCars {
-			_sideCar := map[string]any{
-				"name":    sideCar.Name,
-				"image":   sideCar.Image,
-				"command": sideCar.Command,
-			}
-			if sideCar.Env != nil {
-				_sideCar["env"] = sideCar.Env
-			}
-
-			if sideCar.VolumeMounts != nil && len(sideCar.VolumeMounts) > 0 {
-				_sideCar["volumeMounts"] = sideCar.VolumeMounts
-			}
-
-			if sideCar.LivenessProbe != nil {
-				_sideCar["livenessProbe"] = sideCar.LivenessProbe
-			}
-
-			yaml["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"] = append(
-				yaml["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"].([]map[string]any),
-				_sideCar,
-			)
+		if c.LogCollection.Log4jConfig != nil {
+			req.Data["log4j-console.properties"] = *c.LogCollection.Log4jConfig
 		}
 	}
-
-	return yaml
+	return req
 }
 
-func (c *CreateFlinkV12ClusterRequest) NewTaskManagerDeployment() map[string]any {
-	yaml := resourceTaskManagerDeployment
-
-	if yaml["metadata"].(map[string]any)["labels"] == nil {
-		yaml["metadata"].(map[string]any)["labels"] = map[string]string{}
-	}
-	yaml["metadata"].(map[string]any)["labels"].(map[string]string)["app"] = *c.Name
-	yaml["metadata"].(map[string]any)["name"] = fmt.Sprintf(TaskManagerDeploymentName, *c.Name)
-
+// NewLogConfigMap 日志 sidecar 的配置，没有配置时返回 nil
+func (c *CreateFlinkV12ClusterRequest) NewLogConfigMap() *ApplyConfigMapRequest {
+	var labels map[string]string
 	if c.Owner != nil {
-		yaml["metadata"].(map[string]any)["labels"].(map[string]string)["owner"] = *c.Owner
+		labels = map[string]string{"owner": *c.Owner}
 	}
+	return c.LogCollection.NewConfigMap(c.NameSpace, *c.Name, labels)
+}
 
-	if c.NameSpace != nil {
-		yaml["metadata"].(map[string]any)["namespace"] = *c.NameSpace
+func (c *CreateFlinkV12ClusterRequest) logCollection() *FlinkLogCollection {
+	if c.LogCollection != nil {
+		return c.LogCollection
 	}
-	yaml["spec"].(map[string]any)["replicas"] = c.TaskManager.Nu
-	// containers 组装
-	taskManagerContainer := map[string]any{
-		"name":  "taskmanager",
-		"image": FlinkVersion,
-		"args": []string{
-			"taskmanager",
-		},
-		"ports": []map[string]any{
-			{
-				"containerPort": 6122,
-				"name":          "rpc",
-			},
-			{
-				"containerPort": 6125,
-				"name":          "query-state",
-			},
-		},
-		"livenessProbe": map[string]any{
-			"tcpSocket": map[string]any{
-				"port": 6122,
-			},
-			"initialDelaySeconds": 30,
-			"periodSeconds":       60,
-		},
-		"env": []map[string]string{},
-		"securityContext": map[string]any{
-			"runAsUser": 0,
-		},
-		"lifecycle": map[string]any{
-			"postStart": map[string]any{
-				"exec": map[string]any{
-					"command": []string{
-						"sh",
-						"-c",
-						"chown 9999:9999 /opt/flink/log",
-					},
-				},
-			},
-		},
+	return &FlinkLogCollection{VolumeType: LogVolumeHostPath}
+}
 
-		"volumeMounts": []map[string]any{
-			{
-				"mountPath": "/opt/flink/log",
-				"name":      "flink-log",
-			},
-			{
-				"mountPath": "/opt/flink/conf",
-				"name":      "flink-config",
-			},
+// podVolumes JM/TM 公共的日志卷和配置卷
+func (c *CreateFlinkV12ClusterRequest) podVolumes() []map[string]any {
+	logCollection := c.logCollection()
+	configItems := []map[string]any{
+		{
+			"key":  "flink-conf.yaml",
+			"path": "flink-conf.yaml",
+		}, {
+			"key":  "logback-console.xml",
+			"path": "logback-console.xml",
 		},
 	}
-	if c.Image != nil {
-		taskManagerContainer["image"] = *c.Image
-	}
-	if c.Env != nil {
-		for k, v := range c.Env {
-			taskManagerContainer["env"] = append(taskManagerContainer["env"].([]map[string]string), map[string]string{
-				"name":  k,
-				"value": v,
-			})
-		}
-	}
-	if c.TaskManager != nil && c.TaskManager.Resource != nil {
-		// 解析内存资源
-		memLimit := resource.MustParse(*c.TaskManager.Resource.Memory)
-
-		// 计算内存请求为一半
-		memRequest := resource.NewQuantity(memLimit.Value()/2, memLimit.Format)
-
-		taskManagerContainer["resources"] = map[string]any{
-			"requests": v1.ResourceList(v1.ResourceList{
-				v1.ResourceCPU:    resource.MustParse("100m"), // 默认少点，避免资源浪费
-				v1.ResourceMemory: *memRequest,
-			}),
-			"limits": v1.ResourceList(v1.ResourceList{
-				v1.ResourceCPU:    resource.MustParse(*c.TaskManager.Resource.CPU),
-				v1.ResourceMemory: memLimit,
-			}),
-		}
+	if logCollection.Log4jConfig != nil {
+		configItems = append(configItems, map[string]any{
+			"key":  "log4j-console.properties",
+			"path": "log4j-console.properties",
+		})
 	}
-
-	yaml["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"] = []map[string]any{taskManagerContainer}
-
-	// volume 组装
-	yaml["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["volumes"] = []map[string]any{
-		{
-			"name": "flink-log",
-			"hostPath": map[string]any{
-				"path": fmt.Sprintf(hostLogPath, *c.Name),
-			},
-		},
+	volumes := []map[string]any{
+		logCollection.logVolume("flink-log", fmt.Sprintf(hostLogPath, *c.Name)),
 		{
 			"name": "flink-config",
 			"configMap": map[string]any{
-				"name": fmt.Sprintf(ConfigMapV12Name, *c.Name),
-				"items": []map[string]any{
-					{
-						"key":  "flink-conf.yaml",
-						"path": "flink-conf.yaml",
-					}, {
-						"key":  "logback-console.xml",
-						"path": "logback-console.xml",
-					},
-				},
+				"name":  fmt.Sprintf(ConfigMapV12Name, *c.Name),
+				"items": configItems,
 			},
 		},
 	}
+	if configVolume := logCollection.configVolume(*c.Name); configVolume != nil {
+		volumes = append(volumes, configVolume)
+	}
+	return volumes
+}
 
-	// nodeSelector 组装
-	if c.TaskManager.NodeSelector != nil {
-		yaml["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["nodeSelector"] = c.TaskManager.NodeSelector
+func ToString(value map[string]any) string {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return ""
 	}
-	return yaml
+	return string(b)
 }
 
 // flink在 configmap中的配置为\n换行 : 间隔的 kv
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	v1 "k8s.io/api/core/v1"
+)
+
+// 每次修改 flink-conf.yaml 之前把当前配置保存为 <name>-configmap-rev-<n>，用于查询历史、对比和回滚
+const (
+	ConfigRevisionName      = "%s-configmap-rev-%d"
+	ConfigRevisionOfLabel   = "multi-k8s-client/config-revision-of" // 所属集群名称
+	ConfigRevisionLabel     = "multi-k8s-client/config-revision"    // 版本号
+	ConfigRevisionHashLabel = "multi-k8s-client/config-hash"        // 配置摘要，和 pod 模板的注解一致
+	MaxConfigRevisions      = 10                                    // 超过的旧版本会被删除
+	CurrentConfigRevision   = 0                                     // diff 时表示当前使用的配置
+)
+
+type FlinkV12Config struct {
+	Name               string            `json:"name"` // configmap 名称
+	Hash               string            `json:"hash"`
+	FlinkConfiguration map[string]any    `json:"flink_configuration"` // 解析后的 flink-conf.yaml
+	Logback            string            `json:"logback"`
+	Log4j              string            `json:"log4j,omitempty"`
+	Data               map[string]string `json:"-"` // 原始数据，回滚时使用
+}
+
+func NewFlinkV12Config(configMap v1.ConfigMap) (FlinkV12Config, error) {
+	flinkConfiguration, err := ConvertYamlToMap(configMap.Data["flink-conf.yaml"])
+	if err != nil {
+		return FlinkV12Config{}, fmt.Errorf("parse %s flink-conf.yaml error: %v", configMap.Name, err)
+	}
+	return FlinkV12Config{
+		Name:               configMap.Name,
+		Hash:               ConfigHash(configMap.Data),
+		FlinkConfiguration: flinkConfiguration,
+		Logback:            configMap.Data["logback-console.xml"],
+		Log4j:              configMap.Data["log4j-console.properties"],
+		Data:               configMap.Data,
+	}, nil
+}
+
+type FlinkV12ConfigRevision struct {
+	Revision   int            `json:"revision"`
+	CreateTime string         `json:"create_time"` // 保存的时间，也就是这个版本被替换的时间
+	Config     FlinkV12Config `json:"config"`
+}
+
+func NewFlinkV12ConfigRevision(configMap v1.ConfigMap) (FlinkV12ConfigRevision, error) {
+	var revision int
+	if _, err := fmt.Sscanf(configMap.Labels[ConfigRevisionLabel], "%d", &revision); err != nil {
+		return FlinkV12ConfigRevision{}, fmt.Errorf("configmap %s has invalid revision label: %v", configMap.Name, err)
+	}
+	config, err := NewFlinkV12Config(configMap)
+	if err != nil {
+		return FlinkV12ConfigRevision{}, err
+	}
+	return FlinkV12ConfigRevision{
+		Revision:   revision,
+		CreateTime: configMap.CreationTimestamp.Format(time.RFC3339),
+		Config:     config,
+	}, nil
+}
+
+// SortConfigRevisions 按版本号倒序
+func SortConfigRevisions(revisions []FlinkV12ConfigRevision) {
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+}
+
+// NewConfigRevision 保存当前配置为新的版本
+func NewConfigRevision(namespace, clusterName string, revision int, data map[string]string) ApplyConfigMapRequest {
+	return ApplyConfigMapRequest{
+		Namespace: tea.String(namespace),
+		Name:      tea.String(fmt.Sprintf(ConfigRevisionName, clusterName, revision)),
+		Labels: map[string]string{
+			"app":                   clusterName,
+			ConfigRevisionOfLabel:   clusterName,
+			ConfigRevisionLabel:     fmt.Sprint(revision),
+			ConfigRevisionHashLabel: ConfigHash(data),
+		},
+		Data: data,
+	}
+}
+
+type FlinkV12ConfigDiffType string
+
+const (
+	ConfigAdded   FlinkV12ConfigDiffType = "added"
+	ConfigRemoved FlinkV12ConfigDiffType = "removed"
+	ConfigChanged FlinkV12ConfigDiffType = "changed"
+)
+
+type FlinkV12ConfigChange struct {
+	Key  string                 `json:"key"`
+	Type FlinkV12ConfigDiffType `json:"type"`
+	From any                    `json:"from,omitempty"`
+	To   any                    `json:"to,omitempty"`
+}
+
+type FlinkV12ConfigDiff struct {
+	From           int                    `json:"from"` // 0 表示当前配置
+	To             int                    `json:"to"`
+	Changes        []FlinkV12ConfigChange `json:"changes"` // flink-conf.yaml 的变化，按 key 排序
+	LogbackChanged bool                   `json:"logback_changed"`
+	Log4jChanged   bool                   `json:"log4j_changed"`
+}
+
+func DiffFlinkV12Config(from, to FlinkV12Config) FlinkV12ConfigDiff {
+	diff := FlinkV12ConfigDiff{
+		Changes:        []FlinkV12ConfigChange{},
+		LogbackChanged: from.Logback != to.Logback,
+		Log4jChanged:   from.Log4j != to.Log4j,
+	}
+	keys := map[string]bool{}
+	for k := range from.FlinkConfiguration {
+		keys[k] = true
+	}
+	for k := range to.FlinkConfiguration {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		a, inFrom := from.FlinkConfiguration[k]
+		b, inTo := to.FlinkConfiguration[k]
+		switch {
+		case !inFrom:
+			diff.Changes = append(diff.Changes, FlinkV12ConfigChange{Key: k, Type: ConfigAdded, To: b})
+		case !inTo:
+			diff.Changes = append(diff.Changes, FlinkV12ConfigChange{Key: k, Type: ConfigRemoved, From: a})
+		case fmt.Sprint(a) != fmt.Sprint(b):
+			diff.Changes = append(diff.Changes, FlinkV12ConfigChange{Key: k, Type: ConfigChanged, From: a, To: b})
+		}
+	}
+	return diff
+}
@@ -0,0 +1,505 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alibabacloud-go/tea/tea"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// v1.12 的 JM/TM deployment，每次调用都构造新的对象，不共享可变的模板，可以并发创建集群
+
+const (
+	ConfigHashAnnotation = "multi-k8s-client/config-hash" // pod 模板上的配置摘要，配置变化时修改触发滚动重启
+	SideCarsAnnotation   = "multi-k8s-client/side-cars"   // JM 上用户 sidecar 的名称，更新时替换这些容器
+)
+
+// ConfigHash configmap 数据的摘要
+func ConfigHash(data map[string]string) string {
+	h := sha256.New()
+	for _, k := range sortedKeys(data) {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(data[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func (c *CreateFlinkV12ClusterRequest) NewJobManagerDeployment() (*appv1.Deployment, error) {
+	deployment := c.newDeployment(fmt.Sprintf(JobManagerDeploymentName, *c.Name), "jobmanager")
+	deployment.Spec.Replicas = tea.Int32(1)
+	deployment.Spec.Strategy = appv1.DeploymentStrategy{Type: appv1.RecreateDeploymentStrategyType}
+
+	volumes, err := c.newPodVolumes()
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.Volumes = append(volumes, v1.Volume{
+		Name: "flink-target-pvc",
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: fmt.Sprintf(PvcName, *c.Name)},
+		},
+	})
+
+	jobContainer := v1.Container{
+		Name:  "jobmanager",
+		Image: c.image(),
+		Args:  []string{"jobmanager"},
+		Env:   c.containerEnv(),
+		Ports: []v1.ContainerPort{
+			{ContainerPort: 6123, Name: "rpc"},
+			{ContainerPort: 8081, Name: "webui"},
+			{ContainerPort: 6124, Name: "blob-service"},
+		},
+		LivenessProbe:   tcpLivenessProbe(6123),
+		SecurityContext: &v1.SecurityContext{RunAsUser: tea.Int64(0)},
+		Lifecycle: &v1.Lifecycle{
+			PostStart: &v1.LifecycleHandler{
+				Exec: &v1.ExecAction{Command: []string{
+					"/bin/sh",
+					"-c",
+					"mkdir -p /opt/flink/target/flink-web-upload&&chown -R flink:flink /opt/flink/target/",
+				}},
+			},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{MountPath: "/opt/flink/log", Name: "flink-log"},
+			{MountPath: "/opt/flink/target", Name: "flink-target-pvc"},
+			{MountPath: "/opt/flink/conf", Name: "flink-config"},
+		},
+	}
+	if c.JobManager != nil && c.JobManager.Resource != nil {
+		jobContainer.Resources, err = newV12Resources(c.JobManager.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("jobManager.resource: %v", err)
+		}
+	}
+	podSpec.Containers = []v1.Container{jobContainer}
+	if err := c.appendLogSideCar(podSpec); err != nil {
+		return nil, err
+	}
+
+	if c.JobManager != nil {
+		if c.JobManager.NodeSelector != nil {
+			podSpec.NodeSelector = copyStringMap(*c.JobManager.NodeSelector)
+		}
+		// support sidecar
+		var names []string
+		for _, sideCar := range c.JobManager.SideCars {
+			container, err := sideCar.ToContainer()
+			if err != nil {
+				return nil, err
+			}
+			podSpec.Containers = append(podSpec.Containers, container)
+			names = append(names, container.Name)
+		}
+		if len(names) > 0 {
+			deployment.Annotations = map[string]string{SideCarsAnnotation: strings.Join(names, ",")}
+		}
+	}
+	return deployment, nil
+}
+
+func (c *CreateFlinkV12ClusterRequest) NewTaskManagerDeployment() (*appv1.Deployment, error) {
+	deployment := c.newDeployment(fmt.Sprintf(TaskManagerDeploymentName, *c.Name), "taskmanager")
+	if c.TaskManager != nil && c.TaskManager.Nu != nil {
+		deployment.Spec.Replicas = tea.Int32(int32(*c.TaskManager.Nu))
+	}
+
+	volumes, err := c.newPodVolumes()
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.Volumes = volumes
+
+	taskManagerContainer := v1.Container{
+		Name:  "taskmanager",
+		Image: c.image(),
+		Args:  []string{"taskmanager"},
+		Env:   c.containerEnv(),
+		Ports: []v1.ContainerPort{
+			{ContainerPort: 6122, Name: "rpc"},
+			{ContainerPort: 6125, Name: "query-state"},
+		},
+		LivenessProbe:   tcpLivenessProbe(6122),
+		SecurityContext: &v1.SecurityContext{RunAsUser: tea.Int64(0)},
+		Lifecycle: &v1.Lifecycle{
+			PostStart: &v1.LifecycleHandler{
+				Exec: &v1.ExecAction{Command: []string{"sh", "-c", "chown 9999:9999 /opt/flink/log"}},
+			},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{MountPath: "/opt/flink/log", Name: "flink-log"},
+			{MountPath: "/opt/flink/conf", Name: "flink-config"},
+		},
+	}
+	if c.TaskManager != nil && c.TaskManager.Resource != nil {
+		taskManagerContainer.Resources, err = newV12Resources(c.TaskManager.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("taskManager.resource: %v", err)
+		}
+	}
+	podSpec.Containers = []v1.Container{taskManagerContainer}
+	if err := c.appendLogSideCar(podSpec); err != nil {
+		return nil, err
+	}
+
+	if c.TaskManager != nil && c.TaskManager.NodeSelector != nil {
+		podSpec.NodeSelector = copyStringMap(*c.TaskManager.NodeSelector)
+	}
+	return deployment, nil
+}
+
+// newDeployment JM/TM 公共的 metadata、selector 和 pod 模板
+func (c *CreateFlinkV12ClusterRequest) newDeployment(name, component string) *appv1.Deployment {
+	namespace := v1.NamespaceDefault
+	if c.NameSpace != nil {
+		namespace = *c.NameSpace
+	}
+	labels := map[string]string{"app": *c.Name}
+	if c.Owner != nil {
+		labels["owner"] = *c.Owner
+	}
+	return &appv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: appv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": *c.Name, "component": component},
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{"app": *c.Name, "component": component},
+					Annotations: map[string]string{ConfigHashAnnotation: ConfigHash(c.NewConfigMap().Data)},
+				},
+				Spec: v1.PodSpec{RestartPolicy: v1.RestartPolicyAlways},
+			},
+		},
+	}
+}
+
+func (c *CreateFlinkV12ClusterRequest) image() string {
+	if c.Image != nil {
+		return *c.Image
+	}
+	return FlinkVersion
+}
+
+// containerEnv 按 key 排序，保证每次生成的 deployment 一致
+func (c *CreateFlinkV12ClusterRequest) containerEnv() []v1.EnvVar {
+	keys := sortedKeys(c.Env)
+	env := make([]v1.EnvVar, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, v1.EnvVar{Name: k, Value: c.Env[k]})
+	}
+	return env
+}
+
+func (c *CreateFlinkV12ClusterRequest) newPodVolumes() ([]v1.Volume, error) {
+	var volumes []v1.Volume
+	if err := convertMap(c.podVolumes(), &volumes); err != nil {
+		return nil, fmt.Errorf("volumes: %v", err)
+	}
+	return volumes, nil
+}
+
+func (c *CreateFlinkV12ClusterRequest) appendLogSideCar(podSpec *v1.PodSpec) error {
+	sideCar := c.logCollection().sideCarContainer("flink-log")
+	if sideCar == nil {
+		return nil
+	}
+	var container v1.Container
+	if err := convertMap(sideCar, &container); err != nil {
+		return fmt.Errorf("log_collection.side_car: %v", err)
+	}
+	podSpec.Containers = append(podSpec.Containers, container)
+	return nil
+}
+
+// newV12Resources request 的 CPU 固定 100m 避免资源浪费，内存为 limit 的一半
+func newV12Resources(r *FlinkResource) (v1.ResourceRequirements, error) {
+	cpuLimit, err := resource.ParseQuantity(tea.StringValue(r.CPU))
+	if err != nil {
+		return v1.ResourceRequirements{}, fmt.Errorf("cpu %q: %v", tea.StringValue(r.CPU), err)
+	}
+	memLimit, err := resource.ParseQuantity(tea.StringValue(r.Memory))
+	if err != nil {
+		return v1.ResourceRequirements{}, fmt.Errorf("memory %q: %v", tea.StringValue(r.Memory), err)
+	}
+	memRequest := resource.NewQuantity(memLimit.Value()/2, memLimit.Format)
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("100m"),
+			v1.ResourceMemory: *memRequest,
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    cpuLimit,
+			v1.ResourceMemory: memLimit,
+		},
+	}, nil
+}
+
+func tcpLivenessProbe(port int) *v1.Probe {
+	return &v1.Probe{
+		ProbeHandler: v1.ProbeHandler{
+			TCPSocket: &v1.TCPSocketAction{Port: intstr.FromInt(port)},
+		},
+		InitialDelaySeconds: 30,
+		PeriodSeconds:       60,
+	}
+}
+
+func (s SideCar) ToContainer() (v1.Container, error) {
+	container := v1.Container{
+		Name:          tea.StringValue(s.Name),
+		Image:         tea.StringValue(s.Image),
+		Command:       append([]string(nil), s.Command...),
+		LivenessProbe: s.LivenessProbe.ToProbe(),
+	}
+	for _, env := range s.Env {
+		container.Env = append(container.Env, v1.EnvVar{Name: tea.StringValue(env.Name), Value: tea.StringValue(env.Value)})
+	}
+	if len(s.VolumeMounts) > 0 {
+		if err := convertMap(s.VolumeMounts, &container.VolumeMounts); err != nil {
+			return v1.Container{}, fmt.Errorf("side_car %s volume_mounts: %v", container.Name, err)
+		}
+	}
+	return container, nil
+}
+
+// convertMap 把 k8s 格式的 map 转换为对应的结构体
+func convertMap(in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func copyStringMap(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func (req *ApplyFlinkV12ClusterRequest) Validate(clusterName string) error {
+	if app, ok := req.Labels["app"]; ok && app != clusterName {
+		return fmt.Errorf("app label is not supported update")
+	}
+	if req.JobManager != nil {
+		if req.JobManager.PvcSize != nil {
+			return fmt.Errorf("jobManager.pvc_size is not supported update")
+		}
+		if req.JobManager.Resource != nil {
+			if _, err := newV12Resources(req.JobManager.Resource); err != nil {
+				return fmt.Errorf("jobManager.resource: %v", err)
+			}
+		}
+	}
+	if req.TaskManager != nil {
+		if req.TaskManager.Nu != nil && *req.TaskManager.Nu < 0 {
+			return fmt.Errorf("taskManager.nu must not be negative")
+		}
+		if req.TaskManager.Resource != nil {
+			if _, err := newV12Resources(req.TaskManager.Resource); err != nil {
+				return fmt.Errorf("taskManager.resource: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewConfigData 替换 flink-conf.yaml，保留 logback 等其他配置
+func (req *ApplyFlinkV12ClusterRequest) NewConfigData(old map[string]string) map[string]string {
+	data := copyStringMap(old)
+	if req.FlinkConfiguration != nil {
+		data["flink-conf.yaml"] = ToString(req.FlinkConfiguration)
+	}
+	if _, ok := data["logback-console.xml"]; !ok {
+		data["logback-console.xml"] = LogbackConsole
+	}
+	return data
+}
+
+// ApplyToDeployment 修改查询到的 JM/TM deployment，component 为 jobmanager 或 taskmanager，
+// configHash 不为空时更新 pod 模板的配置摘要；restart 表示 pod 模板有变化
+func (req *ApplyFlinkV12ClusterRequest) ApplyToDeployment(dep *appv1.Deployment, component, configHash string) (changes []FlinkV12Change, restart bool, err error) {
+	record := func(field, from, to string, template bool) {
+		if from == to {
+			return
+		}
+		changes = append(changes, FlinkV12Change{Resource: dep.Name, Field: field, From: from, To: to})
+		restart = restart || template
+	}
+
+	for _, k := range sortedKeys(req.Labels) {
+		if dep.Labels == nil {
+			dep.Labels = map[string]string{}
+		}
+		record("labels."+k, dep.Labels[k], req.Labels[k], false)
+		dep.Labels[k] = req.Labels[k]
+	}
+
+	podSpec := &dep.Spec.Template.Spec
+	index := -1
+	for i, container := range podSpec.Containers {
+		if container.Name == component {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, false, fmt.Errorf("container %s not found in deployment %s", component, dep.Name)
+	}
+	main := &podSpec.Containers[index]
+	if req.Image != nil {
+		record("image", main.Image, *req.Image, true)
+		main.Image = *req.Image
+	}
+	if req.Env != nil {
+		env := (&CreateFlinkV12ClusterRequest{Env: req.Env}).containerEnv()
+		record("env", envString(main.Env), envString(env), true)
+		main.Env = env
+	}
+
+	var resource *FlinkResource
+	var nodeSelector *map[string]string
+	switch component {
+	case "jobmanager":
+		if req.JobManager != nil {
+			resource, nodeSelector = req.JobManager.Resource, req.JobManager.NodeSelector
+			if req.JobManager.SideCars != nil {
+				if err := req.applySideCars(dep, index, record); err != nil {
+					return nil, false, err
+				}
+				// 容器列表重新生成了，重新定位主容器
+				for i := range podSpec.Containers {
+					if podSpec.Containers[i].Name == component {
+						main = &podSpec.Containers[i]
+					}
+				}
+			}
+		}
+	case "taskmanager":
+		if req.TaskManager != nil {
+			resource, nodeSelector = req.TaskManager.Resource, req.TaskManager.NodeSelector
+			if req.TaskManager.Nu != nil {
+				replicas := int32(*req.TaskManager.Nu)
+				record("replicas", fmt.Sprint(tea.Int32Value(dep.Spec.Replicas)), fmt.Sprint(replicas), false)
+				dep.Spec.Replicas = tea.Int32(replicas)
+			}
+		}
+	}
+	if resource != nil {
+		resources, err := newV12Resources(resource)
+		if err != nil {
+			return nil, false, err
+		}
+		record("resources", resourcesString(main.Resources), resourcesString(resources), true)
+		main.Resources = resources
+	}
+	if nodeSelector != nil {
+		record("nodeSelector", fmt.Sprint(podSpec.NodeSelector), fmt.Sprint(*nodeSelector), true)
+		podSpec.NodeSelector = copyStringMap(*nodeSelector)
+	}
+
+	if configHash != "" {
+		if dep.Spec.Template.Annotations == nil {
+			dep.Spec.Template.Annotations = map[string]string{}
+		}
+		record("annotations."+ConfigHashAnnotation, dep.Spec.Template.Annotations[ConfigHashAnnotation], configHash, true)
+		dep.Spec.Template.Annotations[ConfigHashAnnotation] = configHash
+	}
+	return changes, restart, nil
+}
+
+// applySideCars 替换 SideCarsAnnotation 记录的和同名的容器，之前没有记录的集群只按名称替换
+func (req *ApplyFlinkV12ClusterRequest) applySideCars(dep *appv1.Deployment, mainIndex int, record func(field, from, to string, template bool)) error {
+	managed := map[string]bool{}
+	var oldNames []string
+	if names := dep.Annotations[SideCarsAnnotation]; names != "" {
+		oldNames = strings.Split(names, ",")
+	}
+	for _, name := range oldNames {
+		managed[name] = true
+	}
+	var sideCars []v1.Container
+	var newNames []string
+	for _, sideCar := range req.JobManager.SideCars {
+		container, err := sideCar.ToContainer()
+		if err != nil {
+			return err
+		}
+		if container.Name == dep.Spec.Template.Spec.Containers[mainIndex].Name {
+			return fmt.Errorf("side_car name %s conflicts with main container", container.Name)
+		}
+		managed[container.Name] = true
+		sideCars = append(sideCars, container)
+		newNames = append(newNames, container.Name)
+	}
+
+	var containers []v1.Container
+	var removed []v1.Container
+	for _, container := range dep.Spec.Template.Spec.Containers {
+		if managed[container.Name] {
+			removed = append(removed, container)
+			continue
+		}
+		containers = append(containers, container)
+	}
+	from, _ := json.Marshal(removed)
+	to, _ := json.Marshal(sideCars)
+	if string(from) == string(to) {
+		return nil
+	}
+	record("sideCars", strings.Join(oldNames, ","), strings.Join(newNames, ","), true)
+	dep.Spec.Template.Spec.Containers = append(containers, sideCars...)
+	if dep.Annotations == nil {
+		dep.Annotations = map[string]string{}
+	}
+	if len(newNames) > 0 {
+		dep.Annotations[SideCarsAnnotation] = strings.Join(newNames, ",")
+	} else {
+		delete(dep.Annotations, SideCarsAnnotation)
+	}
+	return nil
+}
+
+func envString(env []v1.EnvVar) string {
+	var items []string
+	for _, e := range env {
+		items = append(items, e.Name+"="+e.Value)
+	}
+	return strings.Join(items, ",")
+}
+
+func resourcesString(r v1.ResourceRequirements) string {
+	return fmt.Sprintf("limits: cpu=%s memory=%s, requests: cpu=%s memory=%s",
+		r.Limits.Cpu(), r.Limits.Memory(), r.Requests.Cpu(), r.Requests.Memory())
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
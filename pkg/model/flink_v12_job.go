@@ -0,0 +1,189 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"path"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// v1.12 集群没有 FlinkSessionJob CRD，通过 JM rest 接口管理 jar 和 job
+
+type FlinkV12JarUploadRequest struct {
+	NameSpace   *string `json:"namespace" default:"default"`
+	ClusterName *string `json:"cluster_name" binding:"required"`
+	FileName    *string `json:"file_name" binding:"required"` // 必须以 .jar 结尾
+	Jar         []byte  `json:"jar" binding:"required"`
+}
+
+func (r *FlinkV12JarUploadRequest) Validate() error {
+	if r.ClusterName == nil || *r.ClusterName == "" {
+		return fmt.Errorf("cluster_name is required")
+	}
+	if r.FileName == nil || path.Ext(*r.FileName) != ".jar" {
+		return fmt.Errorf("file_name must end with .jar")
+	}
+	if len(r.Jar) == 0 {
+		return fmt.Errorf("jar is empty")
+	}
+	return nil
+}
+
+// NewMultipartBody 渲染 POST /jars/upload 的请求体，返回 body 和 Content-Type
+func (r *FlinkV12JarUploadRequest) NewMultipartBody() ([]byte, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("jarfile", path.Base(tea.StringValue(r.FileName)))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(r.Jar); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+// POST /jars/upload
+type FlinkJarUploadResponse struct {
+	FileName string `json:"filename"` // JM 本地路径 /tmp/flink-web-xxx/flink-web-upload/<jarid>
+	Status   string `json:"status"`
+}
+
+// JarID 后续 run 使用的 jar id，即上传路径的文件名
+func (r *FlinkJarUploadResponse) JarID() string {
+	return path.Base(r.FileName)
+}
+
+// GET /jars
+type FlinkJarList struct {
+	Address string     `json:"address"`
+	Files   []FlinkJar `json:"files"`
+}
+
+type FlinkJar struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Uploaded int64           `json:"uploaded"`
+	Entry    []FlinkJarEntry `json:"entry"`
+}
+
+type FlinkJarEntry struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type FlinkV12JarRunRequest struct {
+	NameSpace             *string  `json:"namespace" default:"default"`
+	ClusterName           *string  `json:"cluster_name" binding:"required"`
+	JarID                 *string  `json:"jar_id" binding:"required"` // 上传返回的 jar id
+	EntryClass            *string  `json:"entry_class"`               // 主类，jar 的 manifest 没有指定时必填
+	Args                  []string `json:"args"`                      // 启动参数 --arg1=value1
+	Parallelism           *int32   `json:"parallelism"`
+	SavepointPath         *string  `json:"savepoint_path"` // 从 savepoint 恢复
+	AllowNonRestoredState *bool    `json:"allow_non_restored_state"`
+}
+
+func (r *FlinkV12JarRunRequest) Validate() error {
+	if r.ClusterName == nil || *r.ClusterName == "" {
+		return fmt.Errorf("cluster_name is required")
+	}
+	if r.JarID == nil || *r.JarID == "" {
+		return fmt.Errorf("jar_id is required")
+	}
+	if r.Parallelism != nil && *r.Parallelism < 1 {
+		return fmt.Errorf("parallelism must be greater than 0")
+	}
+	return nil
+}
+
+// ToBody POST /jars/:jarid/run 的请求体
+func (r *FlinkV12JarRunRequest) ToBody() map[string]any {
+	body := map[string]any{}
+	if r.EntryClass != nil {
+		body["entryClass"] = *r.EntryClass
+	}
+	if len(r.Args) > 0 {
+		body["programArgsList"] = r.Args
+	}
+	if r.Parallelism != nil {
+		body["parallelism"] = *r.Parallelism
+	}
+	if r.SavepointPath != nil {
+		body["savepointPath"] = *r.SavepointPath
+	}
+	if r.AllowNonRestoredState != nil {
+		body["allowNonRestoredState"] = *r.AllowNonRestoredState
+	}
+	return body
+}
+
+// POST /jars/:jarid/run
+type FlinkJarRunResponse struct {
+	JobID string `json:"jobid"`
+}
+
+type FlinkV12JobStopRequest struct {
+	NameSpace       *string `json:"namespace" default:"default"`
+	ClusterName     *string `json:"cluster_name" binding:"required"`
+	JobID           *string `json:"job_id" binding:"required"`
+	TargetDirectory *string `json:"target_directory"` // 为空时使用集群的 state.savepoints.dir
+	Drain           *bool   `json:"drain"`            // 发送 MAX_WATERMARK 后再停止
+	Timeout         *string `json:"timeout"`          // 等待 savepoint 完成的时间，比如 10m，默认 5 分钟
+}
+
+func (r *FlinkV12JobStopRequest) Validate() error {
+	if r.ClusterName == nil || *r.ClusterName == "" {
+		return fmt.Errorf("cluster_name is required")
+	}
+	if r.JobID == nil || *r.JobID == "" {
+		return fmt.Errorf("job_id is required")
+	}
+	if _, err := parseTimeout("timeout", r.Timeout, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetTimeout 请先调用 Validate 校验
+func (r *FlinkV12JobStopRequest) GetTimeout() time.Duration {
+	timeout, _ := parseTimeout("timeout", r.Timeout, 5*time.Minute)
+	return timeout
+}
+
+// ToBody POST /jobs/:jobid/stop 的请求体
+func (r *FlinkV12JobStopRequest) ToBody() map[string]any {
+	body := map[string]any{
+		"drain": tea.BoolValue(r.Drain),
+	}
+	if r.TargetDirectory != nil {
+		body["targetDirectory"] = *r.TargetDirectory
+	}
+	return body
+}
+
+// POST /jobs/:jobid/stop
+type FlinkTriggerResponse struct {
+	RequestID string `json:"request-id"`
+}
+
+// GET /jobs/:jobid/savepoints/:triggerid
+type FlinkSavepointStatus struct {
+	Status struct {
+		ID string `json:"id"` // IN_PROGRESS, COMPLETED
+	} `json:"status"`
+	Operation *struct {
+		Location     string         `json:"location"`
+		FailureCause map[string]any `json:"failure-cause"`
+	} `json:"operation"`
+}
+
+type FlinkV12JobStopResponse struct {
+	JobID         string `json:"job_id"`
+	SavepointPath string `json:"savepoint_path"`
+}
@@ -7,7 +7,11 @@ import (
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/stretchr/testify/assert"
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 var req = model.CreateFlinkV12ClusterRequest{
@@ -45,7 +49,7 @@ func TestNewService(t *testing.T) {
 
 // NewLBService
 func TestNewLBService(t *testing.T) {
-	service := req.NewLBService()
+	service := req.NewLBService(31000)
 	fmt.Println(tea.Prettify(service))
 }
 
@@ -72,8 +76,7 @@ func TestNewJobManagerDeployment(t *testing.T) {
 			},
 		},
 	}
-	deployment := req.NewJobManagerDeployment()
-	createDeploymentRequest, err := model.NewDeploymentCreateFromMap(deployment)
+	createDeploymentRequest, err := req.NewJobManagerDeployment()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -82,8 +85,7 @@ func TestNewJobManagerDeployment(t *testing.T) {
 
 // NewTaskManagerDeployment
 func TestNewTaskManagerDeployment(t *testing.T) {
-	deployment := req.NewTaskManagerDeployment()
-	createDeploymentRequest, err := model.NewDeploymentCreateFromMap(deployment)
+	createDeploymentRequest, err := req.NewTaskManagerDeployment()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -168,3 +170,279 @@ func TestLargeResourceCalculation(t *testing.T) {
 	assert.Equal(t, expectedMemReqGi.Value(), memLimitGi.Value()/2,
 		"Memory request should be half of limit for Gi values")
 }
+
+func TestNewV12LogCollection(t *testing.T) {
+	logReq := req
+	logReq.LogCollection = &model.FlinkLogCollection{
+		VolumeType:    model.LogVolumeEmptyDir,
+		LogbackConfig: tea.String("<configuration/>"),
+		SideCar: &model.LogSideCar{
+			Image:  tea.String("fluent/fluent-bit:2.2.0"),
+			Config: map[string]string{"fluent-bit.conf": "[INPUT]"},
+		},
+	}
+	configMap := logReq.NewConfigMap()
+	assert.Equal(t, "<configuration/>", configMap.Data["logback-console.xml"])
+	assert.NotNil(t, logReq.NewLogConfigMap())
+
+	deployment, err := logReq.NewTaskManagerDeployment()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	podSpec := deployment.Spec.Template.Spec
+	assert.NotNil(t, podSpec.Volumes[0].EmptyDir)
+	assert.Equal(t, "log-config", podSpec.Volumes[2].Name)
+	assert.Equal(t, "log-shipper", podSpec.Containers[1].Name)
+}
+
+func TestNewV12Ingress(t *testing.T) {
+	v12 := model.CreateFlinkV12ClusterRequest{
+		Name:      tea.String("demo"),
+		NameSpace: tea.String("flink"),
+		Owner:     tea.String("test-owner"),
+		Ingress: &model.FlinkIngress{
+			Template:    tea.String("flink.example.com/{{namespace}}/{{name}}(/|$)(.*)"),
+			ClassName:   tea.String("nginx"),
+			Annotations: map[string]string{"nginx.ingress.kubernetes.io/rewrite-target": "/$2"},
+		},
+	}
+	assert.False(t, v12.NeedLBService())
+	ingressReq := v12.NewIngress()
+	assert.Equal(t, "demo-jobmanager-ingress", *ingressReq.Name)
+	assert.Equal(t, "flink.example.com", *ingressReq.Host)
+	assert.Equal(t, "/flink/demo(/|$)(.*)", *ingressReq.Path)
+	assert.Equal(t, "demo-jobmanager-service", *ingressReq.ServiceName)
+	assert.Equal(t, "test-owner", ingressReq.Labels["owner"])
+
+	ingress, err := ingressReq.NewIngress()
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx", *ingress.Spec.IngressClassName)
+	path := ingress.Spec.Rules[0].HTTP.Paths[0]
+	assert.Equal(t, networkingv1.PathTypeImplementationSpecific, *path.PathType)
+	assert.Equal(t, int32(8081), *path.Backend.Service.Port.Number)
+
+	// 配置了 loadBalancer 时仍然创建 LB
+	v12.LoadBalancer = &model.LoadBalancerRequest{}
+	assert.True(t, v12.NeedLBService())
+	assert.Nil(t, (&model.CreateFlinkV12ClusterRequest{Name: tea.String("demo")}).NewIngress())
+}
+
+func TestFreeNodePort(t *testing.T) {
+	services := []v1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "flink", Name: "a"}, Spec: v1.ServiceSpec{Ports: []v1.ServicePort{{NodePort: 30000}}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "flink", Name: "b"}, Spec: v1.ServiceSpec{Ports: []v1.ServicePort{{NodePort: 30001}, {Port: 80}}}},
+	}
+	port, err := model.FreeNodePort(services, "flink", "new", map[int32]bool{30002: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(30003), port)
+
+	// 已存在的 service 复用原来的端口
+	port, err = model.FreeNodePort(services, "flink", "b", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(30001), port)
+
+	lb := req.NewLBService(0)
+	assert.Nil(t, lb.Spec.Ports[0].NodePort)
+	assert.Equal(t, int32(8081), *lb.Spec.Ports[0].Port)
+
+	assert.Error(t, (&model.LoadBalancerRequest{Port: tea.Int32(80)}).Validate())
+	assert.NoError(t, (&model.LoadBalancerRequest{Port: tea.Int32(31000)}).Validate())
+	assert.True(t, model.IsNodePortAllocatedError(fmt.Errorf(`Service "x" is invalid: spec.ports[0].nodePort: Invalid value: 30000: provided port is already allocated`)))
+}
+
+// TestNewV12DeploymentParallel 并发生成不能串用 name、labels 和 containers，go test -race 运行
+func TestNewV12DeploymentParallel(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("cluster-%d", i)
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			v12 := model.CreateFlinkV12ClusterRequest{
+				Name:        tea.String(name),
+				NameSpace:   tea.String("flink"),
+				Owner:       tea.String(name),
+				Env:         map[string]string{"CLUSTER": name},
+				JobManager:  &model.JobManagerV12{Resource: &model.FlinkResource{Memory: tea.String("1Gi"), CPU: tea.String("1")}},
+				TaskManager: &model.TaskManagerV12{Nu: tea.Int(2)},
+			}
+			jm, err := v12.NewJobManagerDeployment()
+			assert.NoError(t, err)
+			tm, err := v12.NewTaskManagerDeployment()
+			assert.NoError(t, err)
+
+			assert.Equal(t, name+"-jobmanager", jm.Name)
+			assert.Equal(t, map[string]string{"app": name, "owner": name}, jm.Labels)
+			assert.Equal(t, map[string]string{"app": name, "component": "jobmanager"}, jm.Spec.Selector.MatchLabels)
+			assert.Len(t, jm.Spec.Template.Spec.Containers, 1)
+			assert.Equal(t, []v1.EnvVar{{Name: "CLUSTER", Value: name}}, jm.Spec.Template.Spec.Containers[0].Env)
+			assert.Equal(t, name+"-pvc", jm.Spec.Template.Spec.Volumes[2].PersistentVolumeClaim.ClaimName)
+
+			assert.Equal(t, name+"-taskmanager", tm.Name)
+			assert.Equal(t, int32(2), *tm.Spec.Replicas)
+			assert.Equal(t, map[string]string{"app": name, "component": "taskmanager"}, tm.Spec.Template.Labels)
+			assert.Equal(t, name+"-configmap", tm.Spec.Template.Spec.Volumes[1].ConfigMap.Name)
+		})
+	}
+}
+
+func TestNewV12DeploymentSideCar(t *testing.T) {
+	v12 := req
+	v12.JobManager = &model.JobManagerV12{
+		SideCars: []model.SideCar{{
+			Name:          tea.String("sidecar"),
+			Image:         tea.String("busybox"),
+			Command:       []string{"sleep", "10"},
+			VolumeMounts:  []map[string]any{{"name": "flink-log", "mountPath": "/log"}},
+			LivenessProbe: &model.LivenessProbe{PeriodSeconds: tea.Int32(10), Exec: &model.Exec{Command: []string{"true"}}},
+		}},
+	}
+	jm, err := v12.NewJobManagerDeployment()
+	assert.NoError(t, err)
+	sideCar := jm.Spec.Template.Spec.Containers[1]
+	assert.Equal(t, "/log", sideCar.VolumeMounts[0].MountPath)
+	assert.Equal(t, int32(10), sideCar.LivenessProbe.PeriodSeconds)
+	assert.Equal(t, []string{"true"}, sideCar.LivenessProbe.Exec.Command)
+
+	v12.JobManager.Resource = &model.FlinkResource{Memory: tea.String("1G"), CPU: tea.String("one")}
+	_, err = v12.NewJobManagerDeployment()
+	assert.ErrorContains(t, err, "jobManager.resource")
+}
+
+func TestApplyFlinkV12Cluster(t *testing.T) {
+	v12 := model.CreateFlinkV12ClusterRequest{
+		Name:        tea.String("demo"),
+		NameSpace:   tea.String("flink"),
+		Env:         map[string]string{"A": "1"},
+		JobManager:  &model.JobManagerV12{SideCars: []model.SideCar{{Name: tea.String("old"), Image: tea.String("busybox")}}},
+		TaskManager: &model.TaskManagerV12{Nu: tea.Int(2)},
+	}
+	jm, err := v12.NewJobManagerDeployment()
+	assert.NoError(t, err)
+	tm, err := v12.NewTaskManagerDeployment()
+	assert.NoError(t, err)
+	assert.Equal(t, model.ConfigHash(v12.NewConfigMap().Data), jm.Spec.Template.Annotations[model.ConfigHashAnnotation])
+	assert.Equal(t, "old", jm.Annotations[model.SideCarsAnnotation])
+
+	assert.Error(t, (&model.ApplyFlinkV12ClusterRequest{Labels: map[string]string{"app": "other"}}).Validate("demo"))
+	assert.Error(t, (&model.ApplyFlinkV12ClusterRequest{JobManager: &model.JobManagerV12{PvcSize: tea.Int(1)}}).Validate("demo"))
+
+	apply := model.ApplyFlinkV12ClusterRequest{
+		Labels:      map[string]string{"owner": "xops"},
+		Image:       tea.String("flink:1.12.8"),
+		Env:         map[string]string{"A": "1"},
+		JobManager:  &model.JobManagerV12{SideCars: []model.SideCar{{Name: tea.String("new"), Image: tea.String("busybox")}}},
+		TaskManager: &model.TaskManagerV12{Nu: tea.Int(4), Resource: &model.FlinkResource{CPU: tea.String("2"), Memory: tea.String("4Gi")}},
+	}
+	changes, restart, err := apply.ApplyToDeployment(jm, "jobmanager", "")
+	assert.NoError(t, err)
+	assert.True(t, restart)
+	var fields []string
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	assert.Equal(t, []string{"labels.owner", "image", "sideCars"}, fields)
+	assert.Equal(t, []string{"jobmanager", "new"}, []string{jm.Spec.Template.Spec.Containers[0].Name, jm.Spec.Template.Spec.Containers[1].Name})
+	assert.Len(t, jm.Spec.Template.Spec.Containers, 2)
+	assert.Equal(t, "new", jm.Annotations[model.SideCarsAnnotation])
+
+	changes, restart, err = apply.ApplyToDeployment(tm, "taskmanager", "abc")
+	assert.NoError(t, err)
+	assert.True(t, restart)
+	assert.Equal(t, int32(4), *tm.Spec.Replicas)
+	assert.Equal(t, "abc", tm.Spec.Template.Annotations[model.ConfigHashAnnotation])
+	assert.Equal(t, "4Gi", tm.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String())
+	assert.Len(t, changes, 5)
+
+	// 再次更新没有变化
+	changes, restart, err = apply.ApplyToDeployment(tm, "taskmanager", "abc")
+	assert.NoError(t, err)
+	assert.False(t, restart)
+	assert.Empty(t, changes)
+
+	// 只修改副本数不需要重启
+	changes, restart, err = (&model.ApplyFlinkV12ClusterRequest{TaskManager: &model.TaskManagerV12{Nu: tea.Int(1)}}).ApplyToDeployment(tm, "taskmanager", "")
+	assert.NoError(t, err)
+	assert.False(t, restart)
+	assert.Equal(t, []model.FlinkV12Change{{Resource: "demo-taskmanager", Field: "replicas", From: "4", To: "1"}}, changes)
+
+	data := (&model.ApplyFlinkV12ClusterRequest{FlinkConfiguration: map[string]any{"parallelism.default": 2}}).NewConfigData(map[string]string{"logback-console.xml": "<configuration/>"})
+	assert.Equal(t, map[string]string{"flink-conf.yaml": "parallelism.default: 2\n", "logback-console.xml": "<configuration/>"}, data)
+}
+
+func TestFlinkV12SnapshotToCreateRequest(t *testing.T) {
+	v12 := model.CreateFlinkV12ClusterRequest{
+		Name:               tea.String("demo"),
+		NameSpace:          tea.String("flink"),
+		Owner:              tea.String("alice"),
+		JobManager:         &model.JobManagerV12{Resource: &model.FlinkResource{CPU: tea.String("500m"), Memory: tea.String("1Gi")}},
+		TaskManager:        &model.TaskManagerV12{Nu: tea.Int(3), Resource: &model.FlinkResource{CPU: tea.String("2"), Memory: tea.String("4Gi")}},
+		FlinkConfigRequest: map[string]any{"taskmanager.numberOfTaskSlots": 2},
+	}
+	jm, err := v12.NewJobManagerDeployment()
+	assert.NoError(t, err)
+	tm, err := v12.NewTaskManagerDeployment()
+	assert.NoError(t, err)
+	configMap := v12.NewConfigMap()
+	snapshot := model.FlinkV12Snapshot{
+		JobManager:  jm,
+		TaskManager: tm,
+		ConfigMap:   &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: *configMap.Name}, Data: configMap.Data},
+		LBService: &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo-jobmanager-lb-service", Annotations: map[string]string{"lb": "internal"}},
+			Spec:       v1.ServiceSpec{Ports: []v1.ServicePort{{Port: 8081, NodePort: 30081}}},
+		},
+		PvcExists: true,
+	}
+	migrate := model.MigrateFlinkV12Request{
+		ClusterName: tea.String("demo"),
+		Image:       tea.String("flink:1.17"),
+		Job:         &model.Job{JarURI: tea.String("local:///opt/flink/job.jar")},
+	}
+	create, warnings, err := snapshot.ToCreateRequest(migrate)
+	assert.NoError(t, err)
+	assert.NoError(t, create.Validate())
+	assert.Equal(t, "demo", *create.ClusterName)
+	assert.Equal(t, "flink", *create.NameSpace)
+	assert.Equal(t, "flink:1.17", *create.Image)
+	assert.Equal(t, "alice", *create.Submitter)
+	assert.Equal(t, int32(6), *create.Job.Parallelism)
+	assert.Nil(t, migrate.Job.Parallelism)
+	assert.Equal(t, "1", *create.JobManager.Resource.CPU)
+	assert.Equal(t, "1024Mi", *create.JobManager.Resource.Memory)
+	assert.Equal(t, "4096Mi", *create.TaskManager.Resource.Memory)
+	assert.NotContains(t, create.FlinkConfiguration, "jobmanager.rpc.address")
+	assert.Equal(t, 2, create.FlinkConfiguration["taskmanager.numberOfTaskSlots"])
+	assert.Equal(t, int32(30081), *create.LoadBalancer.Port)
+	assert.Equal(t, "internal", create.LoadBalancer.Annotations["lb"])
+	assert.Contains(t, warnings, "jobmanager cpu 500m is rounded up to 1")
+
+	// 不同名称时不接管 LB
+	migrate.TargetName = tea.String("demo-operator")
+	create, _, err = snapshot.ToCreateRequest(migrate)
+	assert.NoError(t, err)
+	assert.Nil(t, create.LoadBalancer.Port)
+
+	assert.Error(t, (&model.MigrateFlinkV12Request{ClusterName: tea.String("demo"), Savepoint: tea.Bool(true)}).Validate())
+}
+
+func TestFlinkDeploymentHealth(t *testing.T) {
+	newItem := func(status map[string]any) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]any{
+			"metadata": map[string]any{"name": "demo"},
+			"spec":     map[string]any{"job": map[string]any{"jarURI": "local:///job.jar"}},
+			"status":   status,
+		}}
+	}
+	healthy, err := model.FlinkDeploymentHealth(newItem(map[string]any{"jobManagerDeploymentStatus": "DEPLOYING"}))
+	assert.NoError(t, err)
+	assert.False(t, healthy)
+	healthy, err = model.FlinkDeploymentHealth(newItem(map[string]any{"jobManagerDeploymentStatus": "READY", "jobStatus": map[string]any{"state": "CREATED"}}))
+	assert.NoError(t, err)
+	assert.False(t, healthy)
+	healthy, err = model.FlinkDeploymentHealth(newItem(map[string]any{"jobManagerDeploymentStatus": "READY", "jobStatus": map[string]any{"state": "RUNNING"}}))
+	assert.NoError(t, err)
+	assert.True(t, healthy)
+	_, err = model.FlinkDeploymentHealth(newItem(map[string]any{"jobManagerDeploymentStatus": "ERROR", "error": "image pull failed"}))
+	assert.ErrorContains(t, err, "image pull failed")
+	_, err = model.FlinkDeploymentHealth(newItem(map[string]any{"jobManagerDeploymentStatus": "READY", "jobStatus": map[string]any{"state": "FAILED"}}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	networkingv1ac "k8s.io/client-go/applyconfigurations/networking/v1"
+)
+
+// 基于路径的模板需要去掉的正则后缀，配合 nginx.ingress.kubernetes.io/rewrite-target: /$2 使用
+const ingressPathRegexSuffix = "(/|$)(.*)"
+
+// FlinkIngress 对应 operator 的 spec.ingress，v1.12 集群也使用相同的模板规则
+type FlinkIngress struct {
+	Template    *string           `json:"template" binding:"required"` // {{name}}.{{namespace}}.flink.example.com 或 flink.example.com/{{namespace}}/{{name}}(/|$)(.*)
+	ClassName   *string           `json:"class_name"`                  // ingressClassName，比如 nginx
+	Annotations map[string]string `json:"annotations"`
+}
+
+func (i *FlinkIngress) Validate() error {
+	if i == nil {
+		return nil
+	}
+	if i.Template == nil || *i.Template == "" {
+		return fmt.Errorf("ingress.template is required")
+	}
+	if !strings.Contains(*i.Template, "{{name}}") {
+		return fmt.Errorf("ingress.template must contain {{name}}")
+	}
+	return nil
+}
+
+func (i *FlinkIngress) ToYaml() map[string]any {
+	yaml := map[string]any{
+		"template": *i.Template,
+	}
+	if i.ClassName != nil {
+		yaml["className"] = *i.ClassName
+	}
+	if len(i.Annotations) > 0 {
+		yaml["annotations"] = i.Annotations
+	}
+	return yaml
+}
+
+// Render 按 operator 的规则渲染模板，第一个 / 之前是 host，之后是 path
+func (i *FlinkIngress) Render(namespace, name string) (host, path string) {
+	rendered := strings.NewReplacer("{{name}}", name, "{{namespace}}", namespace).Replace(*i.Template)
+	index := strings.Index(rendered, "/")
+	if index < 0 {
+		return rendered, "/"
+	}
+	return rendered[:index], rendered[index:]
+}
+
+type ApplyIngressRequest struct {
+	Namespace   *string           `json:"namespace"`
+	Name        *string           `json:"name" binding:"required"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	ClassName   *string           `json:"class_name"`
+	Host        *string           `json:"host"` // 为空时匹配所有域名
+	Path        *string           `json:"path" default:"/"`
+	ServiceName *string           `json:"service_name" binding:"required"`
+	ServicePort *int32            `json:"service_port" binding:"required"`
+}
+
+func (req *ApplyIngressRequest) NewIngress() (*networkingv1ac.IngressApplyConfiguration, error) {
+	if req.Name == nil || req.ServiceName == nil || req.ServicePort == nil {
+		return nil, fmt.Errorf("need name, service_name and service_port required")
+	}
+	var namespace string
+	if req.Namespace != nil {
+		namespace = *req.Namespace
+	} else {
+		namespace = v1.NamespaceDefault
+	}
+	path := "/"
+	if req.Path != nil && *req.Path != "" {
+		path = *req.Path
+	}
+	// 正则路径需要使用 ImplementationSpecific
+	pathType := networkingv1.PathTypePrefix
+	if strings.ContainsAny(path, "()*$") {
+		pathType = networkingv1.PathTypeImplementationSpecific
+	}
+
+	rule := networkingv1ac.IngressRule().WithHTTP(networkingv1ac.HTTPIngressRuleValue().WithPaths(
+		networkingv1ac.HTTPIngressPath().
+			WithPath(path).
+			WithPathType(pathType).
+			WithBackend(networkingv1ac.IngressBackend().WithService(
+				networkingv1ac.IngressServiceBackend().
+					WithName(*req.ServiceName).
+					WithPort(networkingv1ac.ServiceBackendPort().WithNumber(*req.ServicePort)),
+			)),
+	))
+	if req.Host != nil && *req.Host != "" {
+		rule.WithHost(*req.Host)
+	}
+	spec := networkingv1ac.IngressSpec().WithRules(rule)
+	if req.ClassName != nil {
+		spec.WithIngressClassName(*req.ClassName)
+	}
+	yaml := networkingv1ac.Ingress(*req.Name, namespace).WithSpec(spec)
+	if req.Labels != nil {
+		yaml.WithLabels(req.Labels)
+	}
+	if req.Annotations != nil {
+		yaml.WithAnnotations(req.Annotations)
+	}
+	return yaml, nil
+}
+
+func (req *ApplyIngressRequest) ToOptions() metav1.ApplyOptions {
+	return metav1.ApplyOptions{
+		FieldManager: "multi-k8s-client",
+		Force:        true,
+	}
+}
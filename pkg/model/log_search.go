@@ -0,0 +1,230 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 日志搜索同时读取 Flink JM/TM 或者 Spark driver/executor 所有 pod 的日志，按时间合并匹配的行
+
+const (
+	logSearchMaxContext = 50
+	logSearchMaxLine    = 1024 * 1024 // 单行最大长度，超过时该 pod 的扫描出错
+)
+
+type LogSearchRequest struct {
+	NameSpace      *string    `json:"namespace" default:"default"`
+	Name           *string    `json:"name" binding:"required"`    // Flink 集群名称或者 SparkApplication 名称
+	Pattern        *string    `json:"pattern" binding:"required"` // 子串，regex 为 true 时是正则表达式
+	Regex          bool       `json:"regex"`
+	IgnoreCase     bool       `json:"ignore_case"`
+	Context        int        `json:"context"`                              // 匹配行前后各返回多少行，最多 50
+	TailLines      *int64     `json:"tail_lines"`                           // 每个 pod 只搜索最后 n 行
+	SinceTime      *time.Time `json:"since_time"`                           // 只搜索该时间之后的日志
+	Previous       bool       `json:"previous"`                             // 搜索容器上一次重启前的日志
+	MaxBytesPerPod *int64     `json:"max_bytes_per_pod" default:"10485760"` // 每个 pod 最多读取的字节数，默认 10MiB
+	MaxMatches     *int       `json:"max_matches" default:"1000"`           // 最多返回的匹配行数，超过时 truncated 为 true
+	Concurrency    *int       `json:"concurrency" default:"10"`             // 同时读取日志的 pod 数量
+	Timeout        *string    `json:"timeout"`                              // 整体超时时间，比如 1m，默认 30 秒，超时的 pod 记录在 errors 中
+}
+
+type LogSearchLine struct {
+	Pod        string     `json:"pod"`
+	Container  string     `json:"container"`
+	LineNumber int        `json:"line_number"` // 在该 pod 读取到的日志中的行号，从 1 开始
+	Time       *time.Time `json:"time"`        // k8s 记录的时间
+	Text       string     `json:"text"`
+	Match      bool       `json:"match"` // false 表示上下文行
+}
+
+type LogSearchResponse struct {
+	Lines     []LogSearchLine   `json:"lines"`
+	Matches   int               `json:"matches"`
+	Pods      []string          `json:"pods"`      // 搜索的 pod
+	Truncated bool              `json:"truncated"` // 匹配行超过 max_matches
+	Errors    map[string]string `json:"errors"`    // pod -> 读取失败或者超时的原因，其他 pod 的结果正常返回
+}
+
+func (req *LogSearchRequest) Validate() error {
+	if req.Name == nil || *req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if req.Pattern == nil || *req.Pattern == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	if req.Context < 0 || req.Context > logSearchMaxContext {
+		return fmt.Errorf("context must be between 0 and %d", logSearchMaxContext)
+	}
+	if req.MaxMatches != nil && *req.MaxMatches <= 0 {
+		return fmt.Errorf("max_matches must be positive")
+	}
+	if req.Concurrency != nil && *req.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+	if _, err := parseTimeout("timeout", req.Timeout, 0); err != nil {
+		return err
+	}
+	if _, err := req.NewMatcher(); err != nil {
+		return err
+	}
+	opts := req.PodLogOptions("")
+	return opts.Validate()
+}
+
+// PodLogOptions 每个 pod 的读取参数，需要时间戳合并结果，不支持 follow
+func (req *LogSearchRequest) PodLogOptions(container string) PodLogOptions {
+	maxBytes := int64(10 * 1024 * 1024)
+	if req.MaxBytesPerPod != nil {
+		maxBytes = *req.MaxBytesPerPod
+	}
+	opts := PodLogOptions{
+		TailLines:  req.TailLines,
+		SinceTime:  req.SinceTime,
+		Previous:   req.Previous,
+		Timestamps: true,
+		LimitBytes: &maxBytes,
+	}
+	if container != "" {
+		opts.Container = &container
+	}
+	return opts
+}
+
+func (req *LogSearchRequest) GetMaxMatches() int {
+	if req.MaxMatches != nil {
+		return *req.MaxMatches
+	}
+	return 1000
+}
+
+func (req *LogSearchRequest) GetConcurrency() int {
+	if req.Concurrency != nil {
+		return *req.Concurrency
+	}
+	return 10
+}
+
+// GetTimeout 请先调用 Validate 校验
+func (req *LogSearchRequest) GetTimeout() time.Duration {
+	timeout, _ := parseTimeout("timeout", req.Timeout, 30*time.Second)
+	return timeout
+}
+
+type LogMatcher struct {
+	match      func(string) bool
+	context    int
+	maxMatches int
+}
+
+func (req *LogSearchRequest) NewMatcher() (*LogMatcher, error) {
+	m := &LogMatcher{context: req.Context, maxMatches: req.GetMaxMatches()}
+	pattern := *req.Pattern
+	switch {
+	case req.Regex:
+		if req.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %v", err)
+		}
+		m.match = re.MatchString
+	case req.IgnoreCase:
+		pattern = strings.ToLower(pattern)
+		m.match = func(line string) bool { return strings.Contains(strings.ToLower(line), pattern) }
+	default:
+		m.match = func(line string) bool { return strings.Contains(line, pattern) }
+	}
+	return m, nil
+}
+
+// Scan 读取一个 pod 的日志，返回匹配行和上下文行，匹配行超过 max_matches 后停止
+// 日志需要使用 timestamps 读取，没有时间戳的行 Time 为 nil
+func (m *LogMatcher) Scan(pod, container string, r io.Reader) ([]LogSearchLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), logSearchMaxLine)
+	var (
+		lines      []LogSearchLine
+		before     []LogSearchLine // 最近的 context 行，匹配时作为上文
+		after      int             // 还需要输出的下文行数
+		matches    int
+		lineNumber int
+	)
+	for scanner.Scan() {
+		lineNumber++
+		line := LogSearchLine{Pod: pod, Container: container, LineNumber: lineNumber}
+		line.Time, line.Text = splitLogTimestamp(scanner.Text())
+		if m.match(line.Text) {
+			line.Match = true
+			if matches++; matches > m.maxMatches {
+				// 多保留一个匹配行，合并时用来判断是否 truncated
+				lines = append(lines, line)
+				break
+			}
+			lines = append(lines, before...)
+			lines = append(lines, line)
+			before = before[:0]
+			after = m.context
+			continue
+		}
+		if after > 0 {
+			lines = append(lines, line)
+			after--
+			continue
+		}
+		if m.context > 0 {
+			if len(before) == m.context {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitLogTimestamp 拆分 kubelet 在 timestamps=true 时添加的 RFC3339Nano 时间
+func splitLogTimestamp(line string) (*time.Time, string) {
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+			return &t, line[i+1:]
+		}
+	}
+	return nil, line
+}
+
+// MergeLogSearchLines 按时间合并多个 pod 的结果，同一时间按 pod 名称和行号，只保留前 maxMatches 个匹配行和它们的上下文
+func MergeLogSearchLines(results map[string][]LogSearchLine, maxMatches int) (lines []LogSearchLine, matches int, truncated bool) {
+	lines = []LogSearchLine{}
+	for _, podLines := range results {
+		lines = append(lines, podLines...)
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		a, b := lines[i], lines[j]
+		switch {
+		case a.Time != nil && b.Time != nil && !a.Time.Equal(*b.Time):
+			return a.Time.Before(*b.Time)
+		case (a.Time == nil) != (b.Time == nil):
+			// 没有时间的行放在最后
+			return a.Time != nil
+		case a.Pod != b.Pod:
+			return a.Pod < b.Pod
+		}
+		return a.LineNumber < b.LineNumber
+	})
+	for i, line := range lines {
+		if !line.Match {
+			continue
+		}
+		if matches == maxMatches {
+			// 丢弃后面的行，包括属于已保留匹配行的下文
+			return lines[:i], matches, true
+		}
+		matches++
+	}
+	return lines, matches, false
+}
@@ -0,0 +1,82 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestLogSearchScan(t *testing.T) {
+	logs := strings.Join([]string{
+		"2026-10-19T08:00:01.000000001Z starting job",
+		"2026-10-19T08:00:02Z checkpoint 1 completed",
+		"2026-10-19T08:00:03Z java.lang.OutOfMemoryError: Java heap space",
+		"\tat org.apache.flink.Task.run",
+		"2026-10-19T08:00:04Z restarting",
+		"2026-10-19T08:00:05Z checkpoint 2 completed",
+		"2026-10-19T08:00:06Z outofmemoryerror again",
+	}, "\n")
+
+	req := model.LogSearchRequest{Name: tea.String("wordcount"), Pattern: tea.String("OutOfMemoryError"), Context: 1}
+	assert.NoError(t, req.Validate())
+	m, err := req.NewMatcher()
+	assert.NoError(t, err)
+	lines, err := m.Scan("tm-1", "flink-main-container", strings.NewReader(logs))
+	assert.NoError(t, err)
+	assert.Len(t, lines, 3)
+	assert.Equal(t, []int{2, 3, 4}, []int{lines[0].LineNumber, lines[1].LineNumber, lines[2].LineNumber})
+	assert.True(t, lines[1].Match)
+	assert.Equal(t, "java.lang.OutOfMemoryError: Java heap space", lines[1].Text)
+	assert.Equal(t, "2026-10-19T08:00:03Z", lines[1].Time.Format("2006-01-02T15:04:05Z07:00"))
+	// 没有时间戳的行保持原样
+	assert.Nil(t, lines[2].Time)
+	assert.Equal(t, "\tat org.apache.flink.Task.run", lines[2].Text)
+
+	// 忽略大小写的正则，相邻匹配的上下文不重复
+	req = model.LogSearchRequest{Name: tea.String("wordcount"), Pattern: tea.String(`outofmemory|checkpoint 2`), Regex: true, IgnoreCase: true, Context: 2}
+	m, err = req.NewMatcher()
+	assert.NoError(t, err)
+	lines, err = m.Scan("tm-1", "flink-main-container", strings.NewReader(logs))
+	assert.NoError(t, err)
+	var numbers []int
+	for _, line := range lines {
+		numbers = append(numbers, line.LineNumber)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7}, numbers)
+
+	for _, req := range []model.LogSearchRequest{
+		{Name: tea.String("wordcount")},
+		{Name: tea.String("wordcount"), Pattern: tea.String("("), Regex: true},
+		{Name: tea.String("wordcount"), Pattern: tea.String("error"), Context: 51},
+		{Name: tea.String("wordcount"), Pattern: tea.String("error"), MaxBytesPerPod: tea.Int64(0)},
+		{Name: tea.String("wordcount"), Pattern: tea.String("error"), Timeout: tea.String("30")},
+	} {
+		assert.Error(t, req.Validate())
+	}
+}
+
+func TestMergeLogSearchLines(t *testing.T) {
+	req := model.LogSearchRequest{Name: tea.String("spark-pi"), Pattern: tea.String("ERROR"), MaxMatches: tea.Int(2)}
+	m, _ := req.NewMatcher()
+	driver, _ := m.Scan("spark-pi-driver", "spark-kubernetes-driver", strings.NewReader(
+		"2026-10-19T08:00:01Z ERROR a\n2026-10-19T08:00:04Z ERROR d\n"))
+	exec1, _ := m.Scan("spark-pi-exec-1", "spark-kubernetes-executor", strings.NewReader(
+		"2026-10-19T08:00:02Z ERROR b\n2026-10-19T08:00:03Z ERROR c\n2026-10-19T08:00:05Z ERROR e\n"))
+	// 单个 pod 最多多保留一个匹配行
+	assert.Len(t, exec1, 3)
+
+	lines, matches, truncated := model.MergeLogSearchLines(map[string][]model.LogSearchLine{"spark-pi-driver": driver, "spark-pi-exec-1": exec1}, 2)
+	assert.Equal(t, 2, matches)
+	assert.True(t, truncated)
+	assert.Equal(t, "ERROR a", lines[0].Text)
+	assert.Equal(t, "spark-pi-exec-1", lines[1].Pod)
+	assert.Len(t, lines, 2)
+
+	lines, matches, truncated = model.MergeLogSearchLines(map[string][]model.LogSearchLine{"spark-pi-driver": driver}, 2)
+	assert.Equal(t, 2, matches)
+	assert.False(t, truncated)
+	assert.Len(t, lines, 2)
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodExecOptions 对应 kubectl exec，Stdin、Stdout、Stderr 为 nil 时不打开对应的流
+type PodExecOptions struct {
+	Container *string        // 多容器 pod 需要指定
+	Command   []string       // 不经过 shell，需要管道等语法时使用 sh -c
+	Stdin     io.Reader      // 读到 EOF 后关闭远端的 stdin
+	Stdout    io.Writer      // tty 时 stderr 合并到 stdout
+	Stderr    io.Writer      // tty 时不支持
+	TTY       bool           // 分配终端，交互式命令使用
+	Timeout   *time.Duration // 为空时直到命令结束或者连接断开
+}
+
+func (o *PodExecOptions) Validate() error {
+	if len(o.Command) == 0 || o.Command[0] == "" {
+		return fmt.Errorf("command is required")
+	}
+	if o.Container != nil && *o.Container == "" {
+		return fmt.Errorf("container must not be empty")
+	}
+	if o.Stdin == nil && o.Stdout == nil && o.Stderr == nil {
+		return fmt.Errorf("at least one of stdin, stdout and stderr is required")
+	}
+	if o.TTY && o.Stderr != nil {
+		return fmt.Errorf("stderr is not supported with tty, it is merged into stdout")
+	}
+	if o.Timeout != nil && *o.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	return nil
+}
+
+func (o *PodExecOptions) ToOptions() *corev1.PodExecOptions {
+	opts := &corev1.PodExecOptions{
+		Command: o.Command,
+		Stdin:   o.Stdin != nil,
+		Stdout:  o.Stdout != nil,
+		Stderr:  o.Stderr != nil,
+		TTY:     o.TTY,
+	}
+	if o.Container != nil {
+		opts.Container = *o.Container
+	}
+	return opts
+}
+
+// PodExecRequest 执行一条命令并返回输出，比如查看 v1.12 PVC 中 /opt/flink/target 的 jar 或者在 TM 中执行 jcmd
+type PodExecRequest struct {
+	NameSpace *string  `json:"namespace" default:"default"`
+	PodName   *string  `json:"pod_name" binding:"required"`
+	Container *string  `json:"container"` // 为空时使用 Flink 或 Spark 的主容器，都不存在时由 k8s 选择
+	Command   []string `json:"command" binding:"required"`
+	Stdin     *string  `json:"stdin"`
+	Timeout   *string  `json:"timeout"` // 比如 30s，默认 1 分钟
+}
+
+type PodExecResponse struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"` // 命令的退出码，非 0 时不返回 error
+}
+
+func (req *PodExecRequest) Validate() error {
+	if req.PodName == nil || *req.PodName == "" {
+		return fmt.Errorf("pod_name is required")
+	}
+	if len(req.Command) == 0 || req.Command[0] == "" {
+		return fmt.Errorf("command is required")
+	}
+	if req.Container != nil && *req.Container == "" {
+		return fmt.Errorf("container must not be empty")
+	}
+	if _, err := parseTimeout("timeout", req.Timeout, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetTimeout 请先调用 Validate 校验
+func (req *PodExecRequest) GetTimeout() time.Duration {
+	timeout, _ := parseTimeout("timeout", req.Timeout, time.Minute)
+	return timeout
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	FlinkMainContainer     = "flink-main-container"    // operator 创建的 JM/TM 主容器，v1.12 集群为 jobmanager 或 taskmanager
+	SparkDriverContainer   = "spark-kubernetes-driver" // spark driver 主容器
+	SparkExecutorContainer = "spark-kubernetes-executor"
+	SparkAppNameLabel      = "sparkoperator.k8s.io/app-name"
+	SparkRoleLabel         = "spark-role" // driver 或 executor
+)
+
+type PodLogOptions struct {
+	Container  *string    `json:"container"`   // 多容器 pod 需要指定，Flink 和 Spark helper 默认使用主容器
+	TailLines  *int64     `json:"tail_lines"`  // 只返回最后 n 行
+	SinceTime  *time.Time `json:"since_time"`  // 只返回该时间之后的日志
+	Previous   bool       `json:"previous"`    // 容器上一次重启前的日志，排查 OOM 或者 crash
+	Follow     bool       `json:"follow"`      // 持续输出，调用方关闭返回的 ReadCloser 结束
+	Timestamps bool       `json:"timestamps"`  // 每行前面加上时间戳
+	LimitBytes *int64     `json:"limit_bytes"` // 最多返回的字节数，超过时截断
+}
+
+func (o *PodLogOptions) Validate() error {
+	if o.TailLines != nil && *o.TailLines < 0 {
+		return fmt.Errorf("tail_lines must not be negative")
+	}
+	if o.LimitBytes != nil && *o.LimitBytes <= 0 {
+		return fmt.Errorf("limit_bytes must be positive")
+	}
+	if o.Previous && o.Follow {
+		return fmt.Errorf("previous and follow can not be set at the same time")
+	}
+	return nil
+}
+
+func (o *PodLogOptions) ToOptions() *corev1.PodLogOptions {
+	opts := &corev1.PodLogOptions{
+		TailLines:  o.TailLines,
+		Previous:   o.Previous,
+		Follow:     o.Follow,
+		Timestamps: o.Timestamps,
+		LimitBytes: o.LimitBytes,
+	}
+	if o.Container != nil {
+		opts.Container = *o.Container
+	}
+	if o.SinceTime != nil {
+		opts.SinceTime = &metav1.Time{Time: *o.SinceTime}
+	}
+	return opts
+}
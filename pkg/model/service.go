@@ -2,7 +2,9 @@ package model
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/alibabacloud-go/tea/tea"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -88,3 +90,94 @@ func (req *ApplyServiceRequest) ToOptions() metav1.ApplyOptions {
 		Force:        true,
 	}
 }
+
+type ServiceProxyRequest struct {
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name" binding:"required"` // service 名称
+	Port        string            `json:"port"`                    // 端口名称或者端口号
+	Method      string            `json:"method"`                  // 默认 GET
+	Path        string            `json:"path"`
+	Params      map[string]string `json:"params"`
+	Body        []byte            `json:"body"`
+	ContentType string            `json:"content_type"`
+}
+
+// NodePort 默认范围
+const (
+	NodePortMin int32 = 30000
+	NodePortMax int32 = 32767
+)
+
+// NewLBPort LB 对外端口和 NodePort 保持一致，nodePort 为 0 时由 k8s 分配 NodePort，对外端口使用 8081
+func NewLBPort(name string, nodePort int32) Port {
+	port := Port{
+		Name:       tea.String(name),
+		Protocol:   tea.String("TCP"),
+		Port:       tea.Int32(8081),
+		TargetPort: tea.Int32(8081),
+	}
+	if nodePort != 0 {
+		port.Port = tea.Int32(nodePort)
+		port.NodePort = tea.Int32(nodePort)
+	}
+	return port
+}
+
+// FreeNodePort 从已有 service 中找一个没有被占用的 NodePort，已存在的同名 service 复用原来的端口
+func FreeNodePort(services []v1.Service, namespace, name string, exclude map[int32]bool) (int32, error) {
+	used := map[int32]bool{}
+	for port := range exclude {
+		used[port] = true
+	}
+	for _, svc := range services {
+		for _, port := range svc.Spec.Ports {
+			if port.NodePort == 0 {
+				continue
+			}
+			if svc.Namespace == namespace && svc.Name == name && !used[port.NodePort] {
+				return port.NodePort, nil
+			}
+			used[port.NodePort] = true
+		}
+	}
+	for port := NodePortMin; port <= NodePortMax; port++ {
+		if !used[port] {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free node port in %d-%d", NodePortMin, NodePortMax)
+}
+
+// IsNodePortAllocatedError apiserver 返回 provided port is already allocated
+func IsNodePortAllocatedError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "port is already allocated")
+}
+
+// NewApplyServiceRequest 从已有的 service 生成 apply 请求，用于恢复 service
+func NewApplyServiceRequest(svc v1.Service) ApplyServiceRequest {
+	req := ApplyServiceRequest{
+		Namespace:   tea.String(svc.Namespace),
+		Name:        tea.String(svc.Name),
+		Labels:      svc.Labels,
+		Annotations: svc.Annotations,
+		Spec: &ServiceSpec{
+			Type:     tea.String(string(svc.Spec.Type)),
+			Selector: svc.Spec.Selector,
+		},
+	}
+	for _, port := range svc.Spec.Ports {
+		p := Port{
+			Name:     tea.String(port.Name),
+			Protocol: tea.String(string(port.Protocol)),
+			Port:     tea.Int32(port.Port),
+		}
+		if port.TargetPort.IntVal != 0 {
+			p.TargetPort = tea.Int32(port.TargetPort.IntVal)
+		}
+		if port.NodePort != 0 {
+			p.NodePort = tea.Int32(port.NodePort)
+		}
+		req.Spec.Ports = append(req.Spec.Ports, p)
+	}
+	return req
+}
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	SparkRerunOfAnnotation              = "multi-k8s-client/rerun-of"                // 原任务名称
+	SparkRerunOfApplicationIDAnnotation = "multi-k8s-client/rerun-of-application-id" // 原任务的 spark-xxx
+	SparkRerunOfStateAnnotation         = "multi-k8s-client/rerun-of-state"          // 重新运行时原任务的状态
+)
+
+// 生成的名称 <name>-rerun-<unix 时间戳>，再次重新运行时替换时间戳
+var sparkRerunSuffix = regexp.MustCompile(`-rerun-\d+$`)
+
+// RerunSparkApplicationRequest 基于已有 SparkApplication 的 spec 重新提交，覆盖字段为空时保持原值
+type RerunSparkApplicationRequest struct {
+	Namespace *string                `json:"namespace" binding:"required"`
+	Name      *string                `json:"name" binding:"required"` // 原任务名称
+	NewName   *string                `json:"new_name"`                // 新任务名称，为空时生成 <name>-rerun-<时间戳>
+	ReuseName bool                   `json:"reuse_name"`              // 删除原任务后使用相同名称提交，原任务需要已经结束
+	Arguments []string               `json:"arguments"`               // 替换 main 函数的参数，空数组表示不传参数
+	Image     *string                `json:"image"`
+	Driver    *SparkResourceOverride `json:"driver"`
+	Executor  *SparkResourceOverride `json:"executor"`
+	Timeout   *string                `json:"timeout"` // reuse_name 时等待原任务删除的时间，比如 5m，默认 2 分钟
+}
+
+type SparkResourceOverride struct {
+	Cores          *int32  `json:"cores"`
+	CoreLimit      *string `json:"coreLimit"`
+	Memory         *string `json:"memory"`
+	MemoryOverhead *string `json:"memoryOverhead"`
+	Instances      *int32  `json:"instances"` // 只对 executor 生效
+}
+
+func (req *RerunSparkApplicationRequest) Validate() error {
+	if req.Namespace == nil || *req.Namespace == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	if req.Name == nil || *req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if req.ReuseName && req.NewName != nil {
+		return fmt.Errorf("new_name and reuse_name can not be set at the same time")
+	}
+	if req.NewName != nil && (*req.NewName == "" || *req.NewName == *req.Name) {
+		return fmt.Errorf("new_name must be different from name, use reuse_name to rerun with the same name")
+	}
+	if req.Driver != nil && req.Driver.Instances != nil {
+		return fmt.Errorf("driver.instances is not supported")
+	}
+	for field, override := range map[string]*SparkResourceOverride{"driver": req.Driver, "executor": req.Executor} {
+		if override == nil {
+			continue
+		}
+		if override.Cores != nil && *override.Cores <= 0 {
+			return fmt.Errorf("%s.cores must be positive", field)
+		}
+		if override.Instances != nil && *override.Instances < 0 {
+			return fmt.Errorf("%s.instances must not be negative", field)
+		}
+	}
+	if _, err := parseTimeout("timeout", req.Timeout, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetTimeout 请先调用 Validate 校验
+func (req *RerunSparkApplicationRequest) GetTimeout() time.Duration {
+	timeout, _ := parseTimeout("timeout", req.Timeout, 2*time.Minute)
+	return timeout
+}
+
+// TargetName 重新提交使用的名称
+func (req *RerunSparkApplicationRequest) TargetName(now time.Time) string {
+	if req.ReuseName {
+		return *req.Name
+	}
+	if req.NewName != nil {
+		return *req.NewName
+	}
+	suffix := fmt.Sprintf("-rerun-%d", now.Unix())
+	base := sparkRerunSuffix.ReplaceAllString(*req.Name, "")
+	// driver pod 和 UI service 名称基于任务名称，保持在 63 个字符以内
+	if len(base)+len(suffix) > 63 {
+		base = base[:63-len(suffix)]
+	}
+	return base + suffix
+}
+
+// ToYaml 复制原任务的 spec、labels 和 annotations 并应用覆盖字段，不包含 status 和 resourceVersion 等服务端字段
+func (req *RerunSparkApplicationRequest) ToYaml(original CrdResourceDetail, now time.Time) map[string]any {
+	originMetadata, _ := original.Metadata.(map[string]any)
+	originSpec, _ := original.Spec.(map[string]any)
+	originStatus, _ := original.Status.(map[string]any)
+	metadata := map[string]any{
+		"name":      req.TargetName(now),
+		"namespace": *req.Namespace,
+	}
+	if labels, ok := originMetadata["labels"].(map[string]any); ok {
+		metadata["labels"] = runtime.DeepCopyJSONValue(labels)
+	}
+	annotations := map[string]any{}
+	if origin, ok := originMetadata["annotations"].(map[string]any); ok {
+		for k, v := range origin {
+			if k != "kubectl.kubernetes.io/last-applied-configuration" {
+				annotations[k] = v
+			}
+		}
+	}
+	annotations[SparkRerunOfAnnotation] = original.Name
+	if applicationID, _ := originStatus["sparkApplicationId"].(string); applicationID != "" {
+		annotations[SparkRerunOfApplicationIDAnnotation] = applicationID
+	}
+	annotations[SparkRerunOfStateAnnotation] = SparkApplicationState(originStatus)
+	metadata["annotations"] = annotations
+
+	spec := map[string]any{}
+	if originSpec != nil {
+		spec = runtime.DeepCopyJSON(originSpec)
+	}
+	if req.Arguments != nil {
+		spec["arguments"] = slices.Clone(req.Arguments)
+	}
+	if req.Image != nil {
+		spec["image"] = *req.Image
+	}
+	for role, override := range map[string]*SparkResourceOverride{"driver": req.Driver, "executor": req.Executor} {
+		pod := childMap(spec, role)
+		// CreateSparkApplicationRequest 生成的 app 标签跟随任务名称
+		if labels, ok := pod["labels"].(map[string]any); ok && labels["app"] == original.Name+"-"+role {
+			labels["app"] = metadata["name"].(string) + "-" + role
+		}
+		override.applyToYaml(pod)
+	}
+	return map[string]any{
+		"apiVersion": original.ApiVersion,
+		"kind":       original.Kind,
+		"metadata":   metadata,
+		"spec":       spec,
+	}
+}
+
+func (o *SparkResourceOverride) applyToYaml(pod map[string]any) {
+	if o == nil {
+		return
+	}
+	if o.Cores != nil {
+		pod["cores"] = int64(*o.Cores)
+	}
+	if o.CoreLimit != nil {
+		pod["coreLimit"] = *o.CoreLimit
+	}
+	if o.Memory != nil {
+		pod["memory"] = *o.Memory
+	}
+	if o.MemoryOverhead != nil {
+		pod["memoryOverhead"] = *o.MemoryOverhead
+	}
+	if o.Instances != nil {
+		pod["instances"] = int64(*o.Instances)
+	}
+}
+
+// SparkApplicationState status.applicationState.state，还没有 status 时为 NEW
+func SparkApplicationState(status map[string]any) string {
+	if appState, ok := status["applicationState"].(map[string]any); ok {
+		if state, ok := appState["state"].(string); ok && state != "" {
+			return state
+		}
+	}
+	return SparkStateNew
+}
+
+// IsSparkTerminalState 任务已经结束，不会再被 operator 重试
+func IsSparkTerminalState(state string) bool {
+	return slices.Contains(sparkTerminalStates, state)
+}
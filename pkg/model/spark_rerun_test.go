@@ -0,0 +1,86 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestRerunSparkApplicationRequest(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	original := model.CrdResourceDetail{
+		ApiVersion: "sparkoperator.k8s.io/v1beta2",
+		Kind:       "SparkApplication",
+		Name:       "spark-etl",
+		Metadata: map[string]any{
+			"name": "spark-etl", "namespace": "spark", "resourceVersion": "42", "uid": "abc",
+			"labels":      map[string]any{"team": "data"},
+			"annotations": map[string]any{"owner": "alice", "kubectl.kubernetes.io/last-applied-configuration": "{}"},
+		},
+		Spec: map[string]any{
+			"image":     "spark:3.5.0",
+			"arguments": []any{"--date", "2026-10-01"},
+			"driver":    map[string]any{"cores": int64(1), "memory": "512m", "labels": map[string]any{"app": "spark-etl-driver"}},
+			"executor":  map[string]any{"cores": int64(2), "instances": int64(2), "labels": map[string]any{"app": "spark-etl-executor"}},
+		},
+		Status: map[string]any{"sparkApplicationId": "spark-123", "applicationState": map[string]any{"state": "FAILED"}},
+	}
+	req := model.RerunSparkApplicationRequest{
+		Namespace: tea.String("spark"),
+		Name:      tea.String("spark-etl"),
+		Arguments: []string{"--date", "2026-10-02"},
+		Executor:  &model.SparkResourceOverride{Instances: tea.Int32(4), Memory: tea.String("2g")},
+	}
+	assert.NoError(t, req.Validate())
+	yaml := req.ToYaml(original, now)
+	metadata := yaml["metadata"].(map[string]any)
+	assert.Equal(t, "spark-etl-rerun-1760000000", metadata["name"])
+	assert.NotContains(t, metadata, "resourceVersion")
+	assert.Equal(t, map[string]any{"team": "data"}, metadata["labels"])
+	assert.Equal(t, map[string]any{
+		"owner":                      "alice",
+		model.SparkRerunOfAnnotation: "spark-etl",
+		model.SparkRerunOfApplicationIDAnnotation: "spark-123",
+		model.SparkRerunOfStateAnnotation:         "FAILED",
+	}, metadata["annotations"])
+	spec := yaml["spec"].(map[string]any)
+	assert.Equal(t, "spark:3.5.0", spec["image"])
+	assert.Equal(t, []string{"--date", "2026-10-02"}, spec["arguments"])
+	executor := spec["executor"].(map[string]any)
+	assert.Equal(t, int64(4), executor["instances"])
+	assert.Equal(t, "2g", executor["memory"])
+	assert.Equal(t, int64(2), executor["cores"])
+	assert.Equal(t, "spark-etl-rerun-1760000000-executor", executor["labels"].(map[string]any)["app"])
+	// 原任务不受影响
+	assert.Equal(t, int64(2), original.Spec.(map[string]any)["executor"].(map[string]any)["instances"])
+
+	// 再次重新运行替换时间戳，名称保持在 63 个字符以内
+	req.Name = tea.String("spark-etl-rerun-1759000000")
+	assert.Equal(t, "spark-etl-rerun-1760000000", req.TargetName(now))
+	req.Name = tea.String(strings.Repeat("a", 60))
+	assert.Len(t, req.TargetName(now), 63)
+	req.Name = tea.String("spark-etl")
+	req.ReuseName = true
+	assert.Equal(t, "spark-etl", req.TargetName(now))
+
+	req.NewName = tea.String("spark-etl-copy")
+	assert.ErrorContains(t, req.Validate(), "can not be set at the same time")
+	req.ReuseName = false
+	assert.Equal(t, "spark-etl-copy", req.TargetName(now))
+	req.NewName = tea.String("spark-etl")
+	assert.ErrorContains(t, req.Validate(), "use reuse_name")
+	req.NewName = nil
+	req.Driver = &model.SparkResourceOverride{Instances: tea.Int32(2)}
+	assert.ErrorContains(t, req.Validate(), "driver.instances is not supported")
+	req.Driver = nil
+	assert.Equal(t, 2*time.Minute, req.GetTimeout())
+	req.Timeout = tea.String("5m")
+	assert.NoError(t, req.Validate())
+	assert.Equal(t, 5*time.Minute, req.GetTimeout())
+	req.Timeout = tea.String("-1m")
+	assert.ErrorContains(t, req.Validate(), "timeout must be positive")
+}
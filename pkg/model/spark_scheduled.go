@@ -0,0 +1,151 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// https://github.com/kubeflow/spark-operator/blob/master/docs/api-docs.md#scheduledsparkapplication
+
+const (
+	ConcurrencyAllow   = "Allow"   // 上一次没有结束也会启动新的任务
+	ConcurrencyForbid  = "Forbid"  // 上一次没有结束时跳过本次
+	ConcurrencyReplace = "Replace" // 停止上一次的任务再启动
+
+	// operator 给定时任务创建的 SparkApplication 打上的标签
+	ScheduledSparkAppNameLabel = "sparkoperator.k8s.io/scheduled-app-name"
+)
+
+// CreateScheduledSparkApplicationRequest 定时任务，每次运行按 CreateSparkApplicationRequest 生成 SparkApplication
+type CreateScheduledSparkApplicationRequest struct {
+	CreateSparkApplicationRequest
+	Schedule                  *string `json:"schedule" binding:"required"`              // cron 表达式 "0 2 * * *" 或者 "@every 1h"、"@daily"
+	ConcurrencyPolicy         *string `json:"concurrency_policy" default:"Forbid"`      // Allow、Forbid、Replace
+	Suspend                   *bool   `json:"suspend"`                                  // 为空时新建的任务不暂停，已有的任务保持当前状态
+	SuccessfulRunHistoryLimit *int32  `json:"successful_run_history_limit" default:"1"` // 保留成功的 SparkApplication 数量
+	FailedRunHistoryLimit     *int32  `json:"failed_run_history_limit" default:"1"`     // 保留失败的 SparkApplication 数量
+}
+
+// Validate 和模板的错误一起返回
+func (req *CreateScheduledSparkApplicationRequest) Validate() error {
+	var errs []error
+	if req.Schedule == nil || *req.Schedule == "" {
+		errs = append(errs, fmt.Errorf("schedule is required"))
+	} else if !strings.HasPrefix(*req.Schedule, "@") && len(strings.Fields(*req.Schedule)) != 5 {
+		// operator 使用 robfig/cron 解析，这里只检查格式
+		errs = append(errs, fmt.Errorf("schedule %q must be a 5-field cron expression or a descriptor like @daily, @every 1h", *req.Schedule))
+	}
+	if req.ConcurrencyPolicy != nil {
+		switch *req.ConcurrencyPolicy {
+		case ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace:
+		default:
+			errs = append(errs, fmt.Errorf("concurrency_policy must be one of %s, %s, %s", ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace))
+		}
+	}
+	if req.SuccessfulRunHistoryLimit != nil && *req.SuccessfulRunHistoryLimit < 0 {
+		errs = append(errs, fmt.Errorf("successful_run_history_limit must not be negative"))
+	}
+	if req.FailedRunHistoryLimit != nil && *req.FailedRunHistoryLimit < 0 {
+		errs = append(errs, fmt.Errorf("failed_run_history_limit must not be negative"))
+	}
+	errs = append(errs, req.CreateSparkApplicationRequest.Validate())
+	return errors.Join(errs...)
+}
+
+func (req *CreateScheduledSparkApplicationRequest) ToYaml() map[string]any {
+	app := req.CreateSparkApplicationRequest.ToYaml()
+	spec := map[string]any{
+		"schedule":                  *req.Schedule,
+		"concurrencyPolicy":         ConcurrencyForbid,
+		"successfulRunHistoryLimit": 1,
+		"failedRunHistoryLimit":     1,
+		"template":                  app["spec"],
+	}
+	if req.ConcurrencyPolicy != nil {
+		spec["concurrencyPolicy"] = *req.ConcurrencyPolicy
+	}
+	if req.Suspend != nil {
+		spec["suspend"] = *req.Suspend
+	}
+	if req.SuccessfulRunHistoryLimit != nil {
+		spec["successfulRunHistoryLimit"] = *req.SuccessfulRunHistoryLimit
+	}
+	if req.FailedRunHistoryLimit != nil {
+		spec["failedRunHistoryLimit"] = *req.FailedRunHistoryLimit
+	}
+	return map[string]any{
+		"apiVersion": app["apiVersion"],
+		"kind":       "ScheduledSparkApplication",
+		"metadata":   app["metadata"],
+		"spec":       spec,
+	}
+}
+
+type CrdScheduledSparkApplicationGetResponse struct {
+	Items []CrdScheduledSparkApplication `json:"items"`
+	Total int                            `json:"total"`
+}
+
+type CrdScheduledSparkApplication struct {
+	Name                   string         `json:"name"`
+	Namespace              string         `json:"namespace"`
+	Schedule               string         `json:"schedule"`
+	ConcurrencyPolicy      string         `json:"concurrency_policy"`
+	Suspend                bool           `json:"suspend"`
+	State                  string         `json:"state"`            // New、Validating、Scheduled、FailedValidation
+	Reason                 string         `json:"reason,omitempty"` // FailedValidation 的原因
+	CreateTime             time.Time      `json:"create_time"`
+	LastRun                *time.Time     `json:"last_run"`       // 没有运行过时为 null
+	LastRunName            string         `json:"last_run_name"`  // 最后一次运行的 SparkApplication
+	LastRunState           string         `json:"last_run_state"` // 最后一次运行的状态，已经被清理时为空
+	NextRun                *time.Time     `json:"next_run"`       // 暂停时为 null
+	PastSuccessfulRunNames []string       `json:"past_successful_run_names"`
+	PastFailedRunNames     []string       `json:"past_failed_run_names"`
+	Template               map[string]any `json:"template,omitempty"` // SparkApplication spec，只有查询详情时返回
+}
+
+type scheduledSparkApplicationStatus struct {
+	ScheduleState          string   `json:"scheduleState"`
+	Reason                 string   `json:"reason"`
+	LastRun                string   `json:"lastRun"`
+	NextRun                string   `json:"nextRun"`
+	LastRunName            string   `json:"lastRunName"`
+	PastSuccessfulRunNames []string `json:"pastSuccessfulRunNames"`
+	PastFailedRunNames     []string `json:"pastFailedRunNames"`
+}
+
+// NewCrdScheduledSparkApplication 解析 ScheduledSparkApplication，缺少或者格式错误的字段为零值
+func NewCrdScheduledSparkApplication(item unstructured.Unstructured) CrdScheduledSparkApplication {
+	app := CrdScheduledSparkApplication{
+		Name:       item.GetName(),
+		Namespace:  item.GetNamespace(),
+		State:      "New",
+		CreateTime: item.GetCreationTimestamp().Time,
+	}
+	app.Schedule, _, _ = unstructured.NestedString(item.Object, "spec", "schedule")
+	app.ConcurrencyPolicy, _, _ = unstructured.NestedString(item.Object, "spec", "concurrencyPolicy")
+	app.Suspend, _, _ = unstructured.NestedBool(item.Object, "spec", "suspend")
+	var status scheduledSparkApplicationStatus
+	if raw, ok := item.Object["status"]; ok && raw != nil {
+		if data, err := json.Marshal(raw); err == nil {
+			json.Unmarshal(data, &status)
+		}
+	}
+	if status.ScheduleState != "" {
+		app.State = status.ScheduleState
+	}
+	app.Reason = status.Reason
+	app.LastRun = parseStatusTime(status.LastRun)
+	app.LastRunName = status.LastRunName
+	if !app.Suspend {
+		app.NextRun = parseStatusTime(status.NextRun)
+	}
+	app.PastSuccessfulRunNames = status.PastSuccessfulRunNames
+	app.PastFailedRunNames = status.PastFailedRunNames
+	return app
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// https://github.com/kubeflow/spark-operator/blob/master/docs/api-docs.md#sparkapplicationstatus
+
+// SparkApplicationState spark operator 的 applicationState.state
+const (
+	SparkStateNew              = "NEW" // 还没有被 operator 处理，status 为空
+	SparkStateSubmitted        = "SUBMITTED"
+	SparkStateRunning          = "RUNNING"
+	SparkStateCompleted        = "COMPLETED"
+	SparkStateFailed           = "FAILED"
+	SparkStateSubmissionFailed = "SUBMISSION_FAILED"
+	SparkStatePendingRerun     = "PENDING_RERUN"
+	SparkStateInvalidating     = "INVALIDATING"
+	SparkStateSucceeding       = "SUCCEEDING"
+	SparkStateFailing          = "FAILING"
+	SparkStateUnknown          = "UNKNOWN"
+)
+
+type SparkDriverInfo struct {
+	PodName             string `json:"pod_name"`
+	WebUIServiceName    string `json:"web_ui_service_name"`
+	WebUIAddress        string `json:"web_ui_address"` // ClusterIP:port
+	WebUIPort           int32  `json:"web_ui_port"`
+	WebUIIngressName    string `json:"web_ui_ingress_name"`
+	WebUIIngressAddress string `json:"web_ui_ingress_address"`
+}
+
+// sparkDriverInfo operator 的 status.driverInfo
+type sparkDriverInfo struct {
+	PodName             string `json:"podName"`
+	WebUIServiceName    string `json:"webUIServiceName"`
+	WebUIAddress        string `json:"webUIAddress"`
+	WebUIPort           int32  `json:"webUIPort"`
+	WebUIIngressName    string `json:"webUIIngressName"`
+	WebUIIngressAddress string `json:"webUIIngressAddress"`
+}
+
+// sparkApplicationStatus 时间字段用字符串解析，operator 未设置时可能是 null 或者空字符串
+type sparkApplicationStatus struct {
+	SparkApplicationID        string          `json:"sparkApplicationId"`
+	SubmissionID              string          `json:"submissionID"`
+	LastSubmissionAttemptTime string          `json:"lastSubmissionAttemptTime"`
+	TerminationTime           string          `json:"terminationTime"`
+	DriverInfo                sparkDriverInfo `json:"driverInfo"`
+	AppState                  struct {
+		State        string `json:"state"`
+		ErrorMessage string `json:"errorMessage"`
+	} `json:"applicationState"`
+	ExecutorState      map[string]string `json:"executorState"`
+	ExecutionAttempts  int64             `json:"executionAttempts"`
+	SubmissionAttempts int64             `json:"submissionAttempts"`
+}
+
+// NewCrdSparkApplication 解析 SparkApplication，刚提交或者运行中的任务 status 不完整，缺少或者格式错误的字段为零值
+func NewCrdSparkApplication(item unstructured.Unstructured) CrdSparkApplication {
+	app := CrdSparkApplication{
+		Name:            item.GetName(),
+		Namespace:       item.GetNamespace(),
+		Status:          SparkStateNew,
+		CreateTime:      item.GetCreationTimestamp().Time,
+		Age:             time.Since(item.GetCreationTimestamp().Time).Round(time.Second).String(),
+		Executors:       map[string]string{},
+		ExecutorSummary: map[string]int{},
+	}
+	raw, ok := item.Object["status"]
+	if !ok || raw == nil {
+		return app
+	}
+	var status sparkApplicationStatus
+	// 类型不匹配的字段跳过，其他字段照常解析
+	if data, err := json.Marshal(raw); err == nil {
+		json.Unmarshal(data, &status)
+	}
+	if status.AppState.State != "" {
+		app.Status = status.AppState.State
+	}
+	app.ErrorMessage = status.AppState.ErrorMessage
+	app.ApplicationID = status.SparkApplicationID
+	app.SubmissionID = status.SubmissionID
+	app.Attempts = status.ExecutionAttempts
+	app.SubmissionAttempts = status.SubmissionAttempts
+	app.Driver = SparkDriverInfo(status.DriverInfo)
+	app.StartTime = parseStatusTime(status.LastSubmissionAttemptTime)
+	app.FinishTime = parseStatusTime(status.TerminationTime)
+	for pod, state := range status.ExecutorState {
+		app.Executors[pod] = state
+		app.ExecutorSummary[state]++
+	}
+	return app
+}
+
+// parseStatusTime 空值、零值和格式错误返回 nil
+func parseStatusTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil || t.Unix() <= 0 {
+		return nil
+	}
+	return &t
+}
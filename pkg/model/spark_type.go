@@ -0,0 +1,169 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// spec.type，Python 和 R 不需要 mainClass
+const (
+	SparkTypeScala  = "Scala"
+	SparkTypeJava   = "Java"
+	SparkTypePython = "Python"
+	SparkTypeR      = "R"
+)
+
+const (
+	sparkDefaultImage   = "apache/spark-py:v3.2.1"
+	sparkDefaultVersion = "3.2.1"
+)
+
+// mainApplicationFile 支持的地址，s3 需要镜像中带 hadoop-aws
+var sparkMainFileSchemes = []string{"local://", "http://", "https://", "s3://", "s3a://"}
+
+var (
+	sparkVersionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+	// 镜像 tag 开头的版本号，比如 v3.2.1、3.5.0-scala2.12-java11-python3-ubuntu
+	sparkImageTagVersion = regexp.MustCompile(`^v?(\d+\.\d+\.\d+)`)
+)
+
+// 每种类型 mainApplicationFile 的扩展名
+var sparkMainFileExtensions = map[string][]string{
+	SparkTypeScala:  {".jar"},
+	SparkTypeJava:   {".jar"},
+	SparkTypePython: {".py", ".zip", ".egg"},
+	SparkTypeR:      {".r", ".R"},
+}
+
+func (req *CreateSparkApplicationRequest) sparkType() string {
+	if req.Type != nil {
+		return *req.Type
+	}
+	return SparkTypeScala
+}
+
+// sparkVersion 没有指定时使用镜像 tag 中的版本，镜像 tag 没有版本时为 3.2.1
+func (req *CreateSparkApplicationRequest) sparkVersion() string {
+	if req.SparkVersion != nil {
+		return *req.SparkVersion
+	}
+	if req.Image != nil {
+		if version := sparkImageVersion(*req.Image); version != "" {
+			return version
+		}
+	}
+	return sparkDefaultVersion
+}
+
+// sparkImageVersion 镜像 tag 中的 spark 版本，没有 tag、使用 digest 或者 tag 不是版本号时为空
+func sparkImageVersion(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	if match := sparkImageTagVersion.FindStringSubmatch(image[i+1:]); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// validateApplication 校验类型、mainClass、mainApplicationFile、pythonVersion 以及镜像和 sparkVersion 是否一致
+func (req *CreateSparkApplicationRequest) validateApplication() []error {
+	var errs []error
+	appType := req.sparkType()
+	extensions, ok := sparkMainFileExtensions[appType]
+	if !ok {
+		errs = append(errs, fmt.Errorf("type must be one of %s, %s, %s, %s", SparkTypeScala, SparkTypeJava, SparkTypePython, SparkTypeR))
+	}
+
+	isJVM := appType == SparkTypeScala || appType == SparkTypeJava
+	if !isJVM && req.MainClass != nil {
+		errs = append(errs, fmt.Errorf("main_class is not supported for %s application", appType))
+	}
+	if appType != SparkTypePython && req.PythonVersion != nil {
+		errs = append(errs, fmt.Errorf("python_version is only supported for Python application"))
+	}
+	// 默认的 mainClass 和 mainApplicationFile 是 SparkPi 示例，只能一起使用
+	if req.MainApplicationFile == nil {
+		if ok && !isJVM {
+			errs = append(errs, fmt.Errorf("main_application_file is required for %s application", appType))
+		}
+	} else {
+		if isJVM && req.MainClass == nil {
+			errs = append(errs, fmt.Errorf("main_class is required when main_application_file is set"))
+		}
+		errs = append(errs, validateSparkMainFile(*req.MainApplicationFile, extensions)...)
+	}
+
+	version := req.sparkVersion()
+	if !sparkVersionPattern.MatchString(version) {
+		errs = append(errs, fmt.Errorf("spark_version must be like 3.5.0, got %q", version))
+	}
+	if req.PythonVersion != nil {
+		switch *req.PythonVersion {
+		case "3":
+		case "2":
+			// spark 3.1 移除了 python 2 支持
+			var major, minor int
+			fmt.Sscanf(version, "%d.%d", &major, &minor)
+			if major > 3 || (major == 3 && minor >= 1) {
+				errs = append(errs, fmt.Errorf("python_version 2 is not supported by spark %s, requires spark < 3.1.0", version))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("python_version must be 2 or 3"))
+		}
+	}
+
+	images := map[string]*string{"image": req.Image}
+	if req.Image == nil && req.SparkVersion != nil {
+		images["image"] = tea.String(sparkDefaultImage)
+	}
+	if req.Driver != nil {
+		images["driver.image"] = req.Driver.Image
+	}
+	if req.Executor != nil {
+		images["executor.image"] = req.Executor.Image
+	}
+	for _, field := range []string{"image", "driver.image", "executor.image"} {
+		if images[field] == nil {
+			continue
+		}
+		if imageVersion := sparkImageVersion(*images[field]); imageVersion != "" && imageVersion != version {
+			errs = append(errs, fmt.Errorf("%s %s does not match spark_version %s", field, *images[field], version))
+		}
+	}
+	return errs
+}
+
+func validateSparkMainFile(file string, extensions []string) []error {
+	var errs []error
+	scheme := ""
+	for _, s := range sparkMainFileSchemes {
+		if strings.HasPrefix(file, s) {
+			scheme = s
+			break
+		}
+	}
+	if scheme == "" || len(file) == len(scheme) {
+		errs = append(errs, fmt.Errorf("main_application_file %s must start with one of %v", file, sparkMainFileSchemes))
+	}
+	if len(extensions) > 0 {
+		matched := false
+		for _, ext := range extensions {
+			if strings.HasSuffix(file, ext) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Errorf("main_application_file %s must end with one of %v", file, extensions))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// checkBatchScheduler 提交前检查调度器的 CRD 是否安装，volcano 同时检查 queue 是否存在，避免 pod 一直 Pending
+func checkBatchScheduler(io model.K8SIO, scheduler *model.BatchScheduler) error {
+	if scheduler == nil {
+		return nil
+	}
+	for _, gvr := range scheduler.RequiredCRDs() {
+		exists, err := io.CrdExists(gvr.Group, gvr.Version, gvr.Resource)
+		if err != nil {
+			return fmt.Errorf("check crd %s.%s error: %v", gvr.Resource, gvr.Group, err)
+		}
+		if !exists {
+			return fmt.Errorf("batch scheduler %s is not installed, crd %s.%s not found", tea.StringValue(scheduler.Name), gvr.Resource, gvr.Group)
+		}
+	}
+	if tea.StringValue(scheduler.Name) == model.BatchSchedulerVolcano && scheduler.Queue != nil {
+		if _, err := io.VolcanoQueueGet(*scheduler.Queue); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("volcano queue %s not found", *scheduler.Queue)
+			}
+			return fmt.Errorf("get volcano queue %s error: %v", *scheduler.Queue, err)
+		}
+	}
+	return nil
+}
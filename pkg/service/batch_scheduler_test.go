@@ -0,0 +1,89 @@
+package service_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestBatchScheduler(t *testing.T) {
+	installed := false
+	var created []string
+	requests := map[string]string{}
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		requests[r.Method+" "+r.URL.Path] = string(body)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/scheduling.volcano.sh/v1beta1" && installed:
+			fmt.Fprint(w, `{"kind":"APIResourceList","apiVersion":"v1","groupVersion":"scheduling.volcano.sh/v1beta1","resources":[
+				{"name":"queues","singularName":"queue","namespaced":false,"kind":"Queue","verbs":["get","list"]},
+				{"name":"podgroups","singularName":"podgroup","namespaced":true,"kind":"PodGroup","verbs":["get","list","create"]}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/scheduling.volcano.sh/v1beta1/queues/etl":
+			fmt.Fprint(w, `{"apiVersion":"scheduling.volcano.sh/v1beta1","kind":"Queue","metadata":{"name":"etl"}}`)
+		case r.Method == http.MethodPost:
+			created = append(created, r.URL.Path)
+			w.Write(body)
+		case r.Method == http.MethodDelete && r.URL.Path == "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments/wordcount":
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Success"}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/apis/scheduling.volcano.sh/v1beta1/namespaces/flink/podgroups/wordcount-podgroup" && installed:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","message":"podgroups is forbidden","reason":"Forbidden","code":403,"metadata":{}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	spark := model.CreateSparkApplicationRequest{
+		Name:           tea.String("spark-etl"),
+		Namespace:      tea.String("spark"),
+		BatchScheduler: &model.BatchScheduler{Name: tea.String(model.BatchSchedulerVolcano), Queue: tea.String("etl")},
+	}
+	_, err := k8s.CrdSparkApplicationApply("fake", spark)
+	assert.ErrorContains(t, err, "batch scheduler volcano is not installed, crd queues.scheduling.volcano.sh not found")
+	assert.Empty(t, created)
+
+	installed = true
+	_, err = k8s.CrdSparkApplicationApply("fake", spark)
+	assert.NoError(t, err)
+	assert.Contains(t, requests["POST /apis/sparkoperator.k8s.io/v1beta2/namespaces/spark/sparkapplications"], `"batchScheduler":"volcano"`)
+
+	spark.BatchScheduler.Queue = tea.String("adhoc")
+	_, err = k8s.CrdSparkApplicationApply("fake", spark)
+	assert.ErrorContains(t, err, "volcano queue adhoc not found")
+
+	// yunikorn 不依赖 CRD
+	installed = false
+	spark.BatchScheduler = &model.BatchScheduler{Name: tea.String(model.BatchSchedulerYuniKorn), Queue: tea.String("root.etl")}
+	_, err = k8s.CrdSparkApplicationApply("fake", spark)
+	assert.NoError(t, err)
+
+	// PodGroup 先于 FlinkDeployment 创建
+	installed, created = true, nil
+	_, err = k8s.CrdFlinkDeploymentApply("fake", model.CreateFlinkClusterRequest{
+		ClusterName:    tea.String("wordcount"),
+		NameSpace:      tea.String("flink"),
+		Submitter:      tea.String("alice"),
+		BatchScheduler: &model.BatchScheduler{Name: tea.String(model.BatchSchedulerVolcano), Queue: tea.String("etl"), Gang: &model.GangScheduling{MinMember: tea.Int32(3)}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/apis/scheduling.volcano.sh/v1beta1/namespaces/flink/podgroups",
+		"/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments",
+	}, created)
+	assert.Contains(t, requests["POST /apis/scheduling.volcano.sh/v1beta1/namespaces/flink/podgroups"], `"minMember":3`)
+	assert.Contains(t, requests["POST /apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments"], `"schedulerName":"volcano"`)
+
+	// 删除 PodGroup 只忽略 NotFound
+	deleteReq := model.DeleteFlinkClusterRequest{ClusterName: tea.String("wordcount"), NameSpace: tea.String("flink")}
+	err = k8s.CrdFlinkDeploymentDelete("fake", deleteReq)
+	assert.ErrorContains(t, err, "delete volcano podgroup wordcount-podgroup error: podgroups is forbidden")
+	installed = false
+	assert.NoError(t, k8s.CrdFlinkDeploymentDelete("fake", deleteReq))
+}
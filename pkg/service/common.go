@@ -2,11 +2,14 @@ package service
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/xops-infra/multi-k8s-client/pkg/io"
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type K8SService struct {
@@ -39,14 +42,31 @@ func (s *K8SService) GetK8SCluster() []model.ClusterInfo {
 }
 
 // 因为 JM 是单副本，所以支持的只有 TM副本调整
+// operator 集群通过 FlinkDeployment spec 调整，v1.12 集群直接调整 -taskmanager deployment
 func (s *K8SService) CrdFlinkTMScale(k8sClusterName string, req model.CrdFlinkTMScaleRequest) error {
 	if io, ok := s.IOs[k8sClusterName]; ok {
-		_, err := io.DeploymentScale(tea.StringValue(req.NameSpace), fmt.Sprintf(model.TaskManagerDeploymentName, *req.ClusterName), *req.Replicas)
+		item, err := getFlinkDeployment(io, tea.StringValue(req.NameSpace), *req.ClusterName)
+		if err != nil {
+			return err
+		}
+		if item != nil {
+			patch, err := req.ToPatch(*item)
+			if err != nil {
+				return err
+			}
+			_, err = io.CrdFlinkDeploymentPatch(tea.StringValue(req.NameSpace), *req.ClusterName, patch)
+			return err
+		}
+		if req.Replicas == nil {
+			return fmt.Errorf("replicas is required")
+		}
+		_, err = io.DeploymentScale(tea.StringValue(req.NameSpace), fmt.Sprintf(model.TaskManagerDeploymentName, *req.ClusterName), *req.Replicas)
 		return err
 	}
 	return fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
 }
 
+// operator 集群通过递增 spec.restartNonce 重启，JM 和 TM 会一起重建，只支持 type ALL；v1.12 集群按 type 重启 deployment
 func (s *K8SService) CrdFlinkDeploymentRestart(k8sClusterName string, req model.RestartFlinkClusterRequest) error {
 	if io, ok := s.IOs[k8sClusterName]; ok {
 		var deploymentName []string
@@ -61,6 +81,17 @@ func (s *K8SService) CrdFlinkDeploymentRestart(k8sClusterName string, req model.
 		default:
 			return fmt.Errorf("type not found, only support ALL, TM, JM")
 		}
+		item, err := getFlinkDeployment(io, tea.StringValue(req.NameSpace), *req.ClusterName)
+		if err != nil {
+			return err
+		}
+		if item != nil {
+			if req.Type != model.FlinkTypeALL {
+				return fmt.Errorf("operator cluster %s only supports restart type ALL, JM and TM are restarted together", *req.ClusterName)
+			}
+			_, err = io.CrdFlinkDeploymentPatch(tea.StringValue(req.NameSpace), *req.ClusterName, model.NewRestartPatch(*item))
+			return err
+		}
 		for _, name := range deploymentName {
 			_, err := io.DeploymentRestart(tea.StringValue(req.NameSpace), name)
 			if err != nil {
@@ -73,6 +104,18 @@ func (s *K8SService) CrdFlinkDeploymentRestart(k8sClusterName string, req model.
 	return fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
 }
 
+// getFlinkDeployment 不存在或者集群没有安装 operator 时返回 nil，表示 v1.12 集群
+func getFlinkDeployment(io model.K8SIO, namespace, name string) (*unstructured.Unstructured, error) {
+	item, err := io.CrdFlinkDeploymentGet(namespace, name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
 func (s *K8SService) CrdFlinkDeploymentList(k8sClusterName string, filter model.Filter) (model.CrdFlinkDeploymentGetResponse, error) {
 	if io, ok := s.IOs[k8sClusterName]; ok {
 		resp, err := io.CrdFlinkDeploymentList(filter)
@@ -80,6 +123,7 @@ func (s *K8SService) CrdFlinkDeploymentList(k8sClusterName string, filter model.
 			return model.CrdFlinkDeploymentGetResponse{}, err
 		}
 		var items []model.CrdFlinkDeployment
+		indexes := newEndpointIndexes(io)
 		for _, item := range resp.Items {
 			info := model.GetInfoFromItem(item)
 			// 因为 opertor是动态任务，所以不知道他的他 TM 数量，这里通过 查询pod labels app=clusterName &component=jobmanager 获取数量
@@ -107,17 +151,19 @@ func (s *K8SService) CrdFlinkDeploymentList(k8sClusterName string, filter model.
 					v.Status = jobStatus
 				}
 			}
-			// 增加 LoadBlance 连接信息
-			lbResp, err := io.ServiceList(model.Filter{
-				NameSpace:     tea.String(item.GetNamespace()),
-				FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", fmt.Sprintf(model.JobManagerLBServiceName, item.GetName()))), // app-session-jobmanager-lb-service
-			})
-			if err != nil {
-				return model.CrdFlinkDeploymentGetResponse{}, err
-			}
-			for k, item := range lbResp.Items {
-				v.Status.(map[string]any)[fmt.Sprintf("loadbalance-%d", k)] = fmt.Sprintf("%s:%d", item.Status.LoadBalancer.Ingress[0].IP, item.Spec.Ports[0].Port) // 可以去掉，兼容需要保留
-				v.LoadBalancer[fmt.Sprintf("loadbalance-%d", k)] = fmt.Sprintf("%s:%d", item.Status.LoadBalancer.Ingress[0].IP, item.Spec.Ports[0].Port)
+			// 增加 LoadBlance 和 ingress 连接信息，查询失败时忽略，和 FlinkV12ClusterList 保持一致
+			if index, err := indexes.get(item.GetNamespace()); err == nil {
+				endpoints := index.resolve("", []string{
+					fmt.Sprintf(model.JobManagerLBServiceName, item.GetName()), // app-session-jobmanager-lb-service
+					fmt.Sprintf(model.RestServiceName, item.GetName()),
+				}, item.GetName())
+				v.Endpoints = endpoints
+				v.LoadBalancer = loadBalancerMap(endpoints)
+				for k, address := range v.LoadBalancer {
+					if strings.HasPrefix(k, "loadbalance-") {
+						v.Status.(map[string]any)[k] = address // 可以去掉，兼容需要保留
+					}
+				}
 			}
 
 			items = append(items, v)
@@ -133,6 +179,22 @@ func (s *K8SService) CrdFlinkDeploymentList(k8sClusterName string, filter model.
 func (s *K8SService) CrdFlinkDeploymentApply(k8sCluster string, req model.CreateFlinkClusterRequest) (model.CreateResponse, error) {
 	if io, ok := s.IOs[k8sCluster]; ok {
 		var response model.CreateResponse
+		// 日志 sidecar 的配置需要先于 FlinkDeployment 创建
+		if logConfigMap := req.NewLogConfigMap(); logConfigMap != nil {
+			_, err := io.ConfigMapApply(*logConfigMap)
+			if err != nil {
+				return model.CreateResponse{}, fmt.Errorf("log configmap apply error: %v", err)
+			}
+		}
+		if err := checkBatchScheduler(io, req.BatchScheduler); err != nil {
+			return model.CreateResponse{}, err
+		}
+		// gang 调度的 PodGroup 需要先于 JM/TM pod 创建
+		if podGroup := req.NewVolcanoPodGroup(); podGroup != nil {
+			if _, err := io.VolcanoPodGroupApply(podGroup); err != nil {
+				return model.CreateResponse{}, fmt.Errorf("volcano podgroup apply error: %v", err)
+			}
+		}
 		_, err := io.CrdFlinkDeploymentApply(req.ToYaml())
 		if err != nil {
 			return model.CreateResponse{}, err
@@ -140,8 +202,7 @@ func (s *K8SService) CrdFlinkDeploymentApply(k8sCluster string, req model.Create
 		response.Info = "create FlinkDeployment success!"
 		if req.LoadBalancer != nil {
 			// 创建 loadbalancer
-			LBServiceYaml := req.NewLBService()
-			_, err := io.ServiceApply(LBServiceYaml)
+			_, err := applyLBService(io, req.LoadBalancer, req.NewLBService)
 			if err != nil {
 				return model.CreateResponse{}, err
 			}
@@ -161,6 +222,13 @@ func (s *K8SService) CrdFlinkDeploymentDelete(k8sClusterName string, req model.D
 		}
 		// 删除 service
 		io.ServiceDelete(tea.StringValue(req.NameSpace), fmt.Sprintf(model.JobManagerLBServiceName, *req.ClusterName))
+		// 删除日志 sidecar 配置，不存在则忽略
+		io.ConfigMapDelete(tea.StringValue(req.NameSpace), fmt.Sprintf(model.LogConfigMapName, *req.ClusterName))
+		// 删除 gang 调度的 PodGroup，没有使用 volcano 或者没有安装 CRD 时返回 NotFound
+		podGroup := fmt.Sprintf(model.VolcanoPodGroupName, *req.ClusterName)
+		if err := io.VolcanoPodGroupDelete(tea.StringValue(req.NameSpace), podGroup); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete volcano podgroup %s error: %v", podGroup, err)
+		}
 
 		return nil
 	}
@@ -232,18 +300,9 @@ func (s *K8SService) CrdSparkApplicationList(k8sClusterName string, filter model
 		if err != nil {
 			return model.CrdSparkApplicationGetResponse{}, err
 		}
-		var items []model.CrdSparkApplication
+		items := []model.CrdSparkApplication{}
 		for _, item := range resp.Items {
-			// fmt.Println(tea.Prettify(item))
-			items = append(items, model.CrdSparkApplication{
-				Name:       item.GetName(),
-				Namespace:  item.GetNamespace(),
-				Status:     item.Object["status"].(map[string]any)["applicationState"].(map[string]any)["state"].(string),
-				Attempts:   item.Object["status"].(map[string]any)["executionAttempts"].(int64),
-				StartTime:  item.Object["status"].(map[string]any)["lastSubmissionAttemptTime"].(string),
-				FinishTime: item.Object["status"].(map[string]any)["terminationTime"].(string),
-				Age:        time.Since(item.GetCreationTimestamp().Time).String(),
-			})
+			items = append(items, model.NewCrdSparkApplication(item))
 		}
 		return model.CrdSparkApplicationGetResponse{
 			Total: len(resp.Items),
@@ -266,13 +325,15 @@ func (s *K8SService) CrdSparkApplicationGet(k8sClusterName, namespace, name stri
 			return model.CrdResourceDetail{}, fmt.Errorf("spark application not found")
 		}
 		item := resp.Items[0]
+		// 刚提交的任务还没有 status
+		status, _, _ := unstructured.NestedMap(item.Object, "status")
 		return model.CrdResourceDetail{
 			Kind:       item.GetObjectKind().GroupVersionKind().Kind,
 			ApiVersion: item.GetObjectKind().GroupVersionKind().GroupVersion().String(),
 			Name:       item.GetName(),
 			Metadata:   item.Object["metadata"].(map[string]any),
 			Spec:       item.Object["spec"].(map[string]any),
-			Status:     item.Object["status"].(map[string]any),
+			Status:     status,
 		}, nil
 	}
 	return model.CrdResourceDetail{}, fmt.Errorf("cluster not found")
@@ -280,6 +341,12 @@ func (s *K8SService) CrdSparkApplicationGet(k8sClusterName, namespace, name stri
 
 func (s *K8SService) CrdSparkApplicationApply(k8sClusterName string, req model.CreateSparkApplicationRequest) (model.CreateResponse, error) {
 	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := req.Validate(); err != nil {
+			return model.CreateResponse{}, err
+		}
+		if err := checkBatchScheduler(io, req.BatchScheduler); err != nil {
+			return model.CreateResponse{}, err
+		}
 		resp, err := io.CrdSparkApplicationApply(req.ToYaml())
 		if err != nil {
 			return model.CreateResponse{}, err
@@ -298,3 +365,33 @@ func (s *K8SService) CrdSparkApplicationDelete(k8sClusterName string, req model.
 	}
 	return fmt.Errorf("cluster not found")
 }
+
+// CrdSparkApplicationCleanup 删除结束超过 older_than 的任务，单个删除失败不影响其他任务，失败原因记录在 Failed
+func (s *K8SService) CrdSparkApplicationCleanup(k8sClusterName string, req model.CleanupSparkApplicationRequest) (model.CleanupSparkApplicationResponse, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := req.Validate(); err != nil {
+			return model.CleanupSparkApplicationResponse{}, err
+		}
+		resp, err := io.CrdSparkApplicationList(model.Filter{NameSpace: req.Namespace, LabelSelector: req.LabelSelector})
+		if err != nil {
+			return model.CleanupSparkApplicationResponse{}, err
+		}
+		result := model.CleanupSparkApplicationResponse{DryRun: req.DryRun, Deleted: []string{}, Failed: map[string]string{}}
+		now := time.Now()
+		for _, item := range resp.Items {
+			app := model.NewCrdSparkApplication(item)
+			if !req.Expired(app, now) {
+				continue
+			}
+			if !req.DryRun {
+				if err := io.CrdSparkApplicationDelete(app.Namespace, app.Name); err != nil && !apierrors.IsNotFound(err) {
+					result.Failed[app.Name] = err.Error()
+					continue
+				}
+			}
+			result.Deleted = append(result.Deleted, app.Name)
+		}
+		return result, nil
+	}
+	return model.CleanupSparkApplicationResponse{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
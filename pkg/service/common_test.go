@@ -2,13 +2,16 @@ package service_test
 
 import (
 	"fmt"
+	"io"
 	"math/rand"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
 	"github.com/xops-infra/multi-k8s-client/pkg/service"
 )
@@ -266,3 +269,120 @@ func TestCrdFlinkTMScale(t *testing.T) {
 	}
 	t.Log("success")
 }
+
+func TestCrdFlinkScaleAndRestartRouting(t *testing.T) {
+	var patches = map[string]string{}
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments/standalone":
+			fmt.Fprint(w, `{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"standalone","namespace":"flink"},"spec":{"mode":"standalone","restartNonce":2,"taskManager":{"replicas":1}}}`)
+		case "PATCH /apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments/standalone",
+			"PATCH /apis/apps/v1/namespaces/flink/deployments/v12-taskmanager",
+			"PATCH /apis/apps/v1/namespaces/flink/deployments/v12-jobmanager":
+			patches[r.URL.Path] = string(body)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Success","metadata":{}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	err := k8s.CrdFlinkTMScale("fake", model.CrdFlinkTMScaleRequest{ClusterName: tea.String("standalone"), NameSpace: tea.String("flink"), Replicas: tea.Int32(3)})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"spec":{"taskManager":{"replicas":3}}}`, patches["/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments/standalone"])
+
+	// operator 集群重启会同时重建 JM 和 TM，不支持单独重启
+	err = k8s.CrdFlinkDeploymentRestart("fake", model.RestartFlinkClusterRequest{ClusterName: tea.String("standalone"), NameSpace: tea.String("flink"), Type: model.FlinkTypeTM})
+	assert.ErrorContains(t, err, "only supports restart type ALL")
+	assert.JSONEq(t, `{"spec":{"taskManager":{"replicas":3}}}`, patches["/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments/standalone"])
+
+	err = k8s.CrdFlinkDeploymentRestart("fake", model.RestartFlinkClusterRequest{ClusterName: tea.String("standalone"), NameSpace: tea.String("flink"), Type: model.FlinkTypeALL})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"spec":{"restartNonce":3}}`, patches["/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments/standalone"])
+
+	// 没有 FlinkDeployment 的是 v1.12 集群，直接调整 deployment
+	err = k8s.CrdFlinkTMScale("fake", model.CrdFlinkTMScaleRequest{ClusterName: tea.String("v12"), NameSpace: tea.String("flink"), Replicas: tea.Int32(3)})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"spec":{"replicas":3}}`, patches["/apis/apps/v1/namespaces/flink/deployments/v12-taskmanager"])
+
+	err = k8s.CrdFlinkDeploymentRestart("fake", model.RestartFlinkClusterRequest{ClusterName: tea.String("v12"), NameSpace: tea.String("flink"), Type: model.FlinkTypeJM})
+	assert.NoError(t, err)
+	assert.Contains(t, patches["/apis/apps/v1/namespaces/flink/deployments/v12-jobmanager"], "kubectl.kubernetes.io/restartedAt")
+}
+
+// 刚提交和运行中的任务缺少 status 字段时不能 panic
+func TestCrdSparkApplicationListStatus(t *testing.T) {
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/apis/sparkoperator.k8s.io/v1beta2/namespaces/spark/sparkapplications" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+			return
+		}
+		items := []string{
+			`{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplication","metadata":{"name":"new","namespace":"spark"},"spec":{}}`,
+			`{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplication","metadata":{"name":"running","namespace":"spark"},"spec":{},
+			 "status":{"applicationState":{"state":"RUNNING"},"lastSubmissionAttemptTime":"2026-10-01T00:01:00Z","executorState":{"running-exec-1":"RUNNING"}}}`,
+		}
+		if r.URL.Query().Get("fieldSelector") == "metadata.name=new" {
+			items = items[:1]
+		}
+		fmt.Fprintf(w, `{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplicationList","metadata":{},"items":[%s]}`, strings.Join(items, ","))
+	})
+	resp, err := k8s.CrdSparkApplicationList("fake", model.Filter{NameSpace: tea.String("spark")})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, model.SparkStateNew, resp.Items[0].Status)
+	assert.Equal(t, model.SparkStateRunning, resp.Items[1].Status)
+	assert.Nil(t, resp.Items[1].FinishTime)
+	assert.Equal(t, 1, resp.Items[1].ExecutorSummary["RUNNING"])
+
+	detail, err := k8s.CrdSparkApplicationGet("fake", "spark", "new")
+	assert.NoError(t, err)
+	assert.Nil(t, detail.Status)
+}
+
+func TestCrdSparkApplicationCleanup(t *testing.T) {
+	const apps = "/apis/sparkoperator.k8s.io/v1beta2/namespaces/spark/sparkapplications"
+	var deleted []string
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == apps:
+			assert.Equal(t, "team=data", r.URL.Query().Get("labelSelector"))
+			item := `{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplication","metadata":{"name":"%s","namespace":"spark","creationTimestamp":"2026-01-01T00:00:00Z"},"status":{"applicationState":{"state":"%s"},"terminationTime":"%s"}}`
+			fmt.Fprintf(w, `{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplicationList","metadata":{},"items":[%s,%s,%s,%s]}`,
+				fmt.Sprintf(item, "old-completed", "COMPLETED", "2026-01-01T01:00:00Z"),
+				fmt.Sprintf(item, "old-failed", "FAILED", "2026-01-01T01:00:00Z"),
+				fmt.Sprintf(item, "running", "RUNNING", ""),
+				fmt.Sprintf(item, "locked", "COMPLETED", "2026-01-01T01:00:00Z"))
+		case r.Method == http.MethodDelete && r.URL.Path == apps+"/locked":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Forbidden","message":"forbidden","code":403,"metadata":{}}`)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, apps+"/"):
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, apps+"/"))
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Success","metadata":{}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	req := model.CleanupSparkApplicationRequest{Namespace: tea.String("spark"), LabelSelector: tea.String("team=data"), OlderThan: tea.String("24h"), DryRun: true}
+	resp, err := k8s.CrdSparkApplicationCleanup("fake", req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old-completed", "old-failed", "locked"}, resp.Deleted)
+	assert.Empty(t, deleted)
+
+	req.DryRun = false
+	resp, err = k8s.CrdSparkApplicationCleanup("fake", req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old-completed", "old-failed"}, resp.Deleted)
+	assert.Equal(t, []string{"old-completed", "old-failed"}, deleted)
+	assert.Contains(t, resp.Failed["locked"], "forbidden")
+
+	_, err = k8s.CrdSparkApplicationCleanup("fake", model.CleanupSparkApplicationRequest{Namespace: tea.String("spark")})
+	assert.ErrorContains(t, err, "older_than is required")
+}
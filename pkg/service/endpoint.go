@@ -0,0 +1,245 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// FlinkClusterEndpoints 汇总 Flink 集群的 service 和 ingress 访问地址，operator 和 v1.12 集群都支持
+func (s *K8SService) FlinkClusterEndpoints(k8sClusterName, namespace, clusterName string) ([]model.Endpoint, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if namespace == "" {
+			namespace = "default"
+		}
+		// operator 创建的 LB service 没有 app 标签，需要按名称查询
+		return resolveEndpoints(io, namespace, []model.Filter{
+			{LabelSelector: tea.String(fmt.Sprintf("app=%s", clusterName))},
+			{FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", fmt.Sprintf(model.JobManagerLBServiceName, clusterName)))},
+		}, clusterName, fmt.Sprintf(model.JobManagerIngressName, clusterName))
+	}
+	return nil, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// CrdSparkApplicationEndpoints spark operator 为 driver UI 创建的 <name>-ui-svc 和 <name>-ui-ingress
+func (s *K8SService) CrdSparkApplicationEndpoints(k8sClusterName, namespace, name string) ([]model.Endpoint, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if namespace == "" {
+			namespace = "default"
+		}
+		return resolveEndpoints(io, namespace, []model.Filter{
+			{FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s-ui-svc", name))},
+		}, fmt.Sprintf("%s-ui-ingress", name))
+	}
+	return nil, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// resolveEndpoints 查询 service 和指定名称的 ingress，多个 filter 查到的 service 去重，ingress 查询失败（比如没有权限）时忽略
+func resolveEndpoints(io model.K8SIO, namespace string, serviceFilters []model.Filter, ingressNames ...string) ([]model.Endpoint, error) {
+	endpoints := []model.Endpoint{}
+	seen := map[string]bool{}
+	for _, filter := range serviceFilters {
+		filter.NameSpace = tea.String(namespace)
+		svcResp, err := io.ServiceList(filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range svcResp.Items {
+			if seen[svc.Name] {
+				continue
+			}
+			seen[svc.Name] = true
+			endpoints = append(endpoints, serviceEndpoints(svc)...)
+		}
+	}
+	for _, name := range ingressNames {
+		ingressResp, err := io.IngressList(model.Filter{
+			NameSpace:     tea.String(namespace),
+			FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", name)),
+		})
+		if err != nil {
+			continue
+		}
+		for _, ingress := range ingressResp.Items {
+			endpoints = append(endpoints, model.IngressEndpoints(ingress)...)
+		}
+	}
+	return endpoints, nil
+}
+
+// endpointIndex 列表接口每个 namespace 只查询一次 service 和 ingress，service 按 app 标签和名称分组，ingress 按名称
+type endpointIndex struct {
+	byApp     map[string][]corev1.Service
+	byName    map[string]corev1.Service
+	ingresses map[string]networkingv1.Ingress
+}
+
+// endpointIndexes 按 namespace 缓存 endpointIndex，service 查询失败也会缓存，同一个 namespace 不再重复查询
+type endpointIndexes struct {
+	io      model.K8SIO
+	indexes map[string]*endpointIndex
+	errs    map[string]error
+}
+
+func newEndpointIndexes(io model.K8SIO) *endpointIndexes {
+	return &endpointIndexes{io: io, indexes: map[string]*endpointIndex{}, errs: map[string]error{}}
+}
+
+// get 第一次访问 namespace 时查询，ingress 查询失败（比如没有权限）时忽略。
+// 访问地址只是补充信息，调用方在返回错误时跳过 endpoints，集群照常返回
+func (x *endpointIndexes) get(namespace string) (*endpointIndex, error) {
+	if index, ok := x.indexes[namespace]; ok {
+		return index, nil
+	}
+	if err, ok := x.errs[namespace]; ok {
+		return nil, err
+	}
+	svcResp, err := x.io.ServiceList(model.Filter{NameSpace: tea.String(namespace)})
+	if err != nil {
+		x.errs[namespace] = err
+		return nil, err
+	}
+	index := &endpointIndex{
+		byApp:     map[string][]corev1.Service{},
+		byName:    map[string]corev1.Service{},
+		ingresses: map[string]networkingv1.Ingress{},
+	}
+	for _, svc := range svcResp.Items {
+		index.byName[svc.Name] = svc
+		if app := svc.Labels["app"]; app != "" {
+			index.byApp[app] = append(index.byApp[app], svc)
+		}
+	}
+	if ingressResp, err := x.io.IngressList(model.Filter{NameSpace: tea.String(namespace)}); err == nil {
+		for _, ingress := range ingressResp.Items {
+			index.ingresses[ingress.Name] = ingress
+		}
+	}
+	x.indexes[namespace] = index
+	return index, nil
+}
+
+// resolve 和 resolveEndpoints 相同：app 为空时只按名称查找 service，service 去重，ingress 按名称查找
+func (x *endpointIndex) resolve(app string, serviceNames []string, ingressNames ...string) []model.Endpoint {
+	endpoints := []model.Endpoint{}
+	seen := map[string]bool{}
+	add := func(svc corev1.Service) {
+		if seen[svc.Name] {
+			return
+		}
+		seen[svc.Name] = true
+		endpoints = append(endpoints, serviceEndpoints(svc)...)
+	}
+	if app != "" {
+		for _, svc := range x.byApp[app] {
+			add(svc)
+		}
+	}
+	for _, name := range serviceNames {
+		if svc, ok := x.byName[name]; ok {
+			add(svc)
+		}
+	}
+	for _, name := range ingressNames {
+		if ingress, ok := x.ingresses[name]; ok {
+			endpoints = append(endpoints, model.IngressEndpoints(ingress)...)
+		}
+	}
+	return endpoints
+}
+
+// servicePort 优先使用 web 端口，否则取第一个端口
+func servicePort(svc corev1.Service) (corev1.ServicePort, bool) {
+	if len(svc.Spec.Ports) == 0 {
+		return corev1.ServicePort{}, false
+	}
+	for _, port := range svc.Spec.Ports {
+		switch port.Name {
+		case "webui", "rest", "spark-driver-ui-port":
+			return port, true
+		}
+	}
+	return svc.Spec.Ports[0], true
+}
+
+// serviceEndpoints LB 未分配地址时返回 Ready=false 的记录，headless service 忽略
+func serviceEndpoints(svc corev1.Service) []model.Endpoint {
+	port, ok := servicePort(svc)
+	if !ok {
+		return nil
+	}
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer:
+		var endpoints []model.Endpoint
+		for _, lb := range svc.Status.LoadBalancer.Ingress {
+			host := lb.IP
+			if host == "" {
+				host = lb.Hostname
+			}
+			if host == "" {
+				continue
+			}
+			endpoints = append(endpoints, model.Endpoint{
+				Type:   model.EndpointLoadBalancer,
+				Name:   svc.Name,
+				Host:   host,
+				Port:   port.Port,
+				Scheme: "http",
+				Ready:  true,
+			})
+		}
+		if len(endpoints) == 0 {
+			endpoints = append(endpoints, model.Endpoint{
+				Type:   model.EndpointLoadBalancer,
+				Name:   svc.Name,
+				Port:   port.Port,
+				Scheme: "http",
+			})
+		}
+		return endpoints
+	case corev1.ServiceTypeNodePort:
+		return []model.Endpoint{{
+			Type:   model.EndpointNodePort,
+			Name:   svc.Name,
+			Port:   port.NodePort,
+			Scheme: "http",
+			Ready:  port.NodePort != 0,
+		}}
+	case corev1.ServiceTypeClusterIP, "":
+		if svc.Spec.ClusterIP == corev1.ClusterIPNone {
+			return nil
+		}
+		return []model.Endpoint{{
+			Type:   model.EndpointClusterIP,
+			Name:   svc.Name,
+			Host:   fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace),
+			Port:   port.Port,
+			Scheme: "http",
+			Ready:  svc.Spec.ClusterIP != "",
+		}}
+	}
+	return nil
+}
+
+// loadBalancerMap 兼容 CrdFlinkDeployment.LoadBalancer，只记录可以访问的 LB 和 ingress 地址
+func loadBalancerMap(endpoints []model.Endpoint) map[string]string {
+	result := map[string]string{}
+	var lb, ingress int
+	for _, endpoint := range endpoints {
+		if !endpoint.Ready {
+			continue
+		}
+		switch endpoint.Type {
+		case model.EndpointLoadBalancer:
+			result[fmt.Sprintf("loadbalance-%d", lb)] = endpoint.Address()
+			lb++
+		case model.EndpointIngress:
+			result[fmt.Sprintf("ingress-%d", ingress)] = endpoint.URL()
+			ingress++
+		}
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package service_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestFlinkEndpoints(t *testing.T) {
+	services := map[string]string{
+		// LB 还没有分配地址
+		"pending-jobmanager-lb-service": `{"metadata":{"name":"pending-jobmanager-lb-service","namespace":"flink"},"spec":{"type":"LoadBalancer","ports":[{"name":"rest","port":31000,"nodePort":31000}]},"status":{"loadBalancer":{}}}`,
+		"aws-jobmanager-lb-service":     `{"metadata":{"name":"aws-jobmanager-lb-service","namespace":"flink"},"spec":{"type":"LoadBalancer","ports":[{"name":"rest","port":31001,"nodePort":31001}]},"status":{"loadBalancer":{"ingress":[{"hostname":"abc.elb.amazonaws.com"}]}}}`,
+		"aws-rest":                      `{"metadata":{"name":"aws-rest","namespace":"flink"},"spec":{"type":"ClusterIP","clusterIP":"10.0.0.1","ports":[{"name":"rest","port":8081}]}}`,
+	}
+	lists := map[string]int{}
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments":
+			fmt.Fprint(w, `{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeploymentList","metadata":{},"items":[
+				{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"pending","namespace":"flink"},"spec":{}},
+				{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"aws","namespace":"flink"},"spec":{}}]}`)
+		case "/api/v1/namespaces/flink/pods":
+			fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","metadata":{},"items":[]}`)
+		case "/api/v1/namespaces/flink/services":
+			lists[r.URL.Path]++
+			var items []string
+			for _, name := range []string{"pending-jobmanager-lb-service", "aws-jobmanager-lb-service", "aws-rest"} {
+				if field := query.Get("fieldSelector"); field == "" || field == "metadata.name="+name {
+					items = append(items, services[name])
+				}
+			}
+			// 这些 service 都没有 app 标签
+			if query.Get("labelSelector") != "" {
+				items = nil
+			}
+			fmt.Fprintf(w, `{"kind":"ServiceList","apiVersion":"v1","metadata":{},"items":[%s]}`, strings.Join(items, ","))
+		case "/apis/networking.k8s.io/v1/namespaces/flink/ingresses":
+			lists[r.URL.Path]++
+			if field := query.Get("fieldSelector"); field == "" || field == "metadata.name=aws" {
+				fmt.Fprint(w, `{"kind":"IngressList","apiVersion":"networking.k8s.io/v1","metadata":{},"items":[{"metadata":{"name":"aws","namespace":"flink"},"spec":{"tls":[{"hosts":["aws.flink.example.com"]}],"rules":[{"host":"aws.flink.example.com","http":{"paths":[{"path":"/","pathType":"Prefix","backend":{"service":{"name":"aws-rest","port":{"number":8081}}}}]}}]},"status":{"loadBalancer":{"ingress":[{"ip":"1.2.3.4"}]}}}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"kind":"IngressList","apiVersion":"networking.k8s.io/v1","metadata":{},"items":[]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	// LB 未就绪不能 panic
+	resp, err := k8s.CrdFlinkDeploymentList("fake", model.Filter{NameSpace: tea.String("flink")})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 2)
+	// 每个 namespace 只查询一次 service 和 ingress
+	assert.Equal(t, map[string]int{"/api/v1/namespaces/flink/services": 1, "/apis/networking.k8s.io/v1/namespaces/flink/ingresses": 1}, lists)
+
+	pending := resp.Items[0]
+	assert.Empty(t, pending.LoadBalancer)
+	assert.Equal(t, []model.Endpoint{{Type: model.EndpointLoadBalancer, Name: "pending-jobmanager-lb-service", Port: 31000, Scheme: "http"}}, pending.Endpoints)
+	_, err = pending.GetWebUrl()
+	assert.Error(t, err)
+
+	aws := resp.Items[1]
+	assert.Equal(t, map[string]string{
+		"loadbalance-0": "abc.elb.amazonaws.com:31001",
+		"ingress-0":     "https://aws.flink.example.com/",
+	}, aws.LoadBalancer)
+	assert.Equal(t, "abc.elb.amazonaws.com:31001", aws.Status.(map[string]any)["loadbalance-0"])
+	assert.Len(t, aws.Endpoints, 3)
+	assert.Equal(t, model.EndpointClusterIP, aws.Endpoints[1].Type)
+	assert.Equal(t, "aws-rest.flink.svc", aws.Endpoints[1].Host)
+
+	endpoints, err := k8s.FlinkClusterEndpoints("fake", "flink", "aws")
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 2)
+	assert.True(t, endpoints[0].Ready)
+	assert.Equal(t, "abc.elb.amazonaws.com", endpoints[0].Host)
+	assert.Equal(t, model.EndpointIngress, endpoints[1].Type)
+	assert.Equal(t, int32(443), endpoints[1].Port)
+}
+
+func TestSparkEndpoints(t *testing.T) {
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/namespaces/spark/services":
+			fmt.Fprint(w, `{"kind":"ServiceList","apiVersion":"v1","metadata":{},"items":[{"metadata":{"name":"pi-ui-svc","namespace":"spark"},"spec":{"type":"NodePort","ports":[{"name":"spark-driver-ui-port","port":4040,"nodePort":30404}]}}]}`)
+		case "/apis/networking.k8s.io/v1/namespaces/spark/ingresses":
+			// ingress 还没有被 controller 处理
+			fmt.Fprint(w, `{"kind":"IngressList","apiVersion":"networking.k8s.io/v1","metadata":{},"items":[{"metadata":{"name":"pi-ui-ingress","namespace":"spark"},"spec":{"rules":[{"http":{"paths":[{"path":"/spark/pi(/|$)(.*)","pathType":"ImplementationSpecific","backend":{"service":{"name":"pi-ui-svc","port":{"number":4040}}}}]}}]},"status":{"loadBalancer":{}}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	endpoints, err := k8s.CrdSparkApplicationEndpoints("fake", "spark", "pi")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Endpoint{
+		{Type: model.EndpointNodePort, Name: "pi-ui-svc", Port: 30404, Scheme: "http", Ready: true},
+		{Type: model.EndpointIngress, Name: "pi-ui-ingress", Port: 80, Scheme: "http", Path: "/spark/pi/"},
+	}, endpoints)
+}
+
+// 没有 service 查询权限时跳过访问地址，集群照常返回，每个 namespace 只查询一次
+func TestFlinkEndpointsServiceListForbidden(t *testing.T) {
+	serviceLists := 0
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments":
+			fmt.Fprint(w, `{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeploymentList","metadata":{},"items":[
+				{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"a","namespace":"flink"},"spec":{}},
+				{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"b","namespace":"flink"},"spec":{}}]}`)
+		case "/api/v1/namespaces/flink/pods":
+			fmt.Fprint(w, `{"kind":"PodList","apiVersion":"v1","metadata":{},"items":[]}`)
+		case "/apis/apps/v1/namespaces/flink/deployments":
+			fmt.Fprint(w, `{"kind":"DeploymentList","apiVersion":"apps/v1","metadata":{},"items":[]}`)
+		case "/api/v1/namespaces/flink/services":
+			serviceLists++
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Forbidden","code":403,"metadata":{}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	resp, err := k8s.CrdFlinkDeploymentList("fake", model.Filter{NameSpace: tea.String("flink")})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 2)
+	assert.Empty(t, resp.Items[0].Endpoints)
+	assert.Empty(t, resp.Items[1].LoadBalancer)
+	assert.Equal(t, 1, serviceLists)
+
+	clusters, err := k8s.FlinkClusterList("fake", model.FilterFlinkCluster{NameSpace: tea.String("flink")})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, clusters.Total)
+	assert.Empty(t, clusters.Items[0].Endpoints)
+	assert.Equal(t, 2, serviceLists)
+}
@@ -0,0 +1,10 @@
+package service
+
+import "time"
+
+// SetFlinkDeploymentPollInterval 测试中缩短等待 FlinkDeployment 就绪的轮询间隔，返回恢复函数
+func SetFlinkDeploymentPollInterval(interval time.Duration) func() {
+	old := flinkDeploymentPollInterval
+	flinkDeploymentPollInterval = interval
+	return func() { flinkDeploymentPollInterval = old }
+}
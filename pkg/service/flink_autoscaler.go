@@ -0,0 +1,37 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+// CrdFlinkAutoscalerStatus 查询 autoscaler 的建议并行度和扩缩历史，operator 还没有生成 ConfigMap 时只返回配置和状态
+func (s *K8SService) CrdFlinkAutoscalerStatus(k8sClusterName string, req model.FlinkAutoscalerStatusRequest) (model.FlinkAutoscalerStatus, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := req.Validate(); err != nil {
+			return model.FlinkAutoscalerStatus{}, err
+		}
+		if req.NameSpace == nil {
+			req.NameSpace = tea.String("default")
+		}
+		item, err := io.CrdFlinkDeploymentGet(*req.NameSpace, *req.ClusterName)
+		if err != nil {
+			return model.FlinkAutoscalerStatus{}, err
+		}
+		cmResp, err := io.ConfigMapList(model.Filter{
+			NameSpace:     req.NameSpace,
+			FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", fmt.Sprintf(model.AutoscalerConfigMapName, *req.ClusterName))),
+		})
+		if err != nil {
+			return model.FlinkAutoscalerStatus{}, err
+		}
+		var data map[string]string
+		if len(cmResp.Items) > 0 {
+			data = cmResp.Items[0].Data
+		}
+		return model.NewFlinkAutoscalerStatus(item.Object, data)
+	}
+	return model.FlinkAutoscalerStatus{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
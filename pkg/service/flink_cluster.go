@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	appv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// FlinkClusterList 合并 operator 和 v1.12 集群，owner 在 k8s 侧过滤，名称、状态和版本过滤后再查询访问地址
+func (s *K8SService) FlinkClusterList(k8sClusterName string, filter model.FilterFlinkCluster) (model.FlinkClusterListResponse, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := filter.Validate(); err != nil {
+			return model.FlinkClusterListResponse{}, err
+		}
+		namespace := tea.StringValue(filter.NameSpace)
+		if namespace == "" {
+			namespace = "default"
+		}
+		var clusters []model.FlinkCluster
+		if filter.HasFlavor(model.FlinkFlavorOperator) {
+			resp, err := io.CrdFlinkDeploymentList(model.Filter{NameSpace: tea.String(namespace), LabelSelector: filter.LabelSelector()})
+			// 没有安装 operator 时 CRD 不存在
+			if err != nil && !apierrors.IsNotFound(err) {
+				return model.FlinkClusterListResponse{}, fmt.Errorf("list flinkdeployment error: %v", err)
+			}
+			if err == nil {
+				for _, item := range resp.Items {
+					clusters = append(clusters, model.NewFlinkClusterFromOperator(item))
+				}
+			}
+		}
+		if filter.HasFlavor(model.FlinkFlavorV12) {
+			v12, err := listV12Clusters(io, namespace, filter.LabelSelector())
+			if err != nil {
+				return model.FlinkClusterListResponse{}, err
+			}
+			clusters = append(clusters, v12...)
+		}
+
+		items := []model.FlinkCluster{}
+		indexes := newEndpointIndexes(io)
+		for _, c := range clusters {
+			if !filter.Match(c) {
+				continue
+			}
+			// 访问地址作为补充信息，查询失败时忽略，和 FlinkClusterEndpoints 一样 operator 的 LB 按名称查找
+			if index, err := indexes.get(c.NameSpace); err == nil {
+				c.Endpoints = index.resolve(c.ClusterName, []string{fmt.Sprintf(model.JobManagerLBServiceName, c.ClusterName)},
+					c.ClusterName, fmt.Sprintf(model.JobManagerIngressName, c.ClusterName))
+			}
+			items = append(items, c)
+		}
+		filter.Sort(items)
+		return model.FlinkClusterListResponse{Total: len(items), Items: items}, nil
+	}
+	return model.FlinkClusterListResponse{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// listV12Clusters 按 <name>-jobmanager 和 <name>-taskmanager 分组，operator standalone 模式的 TM deployment 没有对应的 JM 会被忽略
+func listV12Clusters(io model.K8SIO, namespace string, labelSelector *string) ([]model.FlinkCluster, error) {
+	resp, err := io.DeploymentList(model.Filter{NameSpace: tea.String(namespace), LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("list deployment error: %v", err)
+	}
+	jms, tms := map[string]*appv1.Deployment{}, map[string]*appv1.Deployment{}
+	var names []string
+	for i := range resp.Items {
+		item := &resp.Items[i]
+		if name, ok := strings.CutSuffix(item.Name, "-jobmanager"); ok {
+			jms[name] = item
+			names = append(names, name)
+		} else if name, ok := strings.CutSuffix(item.Name, "-taskmanager"); ok {
+			tms[name] = item
+		}
+	}
+	clusters := make([]model.FlinkCluster, 0, len(names))
+	for _, name := range names {
+		clusters = append(clusters, model.NewFlinkClusterFromV12(name, jms[name], tms[name]))
+	}
+	return clusters, nil
+}
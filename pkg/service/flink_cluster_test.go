@@ -0,0 +1,126 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFlinkClusterList(t *testing.T) {
+	var labelSelectors []string
+	serviceLists := 0
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments":
+			labelSelectors = append(labelSelectors, r.URL.Query().Get("labelSelector"))
+			fmt.Fprint(w, `{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeploymentList","metadata":{},"items":[
+				{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"op-a","namespace":"flink","creationTimestamp":"2026-10-02T00:00:00Z","labels":{"owner":"alice"}},
+				 "spec":{"flinkVersion":"v1_17","image":"flink:1.17","job":{"jarURI":"local:///job.jar"}},
+				 "status":{"lifecycleState":"STABLE","jobManagerDeploymentStatus":"READY","jobStatus":{"state":"RUNNING"},"taskManager":{"replicas":3}}},
+				{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"op-b","namespace":"flink","creationTimestamp":"2026-10-03T00:00:00Z","labels":{"owner":"bob"}},
+				 "spec":{"flinkVersion":"v1_17","image":"flink:1.17","mode":"standalone","taskManager":{"replicas":2}},
+				 "status":{"jobManagerDeploymentStatus":"ERROR","error":"crash loop"}}]}`)
+		case "/apis/apps/v1/namespaces/flink/deployments":
+			labelSelectors = append(labelSelectors, r.URL.Query().Get("labelSelector"))
+			created := metav1.NewTime(metav1.Now().AddDate(0, 0, -30))
+			list := appv1.DeploymentList{Items: []appv1.Deployment{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "legacy-jobmanager", Namespace: "flink", Labels: map[string]string{"app": "legacy", "owner": "bob"}, CreationTimestamp: created},
+					Spec: appv1.DeploymentSpec{Replicas: tea.Int32(1), Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "jobmanager", Image: "flink:1.12.7"}},
+					}}},
+					Status: appv1.DeploymentStatus{ReadyReplicas: 1},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "legacy-taskmanager", Namespace: "flink", Labels: map[string]string{"app": "legacy", "owner": "bob"}},
+					Spec:       appv1.DeploymentSpec{Replicas: tea.Int32(2)},
+					Status:     appv1.DeploymentStatus{ReadyReplicas: 1},
+				},
+				// operator standalone 模式的 TM，没有 -jobmanager
+				{ObjectMeta: metav1.ObjectMeta{Name: "op-b-taskmanager", Namespace: "flink", Labels: map[string]string{"app": "op-b", "owner": "bob"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "op-b", Namespace: "flink", Labels: map[string]string{"app": "op-b", "owner": "bob"}}},
+			}}
+			json.NewEncoder(w).Encode(list)
+		case "/api/v1/namespaces/flink/services":
+			serviceLists++
+			json.NewEncoder(w).Encode(corev1.ServiceList{Items: []corev1.Service{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "legacy-jobmanager-service", Namespace: "flink", Labels: map[string]string{"app": "legacy"}},
+					Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP, ClusterIP: "10.0.0.2", Ports: []corev1.ServicePort{{Name: "webui", Port: 8081}}},
+				},
+				// operator 的 LB 没有 app 标签
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "op-a-jobmanager-lb-service", Namespace: "flink"},
+					Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: []corev1.ServicePort{{Name: "rest", Port: 8081, NodePort: 30081}}},
+				},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`)
+		}
+	})
+
+	resp, err := k8s.FlinkClusterList("fake", model.FilterFlinkCluster{NameSpace: tea.String("flink")})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, resp.Total)
+	var names []string
+	for _, c := range resp.Items {
+		names = append(names, fmt.Sprintf("%s/%s/%s", c.Flavor, c.ClusterName, c.Status.State))
+	}
+	assert.Equal(t, []string{"v12/legacy/DEPLOYING", "operator/op-a/RUNNING", "operator/op-b/FAILED"}, names)
+	legacy := resp.Items[0]
+	assert.Equal(t, model.FlinkV12Version, legacy.Version)
+	assert.Equal(t, "flink:1.12.7", legacy.Image)
+	assert.Equal(t, int32(2), legacy.Status.TaskManagerReplicas)
+	assert.Equal(t, int32(1), legacy.Status.TaskManagerReady)
+	assert.Equal(t, int32(3), resp.Items[1].Status.TaskManagerReplicas)
+	assert.Equal(t, "crash loop", resp.Items[2].Status.Error)
+	// service 只查询一次，按 app 标签和 LB 名称分配给集群
+	assert.Equal(t, 1, serviceLists)
+	assert.Equal(t, "legacy-jobmanager-service.flink.svc", legacy.Endpoints[0].Host)
+	assert.Equal(t, int32(30081), resp.Items[1].Endpoints[0].Port)
+	assert.Empty(t, resp.Items[2].Endpoints)
+
+	resp, err = k8s.FlinkClusterList("fake", model.FilterFlinkCluster{
+		NameSpace: tea.String("flink"),
+		Owner:     tea.String("bob"),
+		SortBy:    tea.String("create_time"),
+		Desc:      tea.Bool(true),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"op-b", "legacy"}, []string{resp.Items[0].ClusterName, resp.Items[1].ClusterName})
+	assert.Equal(t, "owner=bob", labelSelectors[len(labelSelectors)-1])
+
+	resp, err = k8s.FlinkClusterList("fake", model.FilterFlinkCluster{NameSpace: tea.String("flink"), Flavor: model.FlinkFlavorOperator, Version: tea.String("1.12")})
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Items)
+
+	_, err = k8s.FlinkClusterList("fake", model.FilterFlinkCluster{State: tea.String("STOPPED")})
+	assert.Error(t, err)
+}
+
+// 没有安装 operator 时只返回 v1.12 集群
+func TestFlinkClusterListWithoutOperator(t *testing.T) {
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/apis/apps/v1/namespaces/default/deployments" {
+			json.NewEncoder(w).Encode(appv1.DeploymentList{})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`)
+	})
+	resp, err := k8s.FlinkClusterList("fake", model.FilterFlinkCluster{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, resp.Total)
+	assert.NotNil(t, resp.Items)
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+/*
+FlinkV12MigrateToOperator 迁移步骤：
+ 1. 读取 v1.12 集群的 deployment、configmap、pvc 和 LB，生成 CreateFlinkClusterRequest，dry_run 到这里结束
+ 2. 需要 savepoint 时 stop-with-savepoint，新集群的 job.initialSavepointPath 指向 savepoint
+ 3. 创建 FlinkDeployment，同名时接管原来的 LB，保持地址和端口不变
+ 4. 等待 FlinkDeployment 就绪，失败或者超时回滚：删除 FlinkDeployment，恢复 LB
+ 5. 删除 v1.12 的 deployment、configmap、service 和 pvc
+*/
+func (s *K8SService) FlinkV12MigrateToOperator(k8sClusterName string, req model.MigrateFlinkV12Request) (model.MigrateFlinkV12Response, error) {
+	resp := model.MigrateFlinkV12Response{DryRun: tea.BoolValue(req.DryRun), Steps: []string{}}
+	io, ok := s.IOs[k8sClusterName]
+	if !ok {
+		return resp, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+	}
+	if err := req.Validate(); err != nil {
+		return resp, err
+	}
+	namespace := tea.StringValue(req.NameSpace)
+	if namespace == "" {
+		namespace = "default"
+	}
+	req.NameSpace = tea.String(namespace)
+	clusterName, targetName := *req.ClusterName, req.GetTargetName()
+
+	// 1. 读取 v1.12 集群
+	snapshot, err := getV12Snapshot(io, namespace, clusterName)
+	if err != nil {
+		return resp, err
+	}
+	create, warnings, err := snapshot.ToCreateRequest(req)
+	if err != nil {
+		return resp, err
+	}
+	resp.Request, resp.Warnings = create, warnings
+	if ingresses, err := io.IngressList(model.Filter{
+		NameSpace:     tea.String(namespace),
+		FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", fmt.Sprintf(model.JobManagerIngressName, clusterName))),
+	}); err == nil && len(ingresses.Items) > 0 {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("ingress %s is kept, configure ingress for the FlinkDeployment", ingresses.Items[0].Name))
+	}
+	if err := create.Validate(); err != nil {
+		return resp, err
+	}
+	existing, err := getFlinkDeployment(io, namespace, targetName)
+	if err != nil {
+		return resp, err
+	}
+	if existing != nil {
+		return resp, fmt.Errorf("flinkdeployment %s already exists", targetName)
+	}
+	resp.FlinkDeployment = create.ToYaml()
+	if resp.DryRun {
+		return resp, nil
+	}
+
+	// 2. savepoint
+	if tea.BoolValue(req.Savepoint) {
+		jobID, err := s.migrateJobID(k8sClusterName, req)
+		if err != nil {
+			return resp, err
+		}
+		stop, err := s.FlinkV12JobStop(k8sClusterName, model.FlinkV12JobStopRequest{
+			NameSpace:       req.NameSpace,
+			ClusterName:     req.ClusterName,
+			JobID:           tea.String(jobID),
+			TargetDirectory: req.SavepointDirectory,
+		})
+		if err != nil {
+			return resp, fmt.Errorf("stop job with savepoint error: %v", err)
+		}
+		resp.JobID, resp.SavepointPath = stop.JobID, stop.SavepointPath
+		resp.Steps = append(resp.Steps, fmt.Sprintf("stop job %s with savepoint %s", stop.JobID, stop.SavepointPath))
+		job := *create.Job
+		job.InitialSavepointPath = tea.String(stop.SavepointPath)
+		if job.UpgradeMode == nil {
+			job.UpgradeMode = tea.String("savepoint")
+		}
+		create.Job = &job
+		resp.Request, resp.FlinkDeployment = create, create.ToYaml()
+	}
+
+	// 3. 创建 FlinkDeployment
+	if _, err := s.CrdFlinkDeploymentApply(k8sClusterName, create); err != nil {
+		s.rollbackMigration(k8sClusterName, req, snapshot, create, &resp)
+		return resp, fmt.Errorf("create flinkdeployment error: %v", err)
+	}
+	resp.Steps = append(resp.Steps, fmt.Sprintf("create flinkdeployment %s", targetName))
+
+	// 4. 等待就绪
+	timeout := req.GetTimeout()
+	ctx, cancel := context.WithTimeoutCause(context.Background(), timeout, fmt.Errorf("wait flinkdeployment %s ready timeout after %s", targetName, timeout))
+	err = waitFlinkDeploymentReady(ctx, io, namespace, targetName)
+	cancel()
+	if err != nil {
+		s.rollbackMigration(k8sClusterName, req, snapshot, create, &resp)
+		return resp, err
+	}
+	resp.Steps = append(resp.Steps, fmt.Sprintf("flinkdeployment %s is ready", targetName))
+
+	// 5. 删除 v1.12 资源，失败只记录
+	lbTakenOver := snapshot.LBService != nil && snapshot.LBService.Name == fmt.Sprintf(model.JobManagerLBServiceName, targetName)
+	deletes := []struct {
+		kind, name string
+		delete     func(namespace, name string) error
+	}{
+		{"deployment", fmt.Sprintf(model.JobManagerDeploymentName, clusterName), io.DeploymentDelete},
+		{"deployment", fmt.Sprintf(model.TaskManagerDeploymentName, clusterName), io.DeploymentDelete},
+		{"configmap", fmt.Sprintf(model.ConfigMapV12Name, clusterName), io.ConfigMapDelete},
+		{"service", fmt.Sprintf(model.JobManagerServiceName, clusterName), io.ServiceDelete},
+		{"pvc", fmt.Sprintf(model.PvcName, clusterName), io.PvcDelete},
+	}
+	if targetName != clusterName {
+		deletes = append(deletes, struct {
+			kind, name string
+			delete     func(namespace, name string) error
+		}{"configmap", fmt.Sprintf(model.LogConfigMapName, clusterName), io.ConfigMapDelete})
+	}
+	if snapshot.LBService != nil && !lbTakenOver {
+		deletes = append(deletes, struct {
+			kind, name string
+			delete     func(namespace, name string) error
+		}{"service", snapshot.LBService.Name, io.ServiceDelete})
+	}
+	for _, item := range deletes {
+		if err := item.delete(namespace, item.name); err != nil && !apierrors.IsNotFound(err) {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("delete %s %s error: %v", item.kind, item.name, err))
+			continue
+		}
+		resp.Steps = append(resp.Steps, fmt.Sprintf("delete %s %s", item.kind, item.name))
+	}
+	if err := deleteConfigRevisions(io, namespace, clusterName, 0); err != nil {
+		resp.Warnings = append(resp.Warnings, err.Error())
+	}
+	return resp, nil
+}
+
+func getV12Snapshot(io model.K8SIO, namespace, clusterName string) (*model.FlinkV12Snapshot, error) {
+	snapshot := &model.FlinkV12Snapshot{}
+	var err error
+	snapshot.JobManager, err = io.DeploymentGet(namespace, fmt.Sprintf(model.JobManagerDeploymentName, clusterName))
+	if err != nil {
+		return nil, fmt.Errorf("get v1.12 jobmanager error: %v", err)
+	}
+	snapshot.TaskManager, err = io.DeploymentGet(namespace, fmt.Sprintf(model.TaskManagerDeploymentName, clusterName))
+	if err != nil {
+		return nil, fmt.Errorf("get v1.12 taskmanager error: %v", err)
+	}
+	snapshot.ConfigMap, err = getV12ConfigMap(io, namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	pvcs, err := io.PvcList(model.Filter{
+		NameSpace:     tea.String(namespace),
+		FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", fmt.Sprintf(model.PvcName, clusterName))),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list v1.12 pvc error: %v", err)
+	}
+	snapshot.PvcExists = len(pvcs.Items) > 0
+	services, err := io.ServiceList(model.Filter{
+		NameSpace:     tea.String(namespace),
+		FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", fmt.Sprintf(model.JobManagerLBServiceName, clusterName))),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list v1.12 lb service error: %v", err)
+	}
+	if len(services.Items) > 0 {
+		snapshot.LBService = &services.Items[0]
+	}
+	return snapshot, nil
+}
+
+// migrateJobID 没有指定 job_id 时使用唯一运行中的作业
+func (s *K8SService) migrateJobID(k8sClusterName string, req model.MigrateFlinkV12Request) (string, error) {
+	if req.JobID != nil && *req.JobID != "" {
+		return *req.JobID, nil
+	}
+	jobs, err := s.FlinkV12JobList(k8sClusterName, v12RestRequest(req.NameSpace, req.ClusterName, nil))
+	if err != nil {
+		return "", err
+	}
+	var running []string
+	for _, job := range jobs.Jobs {
+		if job.State == "RUNNING" {
+			running = append(running, job.Jid)
+		}
+	}
+	if len(running) != 1 {
+		return "", fmt.Errorf("job_id is required, %d running jobs found: %v", len(running), running)
+	}
+	return running[0], nil
+}
+
+// flinkDeploymentPollInterval 等待 FlinkDeployment 就绪时查询状态的间隔
+var flinkDeploymentPollInterval = 5 * time.Second
+
+// waitFlinkDeploymentReady 直到就绪、失败或者 ctx 结束，ctx 结束时返回 context.Cause
+func waitFlinkDeploymentReady(ctx context.Context, io model.K8SIO, namespace, name string) error {
+	ticker := time.NewTicker(flinkDeploymentPollInterval)
+	defer ticker.Stop()
+	for {
+		item, err := io.CrdFlinkDeploymentGet(namespace, name)
+		if err != nil {
+			return fmt.Errorf("get flinkdeployment %s error: %v", name, err)
+		}
+		healthy, err := model.FlinkDeploymentHealth(*item)
+		if err != nil {
+			return err
+		}
+		if healthy {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// migrateJarID 回滚时重新提交作业使用的 jar，依次按 jar_id、job.jar_url 的文件名、entry_class 匹配，只有一个 jar 时直接使用
+func (s *K8SService) migrateJarID(k8sClusterName string, req model.MigrateFlinkV12Request) (string, error) {
+	if req.JarID != nil && *req.JarID != "" {
+		return *req.JarID, nil
+	}
+	jars, err := s.FlinkV12JarList(k8sClusterName, v12RestRequest(req.NameSpace, req.ClusterName, nil))
+	if err != nil {
+		return "", err
+	}
+	if req.Job != nil && req.Job.JarURI != nil {
+		for _, jar := range jars.Files {
+			if jar.Name == path.Base(*req.Job.JarURI) {
+				return jar.ID, nil
+			}
+		}
+	}
+	if req.Job != nil && req.Job.EntryClass != nil {
+		for _, jar := range jars.Files {
+			for _, entry := range jar.Entry {
+				if entry.Name == *req.Job.EntryClass {
+					return jar.ID, nil
+				}
+			}
+		}
+	}
+	if len(jars.Files) == 1 {
+		return jars.Files[0].ID, nil
+	}
+	return "", fmt.Errorf("jar_id is required, %d uploaded jars found", len(jars.Files))
+}
+
+// rollbackMigration 删除创建的 FlinkDeployment 和 LB，接管的 LB 恢复为 v1.12 的配置，v1.12 的资源没有改动
+// 已经 stop-with-savepoint 的作业从 savepoint 重新提交到 v1.12 集群，失败时记录在 warnings
+func (s *K8SService) rollbackMigration(k8sClusterName string, req model.MigrateFlinkV12Request, snapshot *model.FlinkV12Snapshot, create model.CreateFlinkClusterRequest, resp *model.MigrateFlinkV12Response) {
+	resp.RolledBack = true
+	io := s.IOs[k8sClusterName]
+	namespace, targetName := *req.NameSpace, req.GetTargetName()
+	if err := io.CrdFlinkDeploymentDelete(namespace, targetName); err != nil && !apierrors.IsNotFound(err) {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("rollback: delete flinkdeployment %s error: %v", targetName, err))
+	} else {
+		resp.Steps = append(resp.Steps, fmt.Sprintf("rollback: delete flinkdeployment %s", targetName))
+	}
+	if create.LoadBalancer != nil {
+		lbName := fmt.Sprintf(model.JobManagerLBServiceName, targetName)
+		if snapshot.LBService != nil && snapshot.LBService.Name == lbName {
+			if _, err := io.ServiceApply(model.NewApplyServiceRequest(*snapshot.LBService)); err != nil {
+				resp.Warnings = append(resp.Warnings, fmt.Sprintf("rollback: restore service %s error: %v", lbName, err))
+			} else {
+				resp.Steps = append(resp.Steps, fmt.Sprintf("rollback: restore service %s", lbName))
+			}
+		} else if err := io.ServiceDelete(namespace, lbName); err != nil && !apierrors.IsNotFound(err) {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("rollback: delete service %s error: %v", lbName, err))
+		}
+	}
+	if resp.SavepointPath != "" {
+		run, err := s.resubmitV12Job(k8sClusterName, req, resp.SavepointPath)
+		if err != nil {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("rollback: resubmit job %s from savepoint %s error: %v, resume it with FlinkV12JarRun savepoint_path", resp.JobID, resp.SavepointPath, err))
+		} else {
+			resp.Steps = append(resp.Steps, fmt.Sprintf("rollback: resubmit job %s from savepoint %s as %s", resp.JobID, resp.SavepointPath, run.JobID))
+		}
+	}
+}
+
+// resubmitV12Job 使用迁移请求中作业的参数从 savepoint 重新提交
+func (s *K8SService) resubmitV12Job(k8sClusterName string, req model.MigrateFlinkV12Request, savepointPath string) (model.FlinkJarRunResponse, error) {
+	jarID, err := s.migrateJarID(k8sClusterName, req)
+	if err != nil {
+		return model.FlinkJarRunResponse{}, err
+	}
+	run := model.FlinkV12JarRunRequest{
+		NameSpace:     req.NameSpace,
+		ClusterName:   req.ClusterName,
+		JarID:         tea.String(jarID),
+		SavepointPath: tea.String(savepointPath),
+	}
+	if req.Job != nil {
+		run.EntryClass = req.Job.EntryClass
+		run.Args = req.Job.Args
+		run.Parallelism = req.Job.Parallelism
+		run.AllowNonRestoredState = req.Job.AllowNonRestoredState
+	}
+	return s.FlinkV12JarRun(k8sClusterName, run)
+}
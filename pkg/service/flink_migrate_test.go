@@ -0,0 +1,201 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	"github.com/xops-infra/multi-k8s-client/pkg/service"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeMigrateServer v1.12 集群 demo 和 LB，FlinkDeployment 创建后依次返回 statuses，最后一个一直保持
+func fakeMigrateServer(t *testing.T, statuses ...string) (model.K8SContract, *[]string) {
+	const proxy = "/api/v1/namespaces/flink/services/demo-jobmanager-service:webui/proxy"
+	t.Cleanup(service.SetFlinkDeploymentPollInterval(10 * time.Millisecond))
+	v12 := model.CreateFlinkV12ClusterRequest{
+		Name:        tea.String("demo"),
+		NameSpace:   tea.String("flink"),
+		Owner:       tea.String("alice"),
+		TaskManager: &model.TaskManagerV12{Nu: tea.Int(2)},
+	}
+	jm, _ := v12.NewJobManagerDeployment()
+	tm, _ := v12.NewTaskManagerDeployment()
+	configMap := v12.NewConfigMap()
+	lb := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-jobmanager-lb-service", Namespace: "flink"},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeLoadBalancer,
+			Selector: map[string]string{"app": "demo", "component": "jobmanager"},
+			Ports:    []corev1.ServicePort{{Name: "rest", Port: 8081, NodePort: 30081}},
+		},
+	}
+	var (
+		mu       sync.Mutex
+		requests []string
+		created  bool
+		polls    int
+	)
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			body, _ := io.ReadAll(r.Body)
+			requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, body))
+		}
+		switch {
+		case r.URL.Path == "/apis/apps/v1/namespaces/flink/deployments/demo-jobmanager" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(jm)
+		case r.URL.Path == "/apis/apps/v1/namespaces/flink/deployments/demo-taskmanager" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(tm)
+		case r.URL.Path == "/api/v1/namespaces/flink/configmaps" && r.Method == http.MethodGet:
+			list := corev1.ConfigMapList{}
+			if r.URL.Query().Get("fieldSelector") == "metadata.name=demo-configmap" {
+				list.Items = append(list.Items, corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "demo-configmap", Namespace: "flink"}, Data: configMap.Data})
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.URL.Path == "/api/v1/namespaces/flink/persistentvolumeclaims" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(corev1.PersistentVolumeClaimList{Items: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "demo-pvc"}}}})
+		case r.URL.Path == "/api/v1/namespaces/flink/services" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(corev1.ServiceList{Items: []corev1.Service{lb}})
+		case r.URL.Path == "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments/demo" && r.Method == http.MethodGet && created:
+			status := statuses[min(polls, len(statuses)-1)]
+			polls++
+			fmt.Fprintf(w, `{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"demo","namespace":"flink"},"spec":{"job":{}},"status":{"jobManagerDeploymentStatus":%q,"error":"crash loop","jobStatus":{"state":"RUNNING"}}}`, status)
+		case r.URL.Path == "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments" && r.Method == http.MethodPost:
+			created = true
+			fmt.Fprint(w, `{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"demo","namespace":"flink"}}`)
+		case r.URL.Path == proxy+"/jobs/a1/stop" && r.Method == http.MethodPost:
+			fmt.Fprint(w, `{"request-id":"t1"}`)
+		case r.URL.Path == proxy+"/jobs/a1/savepoints/t1":
+			fmt.Fprint(w, `{"status":{"id":"COMPLETED"},"operation":{"location":"s3://bucket/savepoint-a1"}}`)
+		case r.URL.Path == proxy+"/jars" && r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"files":[{"id":"5f1c_other.jar","name":"other.jar"},{"id":"7a2b_job.jar","name":"job.jar"}]}`)
+		case r.URL.Path == proxy+"/jars/7a2b_job.jar/run" && r.Method == http.MethodPost:
+			fmt.Fprint(w, `{"jobid":"b2"}`)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/flink/services/") && r.Method == http.MethodPatch:
+			json.NewEncoder(w).Encode(lb)
+		case r.Method == http.MethodDelete:
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Success"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`)
+		}
+	})
+	return k8s, &requests
+}
+
+func TestFlinkV12Migrate(t *testing.T) {
+	migrate := model.MigrateFlinkV12Request{
+		NameSpace:   tea.String("flink"),
+		ClusterName: tea.String("demo"),
+		Image:       tea.String("flink:1.17"),
+		Job:         &model.Job{JarURI: tea.String("local:///opt/flink/job.jar")},
+		DryRun:      tea.Bool(true),
+	}
+
+	k8s, requests := fakeMigrateServer(t, "DEPLOYING", "DEPLOYING", "READY")
+	resp, err := k8s.FlinkV12MigrateToOperator("fake", migrate)
+	assert.NoError(t, err)
+	assert.True(t, resp.DryRun)
+	assert.Empty(t, *requests)
+	assert.Equal(t, int32(30081), *resp.Request.LoadBalancer.Port)
+	assert.Equal(t, "flink:1.17", resp.FlinkDeployment["spec"].(map[string]any)["image"])
+
+	migrate.DryRun = tea.Bool(false)
+	resp, err = k8s.FlinkV12MigrateToOperator("fake", migrate)
+	assert.NoError(t, err)
+	assert.False(t, resp.RolledBack)
+	var deleted []string
+	for _, request := range *requests {
+		if strings.HasPrefix(request, "DELETE") {
+			deleted = append(deleted, strings.Fields(request)[1])
+		}
+	}
+	assert.Equal(t, []string{
+		"/apis/apps/v1/namespaces/flink/deployments/demo-jobmanager",
+		"/apis/apps/v1/namespaces/flink/deployments/demo-taskmanager",
+		"/api/v1/namespaces/flink/configmaps/demo-configmap",
+		"/api/v1/namespaces/flink/services/demo-jobmanager-service",
+		"/api/v1/namespaces/flink/persistentvolumeclaims/demo-pvc",
+	}, deleted)
+	// 接管的 LB 保持原来的 NodePort
+	assert.Contains(t, (*requests)[1], `"nodePort":30081`)
+}
+
+func TestFlinkV12MigrateRollback(t *testing.T) {
+	k8s, requests := fakeMigrateServer(t, "ERROR")
+	resp, err := k8s.FlinkV12MigrateToOperator("fake", model.MigrateFlinkV12Request{
+		NameSpace:   tea.String("flink"),
+		ClusterName: tea.String("demo"),
+	})
+	assert.ErrorContains(t, err, "crash loop")
+	assert.True(t, resp.RolledBack)
+	last := (*requests)[len(*requests)-2:]
+	assert.True(t, strings.HasPrefix(last[0], "DELETE /apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments/demo"))
+	// LB 恢复为 v1.12 的 selector
+	assert.Contains(t, last[1], `"component":"jobmanager"`)
+	assert.Contains(t, last[1], `"nodePort":30081`)
+	for _, request := range *requests {
+		assert.NotContains(t, request, "deployments/demo-jobmanager")
+	}
+}
+
+func TestFlinkV12MigrateRollbackSavepoint(t *testing.T) {
+	k8s, requests := fakeMigrateServer(t, "DEPLOYING", "ERROR")
+	resp, err := k8s.FlinkV12MigrateToOperator("fake", model.MigrateFlinkV12Request{
+		NameSpace:   tea.String("flink"),
+		ClusterName: tea.String("demo"),
+		Job:         &model.Job{JarURI: tea.String("local:///opt/flink/usrlib/job.jar"), Args: []string{"--input", "s3://bucket/in"}},
+		Savepoint:   tea.Bool(true),
+		JobID:       tea.String("a1"),
+	})
+	assert.ErrorContains(t, err, "crash loop")
+	assert.True(t, resp.RolledBack)
+	assert.Equal(t, "s3://bucket/savepoint-a1", resp.SavepointPath)
+	// 按 jar_url 的文件名找到 jar，从 savepoint 重新提交到 v1.12 集群
+	last := (*requests)[len(*requests)-1]
+	assert.True(t, strings.HasPrefix(last, "POST /api/v1/namespaces/flink/services/demo-jobmanager-service:webui/proxy/jars/7a2b_job.jar/run"))
+	assert.Contains(t, last, `"savepointPath":"s3://bucket/savepoint-a1"`)
+	assert.Contains(t, last, `"programArgsList":["--input","s3://bucket/in"]`)
+	assert.Contains(t, resp.Steps, "rollback: resubmit job a1 from savepoint s3://bucket/savepoint-a1 as b2")
+	for _, warning := range resp.Warnings {
+		assert.NotContains(t, warning, "resubmit")
+	}
+
+	// 找不到 jar 时只记录 warning
+	k8s, _ = fakeMigrateServer(t, "ERROR")
+	resp, err = k8s.FlinkV12MigrateToOperator("fake", model.MigrateFlinkV12Request{
+		NameSpace:   tea.String("flink"),
+		ClusterName: tea.String("demo"),
+		Job:         &model.Job{JarURI: tea.String("local:///opt/flink/usrlib/wordcount.jar")},
+		Savepoint:   tea.Bool(true),
+		JobID:       tea.String("a1"),
+	})
+	assert.Error(t, err)
+	assert.True(t, resp.RolledBack)
+	assert.Contains(t, resp.Warnings[len(resp.Warnings)-1], "rollback: resubmit job a1 from savepoint s3://bucket/savepoint-a1 error: jar_id is required")
+}
+
+func TestFlinkV12MigrateTimeout(t *testing.T) {
+	k8s, _ := fakeMigrateServer(t, "DEPLOYING")
+	start := time.Now()
+	resp, err := k8s.FlinkV12MigrateToOperator("fake", model.MigrateFlinkV12Request{
+		NameSpace:   tea.String("flink"),
+		ClusterName: tea.String("demo"),
+		Timeout:     tea.String("100ms"),
+	})
+	assert.EqualError(t, err, "wait flinkdeployment demo ready timeout after 100ms")
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.True(t, resp.RolledBack)
+}
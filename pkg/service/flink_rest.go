@@ -0,0 +1,115 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// 通过 apiserver 的 service proxy 访问 JM rest 接口，不依赖 LB
+
+func (s *K8SService) FlinkRestClusterOverview(k8sClusterName string, req model.FlinkRestRequest) (model.FlinkClusterOverview, error) {
+	var resp model.FlinkClusterOverview
+	err := s.flinkRestGet(k8sClusterName, req, "/overview", &resp)
+	return resp, err
+}
+
+func (s *K8SService) FlinkRestJobsOverview(k8sClusterName string, req model.FlinkRestRequest) (model.FlinkJobsOverview, error) {
+	var resp model.FlinkJobsOverview
+	err := s.flinkRestGet(k8sClusterName, req, "/jobs/overview", &resp)
+	return resp, err
+}
+
+func (s *K8SService) FlinkRestJobDetail(k8sClusterName string, req model.FlinkRestRequest) (model.FlinkJobDetail, error) {
+	var resp model.FlinkJobDetail
+	if req.JobID == nil || *req.JobID == "" {
+		return resp, fmt.Errorf("job_id is required")
+	}
+	err := s.flinkRestGet(k8sClusterName, req, fmt.Sprintf("/jobs/%s", *req.JobID), &resp)
+	return resp, err
+}
+
+func (s *K8SService) FlinkRestJobExceptions(k8sClusterName string, req model.FlinkRestRequest) (model.FlinkJobExceptions, error) {
+	var resp model.FlinkJobExceptions
+	if req.JobID == nil || *req.JobID == "" {
+		return resp, fmt.Errorf("job_id is required")
+	}
+	err := s.flinkRestGet(k8sClusterName, req, fmt.Sprintf("/jobs/%s/exceptions", *req.JobID), &resp)
+	return resp, err
+}
+
+func (s *K8SService) FlinkRestJobCheckpoints(k8sClusterName string, req model.FlinkRestRequest) (model.FlinkJobCheckpoints, error) {
+	var resp model.FlinkJobCheckpoints
+	if req.JobID == nil || *req.JobID == "" {
+		return resp, fmt.Errorf("job_id is required")
+	}
+	err := s.flinkRestGet(k8sClusterName, req, fmt.Sprintf("/jobs/%s/checkpoints", *req.JobID), &resp)
+	return resp, err
+}
+
+// flinkRestGet 请求 JM rest 接口并解析 json 到 result
+func (s *K8SService) flinkRestGet(k8sClusterName string, req model.FlinkRestRequest, path string, result any) error {
+	return s.flinkRestDo(k8sClusterName, req, model.ServiceProxyRequest{Path: path}, result)
+}
+
+// flinkRestDo 补全 proxy 的 service 信息后请求 JM rest 接口，result 为 nil 时不解析返回
+func (s *K8SService) flinkRestDo(k8sClusterName string, req model.FlinkRestRequest, proxy model.ServiceProxyRequest, result any) error {
+	io, ok := s.IOs[k8sClusterName]
+	if !ok {
+		return fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+	}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	if req.NameSpace == nil {
+		req.NameSpace = tea.String("default")
+	}
+	flavor, err := flinkFlavor(io, req)
+	if err != nil {
+		return err
+	}
+	proxy.Namespace = *req.NameSpace
+	proxy.Name, proxy.Port = req.RestService(flavor)
+	body, err := io.ServiceProxy(proxy)
+	if err != nil {
+		return fmt.Errorf("request flink rest %s %s failed: %v", proxy.Name, proxy.Path, err)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("decode flink rest %s response failed: %v", proxy.Path, err)
+	}
+	return nil
+}
+
+// flinkFlavor 未指定时自动识别，存在 FlinkDeployment 为 operator，存在 -jobmanager deployment 为 v12。
+// 没有安装 operator 时 FlinkDeployment 查询返回 NotFound，其他错误（比如没有权限）直接返回
+func flinkFlavor(io model.K8SIO, req model.FlinkRestRequest) (model.FlinkFlavor, error) {
+	if req.Flavor != "" {
+		return req.Flavor, nil
+	}
+	filter := model.Filter{
+		NameSpace:     req.NameSpace,
+		FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", *req.ClusterName)),
+	}
+	crdResp, err := io.CrdFlinkDeploymentList(filter)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", err
+	}
+	if err == nil && len(crdResp.Items) > 0 {
+		return model.FlinkFlavorOperator, nil
+	}
+	filter.FieldSelector = tea.String(fmt.Sprintf("metadata.name=%s", fmt.Sprintf(model.JobManagerDeploymentName, *req.ClusterName)))
+	depResp, err := io.DeploymentList(filter)
+	if err != nil {
+		return "", err
+	}
+	if len(depResp.Items) > 0 {
+		return model.FlinkFlavorV12, nil
+	}
+	return "", fmt.Errorf("flink cluster %s not found in namespace %s", *req.ClusterName, *req.NameSpace)
+}
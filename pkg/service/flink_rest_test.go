@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	"github.com/xops-infra/multi-k8s-client/pkg/service"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// newFakeK8S 使用 httptest 模拟 apiserver
+func newFakeK8S(t *testing.T, handler http.HandlerFunc) model.K8SContract {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	kubeConfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+current-context: fake
+users:
+- name: fake
+  user:
+    token: fake
+`, server.URL)
+	_k8s, err := service.NewK8SService([]model.Cluster{
+		{
+			KubeConfig: tea.String(base64.StdEncoding.EncodeToString([]byte(kubeConfig))),
+			Name:       tea.String("fake"),
+			Alias:      tea.String("fake"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return _k8s
+}
+
+func TestFlinkRestOperator(t *testing.T) {
+	var paths []string
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments":
+			fmt.Fprint(w, `{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeploymentList","metadata":{},"items":[{"apiVersion":"flink.apache.org/v1beta1","kind":"FlinkDeployment","metadata":{"name":"demo","namespace":"flink"}}]}`)
+		case "/api/v1/namespaces/flink/services/demo-rest:rest/proxy/overview":
+			fmt.Fprint(w, `{"taskmanagers":2,"slots-total":4,"slots-available":1,"jobs-running":1,"flink-version":"1.17.1"}`)
+		case "/api/v1/namespaces/flink/services/demo-rest:rest/proxy/jobs/overview":
+			fmt.Fprint(w, `{"jobs":[{"jid":"a1","name":"wordcount","state":"RUNNING","start-time":1700000000000,"tasks":{"running":3}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":["Not found"]}`)
+		}
+	})
+
+	req := model.FlinkRestRequest{NameSpace: tea.String("flink"), ClusterName: tea.String("demo")}
+	overview, err := k8s.FlinkRestClusterOverview("fake", req)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, overview.TaskManagers)
+	assert.Equal(t, 1, overview.SlotsAvailable)
+	assert.Equal(t, "1.17.1", overview.FlinkVersion)
+
+	jobs, err := k8s.FlinkRestJobsOverview("fake", req)
+	assert.NoError(t, err)
+	assert.Len(t, jobs.Jobs, 1)
+	assert.Equal(t, "RUNNING", jobs.Jobs[0].State)
+	assert.Equal(t, 3, jobs.Jobs[0].Tasks["running"])
+	assert.Contains(t, paths, "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments")
+
+	_, err = k8s.FlinkRestJobDetail("fake", req)
+	assert.EqualError(t, err, "job_id is required")
+
+	_, err = k8s.FlinkRestClusterOverview("not-exist", req)
+	assert.Error(t, err)
+}
+
+// 没有安装 operator 时识别为 v12，FlinkDeployment 查询的其他错误直接返回
+func TestFlinkRestFlavor(t *testing.T) {
+	var crdStatus atomic.Int32
+	crdStatus.Store(http.StatusNotFound)
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments":
+			code := crdStatus.Load()
+			w.WriteHeader(int(code))
+			fmt.Fprintf(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","code":%d,"metadata":{}}`, code)
+		case "/apis/apps/v1/namespaces/flink/deployments":
+			fmt.Fprint(w, `{"kind":"DeploymentList","apiVersion":"apps/v1","metadata":{},"items":[{"metadata":{"name":"demo-jobmanager","namespace":"flink"}}]}`)
+		case "/api/v1/namespaces/flink/services/demo-jobmanager-service:webui/proxy/overview":
+			fmt.Fprint(w, `{"taskmanagers":1,"flink-version":"1.12.7"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":["Not found"]}`)
+		}
+	})
+
+	req := model.FlinkRestRequest{NameSpace: tea.String("flink"), ClusterName: tea.String("demo")}
+	overview, err := k8s.FlinkRestClusterOverview("fake", req)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.12.7", overview.FlinkVersion)
+
+	crdStatus.Store(http.StatusForbidden)
+	_, err = k8s.FlinkRestClusterOverview("fake", req)
+	assert.True(t, apierrors.IsForbidden(err), err)
+}
+
+func TestFlinkRestV12(t *testing.T) {
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/namespaces/default/services/demo-jobmanager-service:webui/proxy/jobs/a1":
+			fmt.Fprint(w, `{"jid":"a1","name":"wordcount","state":"RUNNING","vertices":[{"id":"v1","name":"Source","parallelism":2,"status":"RUNNING"}]}`)
+		case "/api/v1/namespaces/default/services/demo-jobmanager-service:webui/proxy/jobs/a1/exceptions":
+			fmt.Fprint(w, `{"root-exception":"java.lang.RuntimeException: boom","timestamp":1700000000000,"all-exceptions":[{"exception":"boom","task":"Source (1/2)","location":"10.0.0.1:6122"}],"truncated":false}`)
+		case "/api/v1/namespaces/default/services/demo-jobmanager-service:webui/proxy/jobs/a1/checkpoints":
+			fmt.Fprint(w, `{"counts":{"restored":0,"total":10,"in_progress":0,"completed":9,"failed":1},"latest":{"completed":{"id":10,"status":"COMPLETED","external_path":"s3://bucket/chk-10"}},"history":[]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":["Not found"]}`)
+		}
+	})
+
+	req := model.FlinkRestRequest{ClusterName: tea.String("demo"), Flavor: model.FlinkFlavorV12, JobID: tea.String("a1")}
+	detail, err := k8s.FlinkRestJobDetail("fake", req)
+	assert.NoError(t, err)
+	assert.Equal(t, "wordcount", detail.Name)
+	assert.Len(t, detail.Vertices, 1)
+	assert.Equal(t, 2, detail.Vertices[0].Parallelism)
+
+	exceptions, err := k8s.FlinkRestJobExceptions("fake", req)
+	assert.NoError(t, err)
+	assert.Equal(t, "java.lang.RuntimeException: boom", exceptions.RootException)
+	assert.Len(t, exceptions.AllExceptions, 1)
+
+	checkpoints, err := k8s.FlinkRestJobCheckpoints("fake", req)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, checkpoints.Counts.Completed)
+	assert.Equal(t, "s3://bucket/chk-10", checkpoints.Latest.Completed.ExternalPath)
+
+	_, err = k8s.FlinkRestClusterOverview("fake", req)
+	assert.Error(t, err)
+}
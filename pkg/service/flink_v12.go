@@ -6,6 +6,7 @@ import (
 
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	"k8s.io/client-go/util/retry"
 )
 
 // 查询 flinkNamespace 下的所有 deployment
@@ -75,6 +76,7 @@ func (s *K8SService) FlinkV12ClusterList(k8sClusterName string, filter model.Fil
 
 		// 转换
 		items := make([]model.CrdFlinkDeployment, 0)
+		indexes := newEndpointIndexes(io)
 		for _, v := range clusterMap {
 			flinkconfig := make(map[string]any, 0)
 			// 获取 flink configmap 内容
@@ -99,24 +101,14 @@ func (s *K8SService) FlinkV12ClusterList(k8sClusterName string, filter model.Fil
 			}
 			v.FlinkConfig = flinkconfig
 
-			// 增加 LoadBlance 连接信息
-			inputS := model.Filter{
-				NameSpace: tea.String(v.NameSpace),
-				// FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s-jobmanager-lb-service", v.ClusterName)), // app-session-jobmanager-lb-service
-				LabelSelector: tea.String(fmt.Sprintf("app=%s", v.ClusterName)),
-			}
-			lbResp, err := io.ServiceList(inputS)
-			if err == nil {
-				for k, item := range lbResp.Items {
-					if item.Spec.Type == "LoadBalancer" {
-						// 只记录 LoadBalancer 信息\
-						if len(item.Status.LoadBalancer.Ingress) != 1 {
-							continue
-						}
-						ip := item.Status.LoadBalancer.Ingress[0].IP
-						port := item.Spec.Ports[0].NodePort
-						v.Status.(map[string]any)[fmt.Sprintf("loadbalance-%d", k)] = fmt.Sprintf("%s:%d", ip, port)
-						v.LoadBalancer[fmt.Sprintf("loadbalance-%d", k)] = fmt.Sprintf("%s:%d", ip, port)
+			// 增加 LoadBlance 和 ingress 连接信息，LB 使用 service 端口，和 operator 集群保持一致
+			if index, err := indexes.get(v.NameSpace); err == nil {
+				endpoints := index.resolve(v.ClusterName, nil, fmt.Sprintf(model.JobManagerIngressName, v.ClusterName))
+				v.Endpoints = endpoints
+				v.LoadBalancer = loadBalancerMap(endpoints)
+				for k, address := range v.LoadBalancer {
+					if strings.HasPrefix(k, "loadbalance-") {
+						v.Status.(map[string]any)[k] = address
 					}
 				}
 			}
@@ -137,26 +129,35 @@ func (s *K8SService) FlinkV12ClusterList(k8sClusterName string, filter model.Fil
  2. service x1
  3. pvc x1
  4. configmap x1
+ 5. ingress x1，配置了 ingress 才创建
 */
 func (s *K8SService) FlinkV12ClusterCreate(k8sClusterName string, req model.CreateFlinkV12ClusterRequest) (model.CreateResponse, error) {
 	var resp model.CreateResponse
 	if io, ok := s.IOs[k8sClusterName]; ok {
 		// 1. 初始化所有配置，如果有问题直接报错
-		jobDeployment := req.NewJobManagerDeployment()
-		createJobD, err := model.NewDeploymentCreateFromMap(jobDeployment)
+		createJobD, err := req.NewJobManagerDeployment()
 		if err != nil {
 			return resp, err
 		}
-		taskDeployment := req.NewTaskManagerDeployment()
-		createTaskD, err := model.NewDeploymentCreateFromMap(taskDeployment)
+		createTaskD, err := req.NewTaskManagerDeployment()
 		if err != nil {
 			return resp, err
 		}
 
+		if err := req.LogCollection.Validate(); err != nil {
+			return resp, err
+		}
+		if err := req.Ingress.Validate(); err != nil {
+			return resp, err
+		}
+		if err := req.LoadBalancer.Validate(); err != nil {
+			return resp, err
+		}
 		configMapReq := req.NewConfigMap()
+		logConfigMapReq := req.NewLogConfigMap()
 		pvcReq := req.NewPVC()
 		serviceReq := req.NewService()
-		ServiceLB := req.NewLBService()
+		ingressReq := req.NewIngress()
 
 		// 2. 创建
 		// 如果只是辅助资源出错可以正常结束，返回结果标注错误资源和错误信息，后续人工干预
@@ -182,15 +183,29 @@ func (s *K8SService) FlinkV12ClusterCreate(k8sClusterName string, req model.Crea
 		if err != nil {
 			errors["configmap"] = err.Error()
 		}
+		if logConfigMapReq != nil {
+			_, err = io.ConfigMapApply(*logConfigMapReq)
+			if err != nil {
+				errors["log-configmap"] = err.Error()
+			}
+		}
 
 		// service
 		_, err = io.ServiceApply(serviceReq)
 		if err != nil {
 			errors["service"] = err.Error()
 		}
-		_, err = io.ServiceApply(ServiceLB)
-		if err != nil {
-			errors["service-lb"] = err.Error()
+		if req.NeedLBService() {
+			_, err = applyLBService(io, req.LoadBalancer, req.NewLBService)
+			if err != nil {
+				errors["service-lb"] = err.Error()
+			}
+		}
+		if ingressReq != nil {
+			_, err = io.IngressApply(*ingressReq)
+			if err != nil {
+				errors["ingress"] = err.Error()
+			}
 		}
 		// 优化打印 LB 的请求公网地址+端口，创建的时候看不到，改到查询里面展示
 
@@ -204,112 +219,101 @@ func (s *K8SService) FlinkV12ClusterCreate(k8sClusterName string, req model.Crea
 	return resp, fmt.Errorf("cluster not found")
 }
 
-// labels 不支持修改 app 标签，只能修改 owner 标签
-func (s *K8SService) FlinkV12ClusterApply(k8sClusterName, namespace, clusterName string, req model.ApplyFlinkV12ClusterRequest) error {
+// labels 不支持修改 app 标签；先更新 configmap，flink-conf.yaml 有变化时修改 JM/TM 的配置摘要触发滚动重启
+func (s *K8SService) FlinkV12ClusterApply(k8sClusterName, namespace, clusterName string, req model.ApplyFlinkV12ClusterRequest) (model.ApplyFlinkV12ClusterResponse, error) {
+	var resp model.ApplyFlinkV12ClusterResponse
 	if io, ok := s.IOs[k8sClusterName]; ok {
-		// 涉及到 deployment和 configmap更新
-		// 1. 更新 deployment
 		if namespace == "" {
 			namespace = "default"
 		}
+		if err := req.Validate(clusterName); err != nil {
+			return resp, err
+		}
 
-		if req.Labels != nil {
-			if _, ok := req.Labels["app"]; ok {
-				if req.Labels["app"] != clusterName {
-					return fmt.Errorf("app label is not supported update")
-				}
-			}
-			// 自动加上 app标签
-			req.Labels["app"] = clusterName
-			_, err := io.DeploymentApply(model.ApplyDeploymentRequest{
-				ClusterName: tea.String(fmt.Sprintf(model.JobManagerDeploymentName, clusterName)),
-				Namespace:   tea.String(namespace),
-				Labels:      req.Labels,
-			})
+		// 1. 更新 configmap
+		var configHash string
+		if req.Labels != nil || req.FlinkConfiguration != nil {
+			configMap, err := getV12ConfigMap(io, namespace, clusterName)
 			if err != nil {
-				return fmt.Errorf("job deployment apply error: %v", err)
+				return resp, err
 			}
-			_, err = io.DeploymentApply(model.ApplyDeploymentRequest{
-				ClusterName: tea.String(fmt.Sprintf(model.TaskManagerDeploymentName, clusterName)),
-				Namespace:   tea.String(namespace),
-				Labels:      req.Labels,
-			})
-			if err != nil {
-				return fmt.Errorf("task deployment apply error: %v", err)
+			configMapName := configMap.Name
+			// 确保 ConfigMap 的 app 标签使用原始 clusterName
+			labels := map[string]string{}
+			for k, v := range configMap.Labels {
+				labels[k] = v
 			}
-			// 更新 jobmanager 的 service
-			// _, err = io.ServiceApply(model.ApplyServiceRequest{
-			// 	Name:        tea.String(fmt.Sprintf(model.JobManagerServiceName, clusterName)),
-			// 	Namespace:   tea.String(namespace),
-			// 	Labels:      req.Labels,
-			// 	Spec:        nil,
-			// 	Annotations: nil,
-			// })
-			// if err != nil {
-			// 	return fmt.Errorf("jobmanager service apply error: %v", err)
-			// }
-			// // 更新 JobManagerLBServiceName
-			// _, err = io.ServiceApply(model.ApplyServiceRequest{
-			// 	Name:        tea.String(fmt.Sprintf(model.JobManagerLBServiceName, clusterName)),
-			// 	Namespace:   tea.String(namespace),
-			// 	Labels:      req.Labels,
-			// 	Spec:        nil,
-			// 	Annotations: nil,
-			// })
-			// if err != nil {
-			// 	return fmt.Errorf("jobmanager lb service apply error: %v", err)
-			// }
-
-			// configmap
-			_, err = io.ConfigMapApply(model.ApplyConfigMapRequest{
-				Name:      tea.String(fmt.Sprintf(model.ConfigMapV12Name, clusterName)),
-				Namespace: tea.String(namespace),
-				Labels:    req.Labels,
-				Data:      nil,
-			})
-			if err != nil {
-				return fmt.Errorf("configmap apply error: %v", err)
+			for k, v := range req.Labels {
+				if labels[k] != v {
+					resp.Changes = append(resp.Changes, model.FlinkV12Change{Resource: configMapName, Field: "labels." + k, From: labels[k], To: v})
+				}
+				labels[k] = v
 			}
-		}
-		if req.FlinkConfiguration != nil {
-			// 2. 更新 configmap
-			// 为 ConfigMap 创建独立的 labels，确保 app 标签使用原始的 clusterName
-			configMapLabels := make(map[string]string)
-			if req.Labels != nil {
-				for k, v := range req.Labels {
-					configMapLabels[k] = v
+			labels["app"] = clusterName
+			data := req.NewConfigData(configMap.Data)
+			if data["flink-conf.yaml"] != configMap.Data["flink-conf.yaml"] {
+				resp.Changes = append(resp.Changes, model.FlinkV12Change{
+					Resource: configMapName,
+					Field:    "flink-conf.yaml",
+					From:     configMap.Data["flink-conf.yaml"],
+					To:       data["flink-conf.yaml"],
+				})
+				configHash = model.ConfigHash(data)
+				// 保存修改之前的配置
+				if _, err := saveConfigRevision(io, namespace, clusterName, configMap.Data); err != nil {
+					return resp, err
 				}
 			}
-			configMapLabels["app"] = clusterName // 确保 ConfigMap 的 app 标签使用原始 clusterName
-			
-			_, err := io.ConfigMapApply(model.ApplyConfigMapRequest{
-				Name:      tea.String(fmt.Sprintf(model.ConfigMapV12Name, clusterName)),
+			_, err = io.ConfigMapApply(model.ApplyConfigMapRequest{
+				Name:      tea.String(configMapName),
 				Namespace: tea.String(namespace),
-				Labels:    configMapLabels,
-				Data: map[string]string{
-					"flink-conf.yaml":     model.ToString(req.FlinkConfiguration),
-					"logback-console.xml": model.LogbackConsole,
-				},
+				Labels:    labels,
+				Data:      data,
 			})
 			if err != nil {
-				return fmt.Errorf("configmap apply error: %v", err)
+				return resp, fmt.Errorf("configmap apply error: %v", err)
 			}
 		}
 
-		// 3. 更新 service
-		// TODO: invalid: spec.ports: Required value ???
-		// _, err = io.ServiceApply(model.ApplyServiceRequest{
-		// 	Name:      tea.String(fmt.Sprintf(model.JobManagerServiceName, clusterName)),
-		// 	Namespace: tea.String(namespace),
-		// 	Labels:    req.Labels,
-		// })
-		// if err != nil {
-		// 	return fmt.Errorf("service apply error: %v", err)
-		// }
+		// 2. 更新 deployment
+		if err := applyV12Deployments(io, namespace, clusterName, req, configHash, &resp); err != nil {
+			return resp, err
+		}
+		return resp, nil
+	}
+	return resp, fmt.Errorf("cluster not found")
+}
 
-		return nil
+// applyV12Deployments 更新 JM/TM deployment，resourceVersion 冲突时重新查询
+func applyV12Deployments(io model.K8SIO, namespace, clusterName string, req model.ApplyFlinkV12ClusterRequest, configHash string, resp *model.ApplyFlinkV12ClusterResponse) error {
+	for _, component := range []string{"jobmanager", "taskmanager"} {
+		name := fmt.Sprintf(model.JobManagerDeploymentName, clusterName)
+		if component == "taskmanager" {
+			name = fmt.Sprintf(model.TaskManagerDeploymentName, clusterName)
+		}
+		var changes []model.FlinkV12Change
+		var restart bool
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			deployment, err := io.DeploymentGet(namespace, name)
+			if err != nil {
+				return err
+			}
+			changes, restart, err = req.ApplyToDeployment(deployment, component, configHash)
+			if err != nil || len(changes) == 0 {
+				return err
+			}
+			_, err = io.DeploymentUpdate(deployment)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s deployment update error: %v", component, err)
+		}
+		resp.Changes = append(resp.Changes, changes...)
+		if restart {
+			resp.Restarted = append(resp.Restarted, name)
+		}
 	}
-	return fmt.Errorf("cluster not found")
+	return nil
 }
 
 func (s *K8SService) FlinkV12ClusterDelete(k8sClusterName string, req model.DeleteFlinkClusterRequest) error {
@@ -341,6 +345,16 @@ func (s *K8SService) FlinkV12ClusterDelete(k8sClusterName string, req model.Dele
 				return fmt.Errorf("configmap delete error: %v", err)
 			}
 		}
+		err = io.ConfigMapDelete(tea.StringValue(req.NameSpace), fmt.Sprintf(model.LogConfigMapName, *req.ClusterName))
+		if err != nil {
+			if !strings.Contains(err.Error(), "not found") {
+				return fmt.Errorf("log configmap delete error: %v", err)
+			}
+		}
+		err = deleteConfigRevisions(io, tea.StringValue(req.NameSpace), *req.ClusterName, 0)
+		if err != nil {
+			return err
+		}
 		resp, err := io.ConfigMapList(model.Filter{
 			NameSpace:     tea.String("flink"),
 			LabelSelector: tea.String(fmt.Sprintf("app=%s,configmap-type=high-availability,type=flink-native-kubernetes", *req.ClusterName)),
@@ -367,6 +381,12 @@ func (s *K8SService) FlinkV12ClusterDelete(k8sClusterName string, req model.Dele
 				return fmt.Errorf("service delete error: %v", err)
 			}
 		}
+		err = io.IngressDelete(tea.StringValue(req.NameSpace), fmt.Sprintf(model.JobManagerIngressName, *req.ClusterName))
+		if err != nil {
+			if !strings.Contains(err.Error(), "not found") {
+				return fmt.Errorf("ingress delete error: %v", err)
+			}
+		}
 		err = io.PvcDelete(tea.StringValue(req.NameSpace), fmt.Sprintf(model.PvcName, *req.ClusterName))
 		if err != nil {
 			if !strings.Contains(err.Error(), "not found") {
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	v1 "k8s.io/api/core/v1"
+)
+
+// FlinkV12ClusterGetConfig 查询 v1.12 集群当前使用的 flink-conf.yaml 和日志配置
+func (s *K8SService) FlinkV12ClusterGetConfig(k8sClusterName, namespace, clusterName string) (model.FlinkV12Config, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if namespace == "" {
+			namespace = "default"
+		}
+		configMap, err := getV12ConfigMap(io, namespace, clusterName)
+		if err != nil {
+			return model.FlinkV12Config{}, err
+		}
+		return model.NewFlinkV12Config(*configMap)
+	}
+	return model.FlinkV12Config{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// FlinkV12ConfigRevisionList 历史配置，按版本号倒序
+func (s *K8SService) FlinkV12ConfigRevisionList(k8sClusterName, namespace, clusterName string) ([]model.FlinkV12ConfigRevision, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if namespace == "" {
+			namespace = "default"
+		}
+		return listConfigRevisions(io, namespace, clusterName)
+	}
+	return nil, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// FlinkV12ConfigDiff 对比两个版本，版本号为 0 表示当前配置
+func (s *K8SService) FlinkV12ConfigDiff(k8sClusterName, namespace, clusterName string, from, to int) (model.FlinkV12ConfigDiff, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if namespace == "" {
+			namespace = "default"
+		}
+		fromConfig, err := getV12ConfigRevision(io, namespace, clusterName, from)
+		if err != nil {
+			return model.FlinkV12ConfigDiff{}, err
+		}
+		toConfig, err := getV12ConfigRevision(io, namespace, clusterName, to)
+		if err != nil {
+			return model.FlinkV12ConfigDiff{}, err
+		}
+		diff := model.DiffFlinkV12Config(fromConfig, toConfig)
+		diff.From, diff.To = from, to
+		return diff, nil
+	}
+	return model.FlinkV12ConfigDiff{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// FlinkV12ConfigRollback 回滚到指定版本，当前配置会保存为新的版本，JM/TM 滚动重启
+func (s *K8SService) FlinkV12ConfigRollback(k8sClusterName, namespace, clusterName string, revision int) (model.ApplyFlinkV12ClusterResponse, error) {
+	var resp model.ApplyFlinkV12ClusterResponse
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if namespace == "" {
+			namespace = "default"
+		}
+		if revision <= model.CurrentConfigRevision {
+			return resp, fmt.Errorf("revision must be greater than 0")
+		}
+		target, err := getV12ConfigRevision(io, namespace, clusterName, revision)
+		if err != nil {
+			return resp, err
+		}
+		configMap, err := getV12ConfigMap(io, namespace, clusterName)
+		if err != nil {
+			return resp, err
+		}
+		configHash := model.ConfigHash(target.Data)
+		if configHash == model.ConfigHash(configMap.Data) {
+			return resp, nil
+		}
+		if _, err := saveConfigRevision(io, namespace, clusterName, configMap.Data); err != nil {
+			return resp, err
+		}
+		resp.Changes = append(resp.Changes, model.FlinkV12Change{
+			Resource: configMap.Name,
+			Field:    "flink-conf.yaml",
+			From:     configMap.Data["flink-conf.yaml"],
+			To:       target.Data["flink-conf.yaml"],
+		})
+		_, err = io.ConfigMapApply(model.ApplyConfigMapRequest{
+			Name:      tea.String(configMap.Name),
+			Namespace: tea.String(namespace),
+			Labels:    configMap.Labels,
+			Data:      target.Data,
+		})
+		if err != nil {
+			return resp, fmt.Errorf("configmap apply error: %v", err)
+		}
+		if err := applyV12Deployments(io, namespace, clusterName, model.ApplyFlinkV12ClusterRequest{}, configHash, &resp); err != nil {
+			return resp, err
+		}
+		return resp, nil
+	}
+	return resp, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+func getV12ConfigMap(io model.K8SIO, namespace, clusterName string) (*v1.ConfigMap, error) {
+	configMapName := fmt.Sprintf(model.ConfigMapV12Name, clusterName)
+	configMaps, err := io.ConfigMapList(model.Filter{
+		NameSpace:     tea.String(namespace),
+		FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", configMapName)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("configmap list error: %v", err)
+	}
+	if len(configMaps.Items) == 0 {
+		return nil, fmt.Errorf("configmap %s not found", configMapName)
+	}
+	return &configMaps.Items[0], nil
+}
+
+// getV12ConfigRevision 版本号为 0 时返回当前配置
+func getV12ConfigRevision(io model.K8SIO, namespace, clusterName string, revision int) (model.FlinkV12Config, error) {
+	if revision == model.CurrentConfigRevision {
+		configMap, err := getV12ConfigMap(io, namespace, clusterName)
+		if err != nil {
+			return model.FlinkV12Config{}, err
+		}
+		return model.NewFlinkV12Config(*configMap)
+	}
+	revisions, err := listConfigRevisions(io, namespace, clusterName)
+	if err != nil {
+		return model.FlinkV12Config{}, err
+	}
+	for _, item := range revisions {
+		if item.Revision == revision {
+			return item.Config, nil
+		}
+	}
+	return model.FlinkV12Config{}, fmt.Errorf("config revision %d of %s not found", revision, clusterName)
+}
+
+func listConfigRevisions(io model.K8SIO, namespace, clusterName string) ([]model.FlinkV12ConfigRevision, error) {
+	configMaps, err := io.ConfigMapList(model.Filter{
+		NameSpace:     tea.String(namespace),
+		LabelSelector: tea.String(fmt.Sprintf("%s=%s", model.ConfigRevisionOfLabel, clusterName)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list config revisions error: %v", err)
+	}
+	revisions := []model.FlinkV12ConfigRevision{}
+	for _, configMap := range configMaps.Items {
+		revision, err := model.NewFlinkV12ConfigRevision(configMap)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	model.SortConfigRevisions(revisions)
+	return revisions, nil
+}
+
+// saveConfigRevision 保存为新的版本，超过 MaxConfigRevisions 的旧版本删除
+func saveConfigRevision(io model.K8SIO, namespace, clusterName string, data map[string]string) (int, error) {
+	revisions, err := listConfigRevisions(io, namespace, clusterName)
+	if err != nil {
+		return 0, err
+	}
+	revision := 1
+	if len(revisions) > 0 {
+		revision = revisions[0].Revision + 1
+	}
+	_, err = io.ConfigMapApply(model.NewConfigRevision(namespace, clusterName, revision, data))
+	if err != nil {
+		return 0, fmt.Errorf("config revision apply error: %v", err)
+	}
+	if err := deleteConfigRevisions(io, namespace, clusterName, model.MaxConfigRevisions); err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+// deleteConfigRevisions 只保留最新的 keep 个版本，keep 为 0 时全部删除
+func deleteConfigRevisions(io model.K8SIO, namespace, clusterName string, keep int) error {
+	revisions, err := listConfigRevisions(io, namespace, clusterName)
+	if err != nil {
+		return err
+	}
+	if len(revisions) <= keep {
+		return nil
+	}
+	for _, revision := range revisions[keep:] {
+		err := io.ConfigMapDelete(namespace, revision.Config.Name)
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			return fmt.Errorf("config revision delete error: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+// v1.12 集群的 jar 和 job 管理，通过 service proxy 访问 -jobmanager-service 的 webui 端口
+
+func v12RestRequest(namespace, clusterName, jobID *string) model.FlinkRestRequest {
+	return model.FlinkRestRequest{
+		NameSpace:   namespace,
+		ClusterName: clusterName,
+		Flavor:      model.FlinkFlavorV12,
+		JobID:       jobID,
+	}
+}
+
+func (s *K8SService) FlinkV12JarUpload(k8sClusterName string, req model.FlinkV12JarUploadRequest) (model.FlinkJarUploadResponse, error) {
+	var resp model.FlinkJarUploadResponse
+	if err := req.Validate(); err != nil {
+		return resp, err
+	}
+	body, contentType, err := req.NewMultipartBody()
+	if err != nil {
+		return resp, err
+	}
+	err = s.flinkRestDo(k8sClusterName, v12RestRequest(req.NameSpace, req.ClusterName, nil), model.ServiceProxyRequest{
+		Method:      http.MethodPost,
+		Path:        "/jars/upload",
+		Body:        body,
+		ContentType: contentType,
+	}, &resp)
+	if err != nil {
+		return resp, err
+	}
+	if resp.Status != "success" {
+		return resp, fmt.Errorf("upload jar %s failed, status: %s", *req.FileName, resp.Status)
+	}
+	return resp, nil
+}
+
+func (s *K8SService) FlinkV12JarList(k8sClusterName string, req model.FlinkRestRequest) (model.FlinkJarList, error) {
+	var resp model.FlinkJarList
+	req.Flavor = model.FlinkFlavorV12
+	err := s.flinkRestGet(k8sClusterName, req, "/jars", &resp)
+	return resp, err
+}
+
+func (s *K8SService) FlinkV12JarRun(k8sClusterName string, req model.FlinkV12JarRunRequest) (model.FlinkJarRunResponse, error) {
+	var resp model.FlinkJarRunResponse
+	if err := req.Validate(); err != nil {
+		return resp, err
+	}
+	body, err := json.Marshal(req.ToBody())
+	if err != nil {
+		return resp, err
+	}
+	err = s.flinkRestDo(k8sClusterName, v12RestRequest(req.NameSpace, req.ClusterName, nil), model.ServiceProxyRequest{
+		Method:      http.MethodPost,
+		Path:        fmt.Sprintf("/jars/%s/run", *req.JarID),
+		Body:        body,
+		ContentType: "application/json",
+	}, &resp)
+	return resp, err
+}
+
+func (s *K8SService) FlinkV12JobList(k8sClusterName string, req model.FlinkRestRequest) (model.FlinkJobsOverview, error) {
+	req.Flavor = model.FlinkFlavorV12
+	return s.FlinkRestJobsOverview(k8sClusterName, req)
+}
+
+// FlinkV12JobCancel 直接取消 job，不触发 savepoint
+func (s *K8SService) FlinkV12JobCancel(k8sClusterName string, req model.FlinkRestRequest) error {
+	if req.JobID == nil || *req.JobID == "" {
+		return fmt.Errorf("job_id is required")
+	}
+	req.Flavor = model.FlinkFlavorV12
+	return s.flinkRestDo(k8sClusterName, req, model.ServiceProxyRequest{
+		Method: http.MethodPatch,
+		Path:   fmt.Sprintf("/jobs/%s", *req.JobID),
+		Params: map[string]string{"mode": "cancel"},
+	}, nil)
+}
+
+// FlinkV12JobStop 触发 stop-with-savepoint，并等待 savepoint 完成
+func (s *K8SService) FlinkV12JobStop(k8sClusterName string, req model.FlinkV12JobStopRequest) (model.FlinkV12JobStopResponse, error) {
+	resp := model.FlinkV12JobStopResponse{}
+	if err := req.Validate(); err != nil {
+		return resp, err
+	}
+	resp.JobID = *req.JobID
+	restReq := v12RestRequest(req.NameSpace, req.ClusterName, req.JobID)
+	body, err := json.Marshal(req.ToBody())
+	if err != nil {
+		return resp, err
+	}
+	var trigger model.FlinkTriggerResponse
+	err = s.flinkRestDo(k8sClusterName, restReq, model.ServiceProxyRequest{
+		Method:      http.MethodPost,
+		Path:        fmt.Sprintf("/jobs/%s/stop", *req.JobID),
+		Body:        body,
+		ContentType: "application/json",
+	}, &trigger)
+	if err != nil {
+		return resp, err
+	}
+
+	timeout := req.GetTimeout()
+	deadline := time.Now().Add(timeout)
+	for {
+		var status model.FlinkSavepointStatus
+		err := s.flinkRestGet(k8sClusterName, restReq, fmt.Sprintf("/jobs/%s/savepoints/%s", *req.JobID, trigger.RequestID), &status)
+		if err != nil {
+			return resp, err
+		}
+		if status.Status.ID == "COMPLETED" {
+			if status.Operation == nil {
+				return resp, fmt.Errorf("stop job %s failed: empty savepoint operation", *req.JobID)
+			}
+			if status.Operation.FailureCause != nil {
+				return resp, fmt.Errorf("stop job %s failed: %v", *req.JobID, status.Operation.FailureCause["stack-trace"])
+			}
+			resp.SavepointPath = status.Operation.Location
+			return resp, nil
+		}
+		if time.Now().After(deadline) {
+			return resp, fmt.Errorf("stop job %s timeout after %s, savepoint trigger id: %s", *req.JobID, timeout, trigger.RequestID)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestFlinkV12JobManage(t *testing.T) {
+	const proxy = "/api/v1/namespaces/flink/services/demo-jobmanager-service:webui/proxy"
+	var runBody, stopBody map[string]any
+	var cancelMode string
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST " + proxy + "/jars/upload":
+			file, header, err := r.FormFile("jarfile")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			assert.Equal(t, "wordcount.jar", header.Filename)
+			assert.Equal(t, "jar-content", string(data))
+			fmt.Fprint(w, `{"filename":"/tmp/flink-web-1/flink-web-upload/5f1c_wordcount.jar","status":"success"}`)
+		case "POST " + proxy + "/jars/5f1c_wordcount.jar/run":
+			json.NewDecoder(r.Body).Decode(&runBody)
+			fmt.Fprint(w, `{"jobid":"a1"}`)
+		case "PATCH " + proxy + "/jobs/a1":
+			cancelMode = r.URL.Query().Get("mode")
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, `{}`)
+		case "POST " + proxy + "/jobs/a1/stop":
+			json.NewDecoder(r.Body).Decode(&stopBody)
+			fmt.Fprint(w, `{"request-id":"t1"}`)
+		case "GET " + proxy + "/jobs/a1/savepoints/t1":
+			fmt.Fprint(w, `{"status":{"id":"COMPLETED"},"operation":{"location":"s3://bucket/savepoint-a1"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":["Not found"]}`)
+		}
+	})
+
+	upload, err := k8s.FlinkV12JarUpload("fake", model.FlinkV12JarUploadRequest{
+		NameSpace:   tea.String("flink"),
+		ClusterName: tea.String("demo"),
+		FileName:    tea.String("wordcount.jar"),
+		Jar:         []byte("jar-content"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "5f1c_wordcount.jar", upload.JarID())
+
+	run, err := k8s.FlinkV12JarRun("fake", model.FlinkV12JarRunRequest{
+		NameSpace:     tea.String("flink"),
+		ClusterName:   tea.String("demo"),
+		JarID:         tea.String(upload.JarID()),
+		EntryClass:    tea.String("org.example.WordCount"),
+		Args:          []string{"--input", "s3://bucket/in"},
+		Parallelism:   tea.Int32(2),
+		SavepointPath: tea.String("s3://bucket/savepoint-0"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "a1", run.JobID)
+	assert.Equal(t, "org.example.WordCount", runBody["entryClass"])
+	assert.Equal(t, []any{"--input", "s3://bucket/in"}, runBody["programArgsList"])
+	assert.Equal(t, float64(2), runBody["parallelism"])
+	assert.Equal(t, "s3://bucket/savepoint-0", runBody["savepointPath"])
+
+	err = k8s.FlinkV12JobCancel("fake", model.FlinkRestRequest{
+		NameSpace:   tea.String("flink"),
+		ClusterName: tea.String("demo"),
+		JobID:       tea.String("a1"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "cancel", cancelMode)
+
+	stop, err := k8s.FlinkV12JobStop("fake", model.FlinkV12JobStopRequest{
+		NameSpace:       tea.String("flink"),
+		ClusterName:     tea.String("demo"),
+		JobID:           tea.String("a1"),
+		TargetDirectory: tea.String("s3://bucket"),
+		Timeout:         tea.String("10m"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3://bucket/savepoint-a1", stop.SavepointPath)
+	assert.Equal(t, "s3://bucket", stopBody["targetDirectory"])
+	assert.Equal(t, false, stopBody["drain"])
+
+	// timeout 使用 "10m" 格式，没有单位时报错
+	_, err = k8s.FlinkV12JobStop("fake", model.FlinkV12JobStopRequest{
+		ClusterName: tea.String("demo"),
+		JobID:       tea.String("a1"),
+		Timeout:     tea.String("300"),
+	})
+	assert.ErrorContains(t, err, "timeout: time: missing unit")
+
+	_, err = k8s.FlinkV12JarUpload("fake", model.FlinkV12JarUploadRequest{
+		ClusterName: tea.String("demo"),
+		FileName:    tea.String("wordcount.zip"),
+		Jar:         []byte("jar-content"),
+	})
+	assert.True(t, strings.Contains(err.Error(), ".jar"))
+}
@@ -1,10 +1,22 @@
 package service_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
 	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // TEST FlinkV12ClusterList
@@ -64,6 +76,76 @@ func TestFlinkV12ClusterCreate(t *testing.T) {
 	t.Log("FlinkV12ClusterCreate success", tea.Prettify(resp))
 }
 
+// 并发创建多个集群，每个 deployment 只能带自己集群的 name、labels 和 containers，go test -race 运行
+func TestFlinkV12ClusterCreateParallel(t *testing.T) {
+	var mu sync.Mutex
+	created := map[string]appv1.Deployment{}
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/services":
+			fmt.Fprint(w, `{"kind":"ServiceList","apiVersion":"v1","metadata":{},"items":[]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/apis/apps/v1/namespaces/flink/deployments":
+			var dep appv1.Deployment
+			if err := json.Unmarshal(body, &dep); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			created[dep.Name] = dep
+			mu.Unlock()
+			w.Write(body)
+		case r.Method == http.MethodPost || r.Method == http.MethodPatch:
+			// server-side apply 和创建 service 原样返回
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	// client-go 默认限速 5 QPS，集群数量不宜太多
+	var wg sync.WaitGroup
+	names := []string{}
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("cluster-%d", i)
+		names = append(names, name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := k8s.FlinkV12ClusterCreate("fake", model.CreateFlinkV12ClusterRequest{
+				Name:        tea.String(name),
+				NameSpace:   tea.String("flink"),
+				Owner:       tea.String(name),
+				Env:         map[string]string{"CLUSTER": name},
+				JobManager:  &model.JobManagerV12{Resource: &model.FlinkResource{Memory: tea.String("1Gi"), CPU: tea.String("1")}},
+				TaskManager: &model.TaskManagerV12{Nu: tea.Int(2)},
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, created, 2*len(names))
+	for _, name := range names {
+		for _, component := range []string{"jobmanager", "taskmanager"} {
+			dep, ok := created[name+"-"+component]
+			if !assert.True(t, ok, name+"-"+component) {
+				continue
+			}
+			assert.Equal(t, name, dep.Labels["app"])
+			assert.Equal(t, name, dep.Labels["owner"])
+			assert.Equal(t, map[string]string{"app": name, "component": component}, dep.Spec.Selector.MatchLabels)
+			assert.Equal(t, map[string]string{"app": name, "component": component}, dep.Spec.Template.Labels)
+			assert.Len(t, dep.Spec.Template.Spec.Containers, 1)
+			assert.Equal(t, component, dep.Spec.Template.Spec.Containers[0].Name)
+			assert.Contains(t, dep.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: "CLUSTER", Value: name})
+			assert.Equal(t, name+"-configmap", dep.Spec.Template.Spec.Volumes[1].ConfigMap.Name)
+		}
+	}
+}
+
 // TEST FlinkV12ClusterDelete
 func TestFlinkV12ClusterDelete(t *testing.T) {
 	err := k8s.FlinkV12ClusterDelete("test", model.DeleteFlinkClusterRequest{
@@ -77,7 +159,7 @@ func TestFlinkV12ClusterDelete(t *testing.T) {
 }
 
 func TestFlinkV12ClusterApply(t *testing.T) {
-	err := k8s.FlinkV12ClusterApply("test", "flink", "flink-zhoushoujian", model.ApplyFlinkV12ClusterRequest{
+	_, err := k8s.FlinkV12ClusterApply("test", "flink", "flink-zhoushoujian", model.ApplyFlinkV12ClusterRequest{
 		Labels: map[string]string{"owner": "zhoushoujian"},
 		// FlinkConfiguration: map[string]any{
 		// 	"jobmanager.memory.flink.size":         "3072m",
@@ -93,3 +175,138 @@ func TestFlinkV12ClusterApply(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// fakeV12Server 保存 configmap 的 apply 结果，deployment 每次查询返回初始状态
+func fakeV12Server(t *testing.T, updates *[]appv1.Deployment) (model.K8SContract, map[string]corev1.ConfigMap) {
+	configMaps := map[string]corev1.ConfigMap{
+		"demo-configmap": {
+			ObjectMeta: metav1.ObjectMeta{Name: "demo-configmap", Namespace: "flink", Labels: map[string]string{"app": "demo"}},
+			Data:       map[string]string{"flink-conf.yaml": "parallelism.default: 1\n", "logback-console.xml": "<configuration/>"},
+		},
+	}
+	conflict := true
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/namespaces/flink/configmaps" && r.Method == http.MethodGet:
+			list := corev1.ConfigMapList{}
+			for _, name := range sortedNames(configMaps) {
+				configMap := configMaps[name]
+				query := r.URL.Query()
+				if field := query.Get("fieldSelector"); field != "" && field != "metadata.name="+name {
+					continue
+				}
+				if label := query.Get("labelSelector"); label != "" && label != model.ConfigRevisionOfLabel+"="+configMap.Labels[model.ConfigRevisionOfLabel] {
+					continue
+				}
+				list.Items = append(list.Items, configMap)
+			}
+			json.NewEncoder(w).Encode(list)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/flink/configmaps/") && r.Method == http.MethodPatch:
+			var configMap corev1.ConfigMap
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &configMap)
+			configMaps[configMap.Name] = configMap
+			w.Write(body)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/flink/configmaps/") && r.Method == http.MethodDelete:
+			delete(configMaps, path.Base(r.URL.Path))
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Success"}`)
+		case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/flink/deployments/") && r.Method == http.MethodGet:
+			component := strings.TrimPrefix(path.Base(r.URL.Path), "demo-")
+			fmt.Fprintf(w, `{"kind":"Deployment","apiVersion":"apps/v1","metadata":{"name":"demo-%s","namespace":"flink","resourceVersion":"1"},"spec":{"replicas":1,"selector":{"matchLabels":{"app":"demo"}},"template":{"metadata":{"annotations":{"%s":"old"}},"spec":{"containers":[{"name":"%s","image":"flink:1.12.7"}]}}}}`, component, model.ConfigHashAnnotation, component)
+		case strings.HasPrefix(r.URL.Path, "/apis/apps/v1/namespaces/flink/deployments/") && r.Method == http.MethodPut:
+			if conflict {
+				conflict = false
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Conflict","code":409}`)
+				return
+			}
+			var deployment appv1.Deployment
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &deployment)
+			*updates = append(*updates, deployment)
+			w.Write(body)
+		case r.Method == http.MethodDelete:
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Success"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`)
+		}
+	})
+	return k8s, configMaps
+}
+
+func sortedNames(configMaps map[string]corev1.ConfigMap) []string {
+	var names []string
+	for name := range configMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFlinkV12ClusterApplyRolling(t *testing.T) {
+	var updates []appv1.Deployment
+	k8s, configMaps := fakeV12Server(t, &updates)
+	resp, err := k8s.FlinkV12ClusterApply("fake", "flink", "demo", model.ApplyFlinkV12ClusterRequest{
+		FlinkConfiguration: map[string]any{"parallelism.default": 4},
+		TaskManager:        &model.TaskManagerV12{Nu: tea.Int(3)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"demo-jobmanager", "demo-taskmanager"}, resp.Restarted)
+	assert.Equal(t, "flink-conf.yaml", resp.Changes[0].Field)
+	assert.Len(t, updates, 2)
+	hash := updates[0].Spec.Template.Annotations[model.ConfigHashAnnotation]
+	assert.NotEqual(t, "old", hash)
+	assert.Equal(t, hash, updates[1].Spec.Template.Annotations[model.ConfigHashAnnotation])
+	assert.Equal(t, int32(3), *updates[1].Spec.Replicas)
+	// logback 保留，之前的配置保存为版本 1
+	assert.Equal(t, "<configuration/>", configMaps["demo-configmap"].Data["logback-console.xml"])
+	assert.Equal(t, "parallelism.default: 1\n", configMaps["demo-configmap-rev-1"].Data["flink-conf.yaml"])
+}
+
+func TestFlinkV12ConfigRevision(t *testing.T) {
+	var updates []appv1.Deployment
+	k8s, configMaps := fakeV12Server(t, &updates)
+	for _, parallelism := range []int{2, 3} {
+		_, err := k8s.FlinkV12ClusterApply("fake", "flink", "demo", model.ApplyFlinkV12ClusterRequest{
+			FlinkConfiguration: map[string]any{"parallelism.default": parallelism, "state.backend": "rocksdb"},
+		})
+		assert.NoError(t, err)
+	}
+
+	config, err := k8s.FlinkV12ClusterGetConfig("fake", "flink", "demo")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"parallelism.default": 3, "state.backend": "rocksdb"}, config.FlinkConfiguration)
+	assert.Equal(t, "<configuration/>", config.Logback)
+
+	revisions, err := k8s.FlinkV12ConfigRevisionList("fake", "flink", "demo")
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Revision)
+	assert.Equal(t, 2, revisions[0].Config.FlinkConfiguration["parallelism.default"])
+
+	diff, err := k8s.FlinkV12ConfigDiff("fake", "flink", "demo", 1, model.CurrentConfigRevision)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.FlinkV12ConfigChange{
+		{Key: "parallelism.default", Type: model.ConfigChanged, From: 1, To: 3},
+		{Key: "state.backend", Type: model.ConfigAdded, To: "rocksdb"},
+	}, diff.Changes)
+	assert.False(t, diff.LogbackChanged)
+
+	updates = nil
+	resp, err := k8s.FlinkV12ConfigRollback("fake", "flink", "demo", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"demo-jobmanager", "demo-taskmanager"}, resp.Restarted)
+	assert.Equal(t, "parallelism.default: 1\n", configMaps["demo-configmap"].Data["flink-conf.yaml"])
+	assert.Equal(t, model.ConfigHash(configMaps["demo-configmap"].Data), updates[0].Spec.Template.Annotations[model.ConfigHashAnnotation])
+	// 回滚之前的配置保存为版本 3
+	assert.Equal(t, "3", configMaps["demo-configmap-rev-3"].Labels[model.ConfigRevisionLabel])
+
+	_, err = k8s.FlinkV12ConfigRollback("fake", "flink", "demo", 9)
+	assert.ErrorContains(t, err, "config revision 9 of demo not found")
+
+	// 删除集群时一起删除历史版本
+	assert.NoError(t, k8s.FlinkV12ClusterDelete("fake", model.DeleteFlinkClusterRequest{ClusterName: tea.String("demo"), NameSpace: tea.String("flink")}))
+	assert.NotContains(t, configMaps, "demo-configmap-rev-1")
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	v1 "k8s.io/api/core/v1"
+)
+
+// 并发创建时 NodePort 可能被其他 service 抢占，重新分配的次数
+const nodePortRetry = 3
+
+// applyLBService 创建 LB service：
+// 指定了 port 直接使用；auto_node_port 由 k8s 分配；否则查询集群所有 service 选一个空闲的 NodePort，冲突时重新分配
+func applyLBService(io model.K8SIO, lb *model.LoadBalancerRequest, newService func(nodePort int32) model.ApplyServiceRequest) (*v1.Service, error) {
+	if lb != nil && lb.Port != nil {
+		svc, err := io.ServiceApply(newService(*lb.Port))
+		if model.IsNodePortAllocatedError(err) {
+			return nil, fmt.Errorf("loadBalancer.port %d is already allocated: %v", *lb.Port, err)
+		}
+		return svc, err
+	}
+	if lb != nil && tea.BoolValue(lb.AutoNodePort) {
+		return io.ServiceApply(newService(0))
+	}
+
+	exclude := map[int32]bool{}
+	var lastErr error
+	for i := 0; i < nodePortRetry; i++ {
+		req := newService(0)
+		// NodePort 是集群级别的，需要查询所有 namespace
+		services, err := io.ServiceList(model.Filter{NameSpace: tea.String(v1.NamespaceAll)})
+		if err != nil {
+			return nil, fmt.Errorf("list services for node port error: %v", err)
+		}
+		namespace := tea.StringValue(req.Namespace)
+		if namespace == "" {
+			namespace = v1.NamespaceDefault
+		}
+		nodePort, err := model.FreeNodePort(services.Items, namespace, tea.StringValue(req.Name), exclude)
+		if err != nil {
+			return nil, err
+		}
+		svc, err := io.ServiceApply(newService(nodePort))
+		if !model.IsNodePortAllocatedError(err) {
+			return svc, err
+		}
+		exclude[nodePort] = true
+		lastErr = err
+	}
+	return nil, fmt.Errorf("allocate node port failed after %d retries: %v", nodePortRetry, lastErr)
+}
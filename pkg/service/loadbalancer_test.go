@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestLBServiceNodePort(t *testing.T) {
+	var applied []string
+	nodePortRe := regexp.MustCompile(`"nodePort":(\d+)`)
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/apis/flink.apache.org/v1beta1/namespaces/flink/flinkdeployments":
+			body, _ := io.ReadAll(r.Body)
+			w.Write(body)
+		case r.URL.Path == "/api/v1/services":
+			// 所有 namespace 的 service，30000 已经被占用
+			fmt.Fprint(w, `{"kind":"ServiceList","apiVersion":"v1","metadata":{},"items":[{"metadata":{"name":"other","namespace":"default"},"spec":{"type":"NodePort","ports":[{"port":80,"nodePort":30000}]}}]}`)
+		case r.URL.Path == "/api/v1/namespaces/flink/services/demo-jobmanager-lb-service" && r.Method == http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			match := nodePortRe.FindStringSubmatch(string(body))
+			nodePort := "auto"
+			if match != nil {
+				nodePort = match[1]
+			}
+			applied = append(applied, nodePort)
+			if nodePort == "30001" {
+				// 创建之前被其他 service 抢占
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Invalid","code":422,"message":"Service \"demo-jobmanager-lb-service\" is invalid: spec.ports[0].nodePort: Invalid value: 30001: provided port is already allocated"}`)
+				return
+			}
+			fmt.Fprint(w, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"demo-jobmanager-lb-service","namespace":"flink"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+	newReq := func(lb *model.LoadBalancerRequest) model.CreateFlinkClusterRequest {
+		return model.CreateFlinkClusterRequest{
+			ClusterName:  tea.String("demo"),
+			Image:        tea.String("flink:1.17"),
+			Submitter:    tea.String("xops"),
+			NameSpace:    tea.String("flink"),
+			LoadBalancer: lb,
+		}
+	}
+
+	_, err := k8s.CrdFlinkDeploymentApply("fake", newReq(&model.LoadBalancerRequest{}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"30001", "30002"}, applied)
+
+	// 固定端口冲突直接返回
+	applied = nil
+	_, err = k8s.CrdFlinkDeploymentApply("fake", newReq(&model.LoadBalancerRequest{Port: tea.Int32(30001)}))
+	assert.ErrorContains(t, err, "loadBalancer.port 30001 is already allocated")
+	assert.Equal(t, []string{"30001"}, applied)
+
+	applied = nil
+	_, err = k8s.CrdFlinkDeploymentApply("fake", newReq(&model.LoadBalancerRequest{AutoNodePort: tea.Bool(true)}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"auto"}, applied)
+}
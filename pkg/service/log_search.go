@@ -0,0 +1,176 @@
+package service
+
+import (
+	"fmt"
+	goio "io"
+	"sync"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FlinkLogSearch 搜索 Flink 集群所有 JM 和 TM pod 的日志，operator 和 v1.12 集群都支持
+func (s *K8SService) FlinkLogSearch(k8sClusterName string, req model.LogSearchRequest) (model.LogSearchResponse, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := req.Validate(); err != nil {
+			return model.LogSearchResponse{}, err
+		}
+		pods, err := listSearchPods(io, req, fmt.Sprintf("app=%s,component in (jobmanager,taskmanager)", *req.Name))
+		if err != nil {
+			return model.LogSearchResponse{}, err
+		}
+		if len(pods) == 0 {
+			return model.LogSearchResponse{}, fmt.Errorf("pods of flink cluster %s not found", *req.Name)
+		}
+		return searchPodLogs(io, pods, req, func(pod *corev1.Pod) string {
+			return defaultContainer(pod, model.FlinkMainContainer, pod.Labels["component"])
+		})
+	}
+	return model.LogSearchResponse{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// CrdSparkApplicationLogSearch 搜索 SparkApplication driver 和所有 executor pod 的日志，已经删除的 executor 不包含在内
+func (s *K8SService) CrdSparkApplicationLogSearch(k8sClusterName string, req model.LogSearchRequest) (model.LogSearchResponse, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := req.Validate(); err != nil {
+			return model.LogSearchResponse{}, err
+		}
+		pods, err := listSearchPods(io, req, fmt.Sprintf("%s=%s", model.SparkAppNameLabel, *req.Name))
+		if err != nil {
+			return model.LogSearchResponse{}, err
+		}
+		if len(pods) == 0 {
+			return model.LogSearchResponse{}, fmt.Errorf("pods of spark application %s not found, it may not be submitted yet or has been deleted", *req.Name)
+		}
+		return searchPodLogs(io, pods, req, func(pod *corev1.Pod) string {
+			if pod.Labels[model.SparkRoleLabel] == "executor" {
+				return defaultContainer(pod, model.SparkExecutorContainer)
+			}
+			return defaultContainer(pod, model.SparkDriverContainer)
+		})
+	}
+	return model.LogSearchResponse{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+func listSearchPods(io model.K8SIO, req model.LogSearchRequest, labelSelector string) ([]corev1.Pod, error) {
+	namespace := "default"
+	if req.NameSpace != nil && *req.NameSpace != "" {
+		namespace = *req.NameSpace
+	}
+	resp, err := io.PodList(model.Filter{
+		NameSpace:     tea.String(namespace),
+		LabelSelector: tea.String(labelSelector),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pods := resp.Items
+	sortPods(pods)
+	return pods, nil
+}
+
+// searchPodLogs 并发读取每个 pod 的日志，单个 pod 失败不影响其他 pod
+// 超过整体超时时间时关闭还在读取的日志流，返回已经完成的 pod 的结果
+func searchPodLogs(io model.K8SIO, pods []corev1.Pod, req model.LogSearchRequest, containerOf func(*corev1.Pod) string) (model.LogSearchResponse, error) {
+	matcher, err := req.NewMatcher()
+	if err != nil {
+		return model.LogSearchResponse{}, err
+	}
+	var (
+		mu       sync.Mutex
+		timedOut bool
+		results  = map[string][]model.LogSearchLine{}
+		errs     = map[string]string{}
+		streams  = map[string]goio.ReadCloser{}
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, req.GetConcurrency())
+	)
+	for i := range pods {
+		pod := &pods[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			mu.Lock()
+			if timedOut {
+				mu.Unlock()
+				return
+			}
+			mu.Unlock()
+
+			container := containerOf(pod)
+			stream, err := io.PodLogs(pod.Namespace, pod.Name, req.PodLogOptions(container))
+			if err != nil {
+				mu.Lock()
+				if !timedOut {
+					errs[pod.Name] = err.Error()
+				}
+				mu.Unlock()
+				return
+			}
+			mu.Lock()
+			if timedOut {
+				mu.Unlock()
+				stream.Close()
+				return
+			}
+			streams[pod.Name] = stream
+			mu.Unlock()
+
+			if container == "" && len(pod.Spec.Containers) > 0 {
+				container = pod.Spec.Containers[0].Name
+			}
+			lines, err := matcher.Scan(pod.Name, container, stream)
+			stream.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if timedOut {
+				return
+			}
+			delete(streams, pod.Name)
+			results[pod.Name] = lines
+			if err != nil {
+				errs[pod.Name] = err.Error()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timeout := req.GetTimeout()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		mu.Lock()
+		timedOut = true
+		for _, stream := range streams {
+			stream.Close()
+		}
+		for i := range pods {
+			name := pods[i].Name
+			if _, ok := results[name]; !ok {
+				if _, ok := errs[name]; !ok {
+					errs[name] = fmt.Sprintf("search timeout after %s", timeout)
+				}
+			}
+		}
+		mu.Unlock()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	resp := model.LogSearchResponse{Pods: make([]string, 0, len(pods)), Errors: errs}
+	for i := range pods {
+		resp.Pods = append(resp.Pods, pods[i].Name)
+	}
+	resp.Lines, resp.Matches, resp.Truncated = model.MergeLogSearchLines(results, req.GetMaxMatches())
+	return resp, nil
+}
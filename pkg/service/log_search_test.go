@@ -0,0 +1,127 @@
+package service_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestLogSearch(t *testing.T) {
+	const pods = "/api/v1/namespaces/flink/pods"
+	var (
+		mu         sync.Mutex
+		containers = map[string]string{}
+	)
+	release := make(chan struct{})
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		pod := func(name, container string, labels string) string {
+			return fmt.Sprintf(`{"metadata":{"name":"%s","namespace":"flink","labels":{%s}},"spec":{"containers":[{"name":"%s","image":"flink"}]},"status":{"conditions":[{"type":"Ready","status":"True"}]}}`,
+				name, labels, container)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == pods:
+			w.Header().Set("Content-Type", "application/json")
+			var items []string
+			switch r.URL.Query().Get("labelSelector") {
+			case "app=wordcount,component in (jobmanager,taskmanager)":
+				items = []string{
+					pod("wordcount-jm", "flink-main-container", `"component":"jobmanager"`),
+					pod("wordcount-taskmanager-1-1", "flink-main-container", `"component":"taskmanager"`),
+					pod("wordcount-taskmanager-1-2", "flink-main-container", `"component":"taskmanager"`),
+				}
+			case "sparkoperator.k8s.io/app-name=spark-pi":
+				items = []string{
+					pod("spark-pi-driver", "spark-kubernetes-driver", `"spark-role":"driver"`),
+					pod("spark-pi-exec-1", "spark-kubernetes-executor", `"spark-role":"executor"`),
+					pod("spark-pi-exec-2", "spark-kubernetes-executor", `"spark-role":"executor"`),
+				}
+			}
+			fmt.Fprintf(w, `{"kind":"PodList","apiVersion":"v1","metadata":{},"items":[%s]}`, strings.Join(items, ","))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/log"):
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, pods+"/"), "/log")
+			mu.Lock()
+			containers[name] = r.URL.Query().Get("container")
+			mu.Unlock()
+			assert.Equal(t, "true", r.URL.Query().Get("timestamps"))
+			w.Header().Set("Content-Type", "text/plain")
+			switch name {
+			case "wordcount-jm":
+				fmt.Fprint(w, "2026-10-19T08:00:01Z job submitted\n2026-10-19T08:00:04Z Job failed: TimeoutException\n")
+			case "wordcount-taskmanager-1-1":
+				fmt.Fprint(w, "2026-10-19T08:00:02Z heartbeat\n2026-10-19T08:00:03Z java.util.concurrent.TimeoutException\n\tat Task.run\n")
+			case "wordcount-taskmanager-1-2":
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","message":"container is waiting to start","reason":"BadRequest","code":400,"metadata":{}}`)
+			case "spark-pi-driver":
+				fmt.Fprint(w, "2026-10-19T08:00:01Z ERROR driver\n")
+			case "spark-pi-exec-1":
+				fmt.Fprint(w, "2026-10-19T08:00:02Z ERROR executor\n")
+			case "spark-pi-exec-2":
+				// 一直没有输出，整体超时后关闭
+				w.(http.Flusher).Flush()
+				select {
+				case <-release:
+				case <-r.Context().Done():
+				}
+			}
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+	t.Cleanup(func() { close(release) })
+
+	resp, err := k8s.FlinkLogSearch("fake", model.LogSearchRequest{
+		NameSpace: tea.String("flink"),
+		Name:      tea.String("wordcount"),
+		Pattern:   tea.String("TimeoutException"),
+		Context:   1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"wordcount-jm", "wordcount-taskmanager-1-1", "wordcount-taskmanager-1-2"}, resp.Pods)
+	assert.Equal(t, 2, resp.Matches)
+	assert.False(t, resp.Truncated)
+	var got []string
+	for _, line := range resp.Lines {
+		got = append(got, fmt.Sprintf("%s:%d %s", line.Pod, line.LineNumber, line.Text))
+	}
+	// 按时间合并，没有时间戳的行在最后
+	assert.Equal(t, []string{
+		"wordcount-jm:1 job submitted",
+		"wordcount-taskmanager-1-1:1 heartbeat",
+		"wordcount-taskmanager-1-1:2 java.util.concurrent.TimeoutException",
+		"wordcount-jm:2 Job failed: TimeoutException",
+		"wordcount-taskmanager-1-1:3 \tat Task.run",
+	}, got)
+	assert.Contains(t, resp.Errors["wordcount-taskmanager-1-2"], "container is waiting to start")
+	assert.Equal(t, "flink-main-container", containers["wordcount-jm"])
+
+	start := time.Now()
+	resp, err = k8s.CrdSparkApplicationLogSearch("fake", model.LogSearchRequest{
+		NameSpace: tea.String("flink"),
+		Name:      tea.String("spark-pi"),
+		Pattern:   tea.String("ERROR"),
+		Timeout:   tea.String("500ms"),
+	})
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, 2, resp.Matches)
+	assert.Equal(t, "spark-pi-driver", resp.Lines[0].Pod)
+	assert.Equal(t, "spark-kubernetes-executor", resp.Lines[1].Container)
+	assert.Equal(t, map[string]string{"spark-pi-exec-2": "search timeout after 500ms"}, resp.Errors)
+	mu.Lock()
+	assert.Equal(t, "spark-kubernetes-driver", containers["spark-pi-driver"])
+	mu.Unlock()
+
+	_, err = k8s.CrdSparkApplicationLogSearch("fake", model.LogSearchRequest{NameSpace: tea.String("flink"), Name: tea.String("spark-etl"), Pattern: tea.String("ERROR")})
+	assert.ErrorContains(t, err, "pods of spark application spark-etl not found")
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// PodExecCommand 执行命令并返回 stdout、stderr 和退出码，没有指定容器时依次使用 Flink operator、v1.12（component 标签）和 Spark 的主容器
+func (s *K8SService) PodExecCommand(k8sClusterName string, req model.PodExecRequest) (model.PodExecResponse, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := req.Validate(); err != nil {
+			return model.PodExecResponse{}, err
+		}
+		namespace := "default"
+		if req.NameSpace != nil && *req.NameSpace != "" {
+			namespace = *req.NameSpace
+		}
+		container := req.Container
+		if container == nil {
+			pod, err := io.PodGet(namespace, *req.PodName)
+			if err != nil {
+				return model.PodExecResponse{}, err
+			}
+			name := defaultContainer(pod, model.FlinkMainContainer, pod.Labels["component"], model.SparkDriverContainer, model.SparkExecutorContainer)
+			if name != "" {
+				container = tea.String(name)
+			}
+		}
+
+		var stdout, stderr bytes.Buffer
+		timeout := req.GetTimeout()
+		opts := model.PodExecOptions{
+			Container: container,
+			Command:   req.Command,
+			Stdout:    &stdout,
+			Stderr:    &stderr,
+			Timeout:   &timeout,
+		}
+		if req.Stdin != nil {
+			opts.Stdin = strings.NewReader(*req.Stdin)
+		}
+		err := io.PodExec(namespace, *req.PodName, opts)
+		resp := model.PodExecResponse{Stdout: stdout.String(), Stderr: stderr.String()}
+		var exitErr utilexec.ExitError
+		if errors.As(err, &exitErr) && exitErr.Exited() {
+			resp.ExitCode = exitErr.ExitStatus()
+			return resp, nil
+		}
+		if err != nil {
+			return resp, fmt.Errorf("exec %v in pod %s error: %v", req.Command, *req.PodName, err)
+		}
+		return resp, nil
+	}
+	return model.PodExecResponse{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
@@ -0,0 +1,216 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/apimachinery/pkg/util/remotecommand"
+)
+
+// fakeExec 模拟容器中的命令，返回写入 error 流的 status
+func fakeExec(command []string, container string, stdin io.Reader, stdout, stderr io.Writer) metav1.Status {
+	switch {
+	case len(command) == 2 && command[0] == "ls":
+		fmt.Fprintf(stdout, "%s: wordcount.jar\n", container)
+	case len(command) == 1 && command[0] == "cat" && stdin != nil:
+		io.Copy(stdout, stdin)
+	default:
+		fmt.Fprintf(stderr, "%s: command not found\n", command[0])
+		return metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  remotecommand.NonZeroExitCodeReason,
+			Details: &metav1.StatusDetails{Causes: []metav1.StatusCause{{Type: remotecommand.ExitCodeCauseType, Message: "127"}}},
+		}
+	}
+	return metav1.Status{Status: metav1.StatusSuccess}
+}
+
+// serveWebSocketExec v5.channel.k8s.io，每条消息的第一个字节是流的编号
+func serveWebSocketExec(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{remotecommand.StreamProtocolV5Name}}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	query := r.URL.Query()
+	var stdin io.Reader
+	if query.Get("stdin") == "true" {
+		var buf bytes.Buffer
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil || len(data) == 0 {
+				return
+			}
+			// 255 是 v5 新增的关闭信号，客户端 stdin 读到 EOF 时发送
+			if data[0] == 255 {
+				break
+			}
+			buf.Write(data[1:])
+		}
+		stdin = &buf
+	}
+	var stdout, stderr bytes.Buffer
+	status := fakeExec(query["command"], query.Get("container"), stdin, &stdout, &stderr)
+	statusData, _ := json.Marshal(status)
+	for id, data := range [][]byte{nil, stdout.Bytes(), stderr.Bytes(), statusData} {
+		if len(data) > 0 {
+			conn.WriteMessage(websocket.BinaryMessage, append([]byte{byte(id)}, data...))
+		}
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+}
+
+// serveSPDYExec v4.channel.k8s.io，客户端为每个流创建一个 SPDY stream
+func serveSPDYExec(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	if _, err := httpstream.Handshake(r, w, []string{remotecommand.StreamProtocolV4Name}); !assert.NoError(t, err) {
+		return
+	}
+	query := r.URL.Query()
+	expected := 1
+	for _, name := range []string{"stdin", "stdout", "stderr"} {
+		if query.Get(name) == "true" {
+			expected++
+		}
+	}
+	streamCh := make(chan httpstream.Stream, expected)
+	conn := spdy.NewResponseUpgrader().UpgradeResponse(w, r, func(stream httpstream.Stream, replySent <-chan struct{}) error {
+		streamCh <- stream
+		return nil
+	})
+	if conn == nil {
+		return
+	}
+	defer conn.Close()
+	streams := map[string]httpstream.Stream{}
+	for len(streams) < expected {
+		select {
+		case stream := <-streamCh:
+			streams[stream.Headers().Get(corev1.StreamType)] = stream
+		case <-time.After(5 * time.Second):
+			t.Error("wait spdy streams timeout")
+			return
+		}
+	}
+	var stdin io.Reader
+	if s, ok := streams[corev1.StreamTypeStdin]; ok {
+		stdin = s
+	}
+	var stdout, stderr io.Writer = io.Discard, io.Discard
+	if s, ok := streams[corev1.StreamTypeStdout]; ok {
+		stdout = s
+	}
+	if s, ok := streams[corev1.StreamTypeStderr]; ok {
+		stderr = s
+	}
+	status := fakeExec(query["command"], query.Get("container"), stdin, stdout, stderr)
+	statusData, _ := json.Marshal(status)
+	streams[corev1.StreamTypeError].Write(statusData)
+	for _, stream := range streams {
+		stream.Close()
+	}
+}
+
+func TestPodExecCommand(t *testing.T) {
+	const pods = "/api/v1/namespaces/flink/pods/"
+	var transports []string
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, pods)
+		switch {
+		case strings.HasSuffix(name, "/exec"):
+			name = strings.TrimSuffix(name, "/exec")
+			switch {
+			case websocket.IsWebSocketUpgrade(r) && name == "v12-taskmanager":
+				// 模拟不支持 WebSocket 的 apiserver
+				w.WriteHeader(http.StatusBadRequest)
+			case websocket.IsWebSocketUpgrade(r):
+				transports = append(transports, "websocket")
+				serveWebSocketExec(t, w, r)
+			default:
+				transports = append(transports, "spdy")
+				serveSPDYExec(t, w, r)
+			}
+		case r.Method == http.MethodGet && (name == "wordcount-jm" || name == "v12-taskmanager"):
+			container := "flink-main-container"
+			if name == "v12-taskmanager" {
+				container = "taskmanager"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"%s","namespace":"flink","labels":{"component":"taskmanager"}},"spec":{"containers":[{"name":"fluent-bit","image":"fluent-bit"},{"name":"%s","image":"flink"}]}}`,
+				name, container)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	resp, err := k8s.PodExecCommand("fake", model.PodExecRequest{
+		NameSpace: tea.String("flink"),
+		PodName:   tea.String("wordcount-jm"),
+		Command:   []string{"ls", "/opt/flink/usrlib"},
+		Timeout:   tea.String("30s"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, model.PodExecResponse{Stdout: "flink-main-container: wordcount.jar\n"}, resp)
+
+	resp, err = k8s.PodExecCommand("fake", model.PodExecRequest{
+		NameSpace: tea.String("flink"),
+		PodName:   tea.String("wordcount-jm"),
+		Container: tea.String("fluent-bit"),
+		Command:   []string{"cat"},
+		Stdin:     tea.String("hello"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", resp.Stdout)
+
+	// 退出码非 0 时返回输出和退出码
+	resp, err = k8s.PodExecCommand("fake", model.PodExecRequest{
+		NameSpace: tea.String("flink"),
+		PodName:   tea.String("wordcount-jm"),
+		Command:   []string{"jcmd", "1", "GC.heap_info"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, model.PodExecResponse{Stderr: "jcmd: command not found\n", ExitCode: 127}, resp)
+	assert.Equal(t, []string{"websocket", "websocket", "websocket"}, transports)
+
+	// WebSocket 升级失败时回退到 SPDY，v1.12 的容器名称是 component
+	transports = nil
+	resp, err = k8s.PodExecCommand("fake", model.PodExecRequest{
+		NameSpace: tea.String("flink"),
+		PodName:   tea.String("v12-taskmanager"),
+		Command:   []string{"ls", "/opt/flink/target"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, model.PodExecResponse{Stdout: "taskmanager: wordcount.jar\n"}, resp)
+	resp, err = k8s.PodExecCommand("fake", model.PodExecRequest{
+		NameSpace: tea.String("flink"),
+		PodName:   tea.String("v12-taskmanager"),
+		Command:   []string{"cat"},
+		Stdin:     tea.String("hello spdy"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello spdy", resp.Stdout)
+	assert.Equal(t, []string{"spdy", "spdy"}, transports)
+
+	_, err = k8s.PodExecCommand("fake", model.PodExecRequest{NameSpace: tea.String("flink"), PodName: tea.String("spark-pi-driver"), Command: []string{"ls"}})
+	assert.Error(t, err)
+	_, err = k8s.PodExecCommand("fake", model.PodExecRequest{NameSpace: tea.String("flink"), PodName: tea.String("wordcount-jm")})
+	assert.ErrorContains(t, err, "command is required")
+	_, err = k8s.PodExecCommand("fake", model.PodExecRequest{NameSpace: tea.String("flink"), PodName: tea.String("wordcount-jm"), Command: []string{"ls"}, Timeout: tea.String("0s")})
+	assert.ErrorContains(t, err, "timeout must be positive")
+}
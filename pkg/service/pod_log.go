@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	goio "io"
+	"sort"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FlinkJobManagerLogs operator 和 v1.12 集群的 JM pod 都有 app=<name>,component=jobmanager 标签，HA 多副本时优先选择 Ready 的 pod
+func (s *K8SService) FlinkJobManagerLogs(k8sClusterName, namespace, clusterName string, opts model.PodLogOptions) (goio.ReadCloser, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		pod, err := findFlinkPod(io, namespace, clusterName, "jobmanager", "")
+		if err != nil {
+			return nil, err
+		}
+		return podLogs(io, pod, opts, model.FlinkMainContainer, "jobmanager")
+	}
+	return nil, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// FlinkTaskManagerLogs podName 为空时使用第一个 Ready 的 TM
+func (s *K8SService) FlinkTaskManagerLogs(k8sClusterName, namespace, clusterName, podName string, opts model.PodLogOptions) (goio.ReadCloser, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		pod, err := findFlinkPod(io, namespace, clusterName, "taskmanager", podName)
+		if err != nil {
+			return nil, err
+		}
+		return podLogs(io, pod, opts, model.FlinkMainContainer, "taskmanager")
+	}
+	return nil, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// CrdSparkApplicationDriverLogs driver pod 通过 operator 添加的 spark-role=driver 标签查询
+func (s *K8SService) CrdSparkApplicationDriverLogs(k8sClusterName, namespace, name string, opts model.PodLogOptions) (goio.ReadCloser, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if namespace == "" {
+			namespace = "default"
+		}
+		resp, err := io.PodList(model.Filter{
+			NameSpace:     tea.String(namespace),
+			LabelSelector: tea.String(fmt.Sprintf("%s=%s,%s=driver", model.SparkAppNameLabel, name, model.SparkRoleLabel)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Items) == 0 {
+			return nil, fmt.Errorf("driver pod of spark application %s not found, it may not be submitted yet or has been deleted", name)
+		}
+		pods := resp.Items
+		sortPods(pods)
+		return podLogs(io, &pods[0], opts, model.SparkDriverContainer)
+	}
+	return nil, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+func findFlinkPod(io model.K8SIO, namespace, clusterName, component, podName string) (*corev1.Pod, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+	resp, err := io.PodList(model.Filter{
+		NameSpace:     tea.String(namespace),
+		LabelSelector: tea.String(fmt.Sprintf("app=%s,component=%s", clusterName, component)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pods := resp.Items
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("%s pod of flink cluster %s not found", component, clusterName)
+	}
+	sortPods(pods)
+	if podName == "" {
+		return &pods[0], nil
+	}
+	names := make([]string, 0, len(pods))
+	for i := range pods {
+		if pods[i].Name == podName {
+			return &pods[i], nil
+		}
+		names = append(names, pods[i].Name)
+	}
+	return nil, fmt.Errorf("pod %s is not a %s of flink cluster %s, available pods: %v", podName, component, clusterName, names)
+}
+
+// sortPods Ready 的 pod 在前，相同时按名称排序
+func sortPods(pods []corev1.Pod) {
+	sort.SliceStable(pods, func(i, j int) bool {
+		ri, rj := podReady(&pods[i]), podReady(&pods[j])
+		if ri != rj {
+			return ri
+		}
+		return pods[i].Name < pods[j].Name
+	})
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podLogs 没有指定容器时按顺序使用 pod 中存在的默认容器，都不存在时由 k8s 选择（单容器 pod）
+func podLogs(io model.K8SIO, pod *corev1.Pod, opts model.PodLogOptions, defaultContainers ...string) (goio.ReadCloser, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Container == nil {
+		if name := defaultContainer(pod, defaultContainers...); name != "" {
+			opts.Container = tea.String(name)
+		}
+	}
+	return io.PodLogs(pod.Namespace, pod.Name, opts)
+}
+
+// defaultContainer pod 中存在的第一个默认容器，都不存在时为空
+func defaultContainer(pod *corev1.Pod, names ...string) string {
+	for _, name := range names {
+		if hasContainer(pod, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+func hasContainer(pod *corev1.Pod, name string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestPodLogs(t *testing.T) {
+	const pods = "/api/v1/namespaces/flink/pods"
+	var logQuery url.Values
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		pod := func(name, ready string, containers ...string) string {
+			var cs []string
+			for _, c := range containers {
+				cs = append(cs, fmt.Sprintf(`{"name":"%s","image":"flink"}`, c))
+			}
+			return fmt.Sprintf(`{"metadata":{"name":"%s","namespace":"flink"},"spec":{"containers":[%s]},"status":{"conditions":[{"type":"Ready","status":"%s"}]}}`,
+				name, strings.Join(cs, ","), ready)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == pods:
+			var items []string
+			switch r.URL.Query().Get("labelSelector") {
+			case "app=wordcount,component=jobmanager":
+				// HA 时 standby 没有 Ready
+				items = []string{pod("wordcount-aaa", "False", "flink-main-container", "fluent-bit"), pod("wordcount-bbb", "True", "flink-main-container", "fluent-bit")}
+			case "app=wordcount,component=taskmanager":
+				items = []string{pod("wordcount-taskmanager-1-2", "True", "flink-main-container"), pod("wordcount-taskmanager-1-1", "True", "flink-main-container")}
+			case "app=v12,component=taskmanager":
+				items = []string{pod("v12-taskmanager-abc", "True", "taskmanager")}
+			case "sparkoperator.k8s.io/app-name=spark-pi,spark-role=driver":
+				items = []string{pod("spark-pi-driver", "True", "spark-kubernetes-driver")}
+			}
+			fmt.Fprintf(w, `{"kind":"PodList","apiVersion":"v1","metadata":{},"items":[%s]}`, strings.Join(items, ","))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, pods+"/") && strings.HasSuffix(r.URL.Path, "/log"):
+			logQuery = r.URL.Query()
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprintf(w, "log of %s", strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, pods+"/"), "/log"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+	read := func(rc io.ReadCloser, err error) string {
+		assert.NoError(t, err)
+		if err != nil {
+			return ""
+		}
+		defer rc.Close()
+		data, _ := io.ReadAll(rc)
+		return string(data)
+	}
+
+	since := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, "log of wordcount-bbb", read(k8s.FlinkJobManagerLogs("fake", "flink", "wordcount", model.PodLogOptions{TailLines: tea.Int64(100), SinceTime: &since})))
+	assert.Equal(t, "flink-main-container", logQuery.Get("container"))
+	assert.Equal(t, "100", logQuery.Get("tailLines"))
+	assert.Equal(t, "2026-10-19T08:00:00Z", logQuery.Get("sinceTime"))
+
+	assert.Equal(t, "log of wordcount-bbb", read(k8s.FlinkJobManagerLogs("fake", "flink", "wordcount", model.PodLogOptions{Container: tea.String("fluent-bit"), Follow: true})))
+	assert.Equal(t, "fluent-bit", logQuery.Get("container"))
+	assert.Equal(t, "true", logQuery.Get("follow"))
+
+	assert.Equal(t, "log of wordcount-taskmanager-1-1", read(k8s.FlinkTaskManagerLogs("fake", "flink", "wordcount", "", model.PodLogOptions{})))
+	assert.Equal(t, "log of wordcount-taskmanager-1-2", read(k8s.FlinkTaskManagerLogs("fake", "flink", "wordcount", "wordcount-taskmanager-1-2", model.PodLogOptions{Previous: true})))
+	assert.Equal(t, "true", logQuery.Get("previous"))
+	// v1.12 集群的容器名称是 taskmanager
+	assert.Equal(t, "log of v12-taskmanager-abc", read(k8s.FlinkTaskManagerLogs("fake", "flink", "v12", "", model.PodLogOptions{})))
+	assert.Equal(t, "taskmanager", logQuery.Get("container"))
+
+	assert.Equal(t, "log of spark-pi-driver", read(k8s.CrdSparkApplicationDriverLogs("fake", "flink", "spark-pi", model.PodLogOptions{})))
+	assert.Equal(t, "spark-kubernetes-driver", logQuery.Get("container"))
+
+	_, err := k8s.FlinkTaskManagerLogs("fake", "flink", "wordcount", "wordcount-taskmanager-9-9", model.PodLogOptions{})
+	assert.ErrorContains(t, err, "available pods: [wordcount-taskmanager-1-1 wordcount-taskmanager-1-2]")
+	_, err = k8s.CrdSparkApplicationDriverLogs("fake", "flink", "spark-etl", model.PodLogOptions{})
+	assert.ErrorContains(t, err, "driver pod of spark application spark-etl not found")
+	_, err = k8s.FlinkJobManagerLogs("fake", "flink", "wordcount", model.PodLogOptions{Previous: true, Follow: true})
+	assert.ErrorContains(t, err, "previous and follow can not be set at the same time")
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+// CrdSparkApplicationRerun 复制已有任务的 spec 重新提交，新任务的 annotations 记录原任务名称、applicationId 和状态
+// reuse_name 时先删除已经结束的原任务，等待删除完成后再用相同名称创建
+func (s *K8SService) CrdSparkApplicationRerun(k8sClusterName string, req model.RerunSparkApplicationRequest) (model.CreateResponse, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := req.Validate(); err != nil {
+			return model.CreateResponse{}, err
+		}
+		original, err := s.CrdSparkApplicationGet(k8sClusterName, *req.Namespace, *req.Name)
+		if err != nil {
+			return model.CreateResponse{}, fmt.Errorf("get spark application %s error: %v", *req.Name, err)
+		}
+		yaml := req.ToYaml(original, time.Now())
+		target := yaml["metadata"].(map[string]any)["name"].(string)
+
+		if req.ReuseName {
+			status, _ := original.Status.(map[string]any)
+			if state := model.SparkApplicationState(status); !model.IsSparkTerminalState(state) {
+				return model.CreateResponse{}, fmt.Errorf("spark application %s is %s, wait for it to finish or rerun with new_name", *req.Name, state)
+			}
+			if err := io.CrdSparkApplicationDelete(*req.Namespace, *req.Name); err != nil {
+				return model.CreateResponse{}, fmt.Errorf("delete spark application %s error: %v", *req.Name, err)
+			}
+			if err := waitSparkApplicationDeleted(io, *req.Namespace, *req.Name, req.GetTimeout()); err != nil {
+				return model.CreateResponse{}, err
+			}
+		}
+
+		resp, err := io.CrdSparkApplicationApply(yaml)
+		if err != nil {
+			if req.ReuseName {
+				return model.CreateResponse{}, fmt.Errorf("spark application %s has been deleted, resubmit error: %v", *req.Name, err)
+			}
+			return model.CreateResponse{}, err
+		}
+		return model.CreateResponse{
+			Result: resp,
+			Info:   fmt.Sprintf("rerun spark application %s as %s success!", *req.Name, target),
+		}, nil
+	}
+	return model.CreateResponse{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+func waitSparkApplicationDeleted(io model.K8SIO, namespace, name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := io.CrdSparkApplicationList(model.Filter{
+			NameSpace:     tea.String(namespace),
+			FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", name)),
+		})
+		if err != nil {
+			return fmt.Errorf("get spark application %s error: %v", name, err)
+		}
+		if len(resp.Items) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("wait spark application %s deleted timeout after %s", name, timeout)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestCrdSparkApplicationRerun(t *testing.T) {
+	const apps = "/apis/sparkoperator.k8s.io/v1beta2/namespaces/spark/sparkapplications"
+	state, deleted := "RUNNING", false
+	var created string
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == apps:
+			items := ""
+			if !deleted {
+				items = fmt.Sprintf(`{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplication","metadata":{"name":"spark-etl","namespace":"spark","resourceVersion":"7"},
+					"spec":{"image":"spark:3.5.0","arguments":["--date","2026-10-01"],"executor":{"instances":2}},
+					"status":{"sparkApplicationId":"spark-123","applicationState":{"state":"%s"}}}`, state)
+			}
+			fmt.Fprintf(w, `{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplicationList","metadata":{},"items":[%s]}`, items)
+		case r.Method == http.MethodDelete && r.URL.Path == apps+"/spark-etl":
+			deleted = true
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Success","metadata":{}}`)
+		case r.Method == http.MethodPost && r.URL.Path == apps:
+			body, _ := io.ReadAll(r.Body)
+			created = string(body)
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	// 生成新名称，原任务运行中也可以复制
+	_, err := k8s.CrdSparkApplicationRerun("fake", model.RerunSparkApplicationRequest{
+		Namespace: tea.String("spark"),
+		Name:      tea.String("spark-etl"),
+		Image:     tea.String("spark:3.5.1"),
+	})
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	assert.Regexp(t, `"name":"spark-etl-rerun-\d+"`, created)
+	assert.Contains(t, created, `"image":"spark:3.5.1"`)
+	assert.Contains(t, created, `"multi-k8s-client/rerun-of":"spark-etl"`)
+	assert.Contains(t, created, `"multi-k8s-client/rerun-of-state":"RUNNING"`)
+	assert.NotContains(t, created, `"resourceVersion"`)
+
+	// 相同名称需要原任务已经结束
+	req := model.RerunSparkApplicationRequest{Namespace: tea.String("spark"), Name: tea.String("spark-etl"), ReuseName: true}
+	_, err = k8s.CrdSparkApplicationRerun("fake", req)
+	assert.ErrorContains(t, err, "spark application spark-etl is RUNNING")
+
+	state = "FAILED"
+	_, err = k8s.CrdSparkApplicationRerun("fake", req)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	assert.Contains(t, created, `"name":"spark-etl"`)
+	assert.Contains(t, created, `"multi-k8s-client/rerun-of-application-id":"spark-123"`)
+
+	_, err = k8s.CrdSparkApplicationRerun("fake", req)
+	assert.ErrorContains(t, err, "spark application not found")
+}
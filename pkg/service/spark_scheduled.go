@@ -0,0 +1,108 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// CrdScheduledSparkApplicationList 同时查询定时任务创建的 SparkApplication，补充最后一次运行的状态
+func (s *K8SService) CrdScheduledSparkApplicationList(k8sClusterName string, filter model.Filter) (model.CrdScheduledSparkApplicationGetResponse, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		resp, err := io.CrdScheduledSparkApplicationList(filter)
+		if err != nil {
+			return model.CrdScheduledSparkApplicationGetResponse{}, err
+		}
+		runStates := map[string]string{}
+		// 作为补充信息，查询失败时忽略
+		if runs, err := io.CrdSparkApplicationList(model.Filter{NameSpace: filter.NameSpace, LabelSelector: tea.String(model.ScheduledSparkAppNameLabel)}); err == nil {
+			for _, run := range runs.Items {
+				runStates[run.GetNamespace()+"/"+run.GetName()] = model.NewCrdSparkApplication(run).Status
+			}
+		}
+		items := []model.CrdScheduledSparkApplication{}
+		for _, item := range resp.Items {
+			app := model.NewCrdScheduledSparkApplication(item)
+			app.LastRunState = runStates[app.Namespace+"/"+app.LastRunName]
+			items = append(items, app)
+		}
+		return model.CrdScheduledSparkApplicationGetResponse{
+			Total: len(items),
+			Items: items,
+		}, nil
+	}
+	return model.CrdScheduledSparkApplicationGetResponse{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// CrdScheduledSparkApplicationGet 返回定时任务和 SparkApplication 模板
+func (s *K8SService) CrdScheduledSparkApplicationGet(k8sClusterName, namespace, name string) (model.CrdScheduledSparkApplication, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		item, err := io.CrdScheduledSparkApplicationGet(namespace, name)
+		if err != nil {
+			return model.CrdScheduledSparkApplication{}, err
+		}
+		app := model.NewCrdScheduledSparkApplication(*item)
+		app.Template, _, _ = unstructured.NestedMap(item.Object, "spec", "template")
+		if app.LastRunName != "" {
+			if runs, err := io.CrdSparkApplicationList(model.Filter{
+				NameSpace:     tea.String(item.GetNamespace()),
+				FieldSelector: tea.String(fmt.Sprintf("metadata.name=%s", app.LastRunName)),
+			}); err == nil && len(runs.Items) == 1 {
+				app.LastRunState = model.NewCrdSparkApplication(runs.Items[0]).Status
+			}
+		}
+		return app, nil
+	}
+	return model.CrdScheduledSparkApplication{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// CrdScheduledSparkApplicationApply 不存在时创建，存在时更新调度配置和模板，已经创建的 SparkApplication 不受影响
+func (s *K8SService) CrdScheduledSparkApplicationApply(k8sClusterName string, req model.CreateScheduledSparkApplicationRequest) (model.CreateResponse, error) {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		if err := req.Validate(); err != nil {
+			return model.CreateResponse{}, err
+		}
+		if err := checkBatchScheduler(io, req.BatchScheduler); err != nil {
+			return model.CreateResponse{}, err
+		}
+		resp, err := io.CrdScheduledSparkApplicationApply(req.ToYaml())
+		if err != nil {
+			return model.CreateResponse{}, err
+		}
+		return model.CreateResponse{
+			Result: resp,
+			Info:   fmt.Sprintf("apply ScheduledSparkApplication %s success!", *req.Name),
+		}, nil
+	}
+	return model.CreateResponse{}, fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// CrdScheduledSparkApplicationDelete 删除定时任务，operator 会级联删除它创建的 SparkApplication
+func (s *K8SService) CrdScheduledSparkApplicationDelete(k8sClusterName string, req model.DeleteSparkApplicationRequest) error {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		return io.CrdScheduledSparkApplicationDelete(tea.StringValue(req.Namespace), tea.StringValue(req.Name))
+	}
+	return fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
+
+// CrdScheduledSparkApplicationSuspend 暂停调度，正在运行的任务不受影响
+func (s *K8SService) CrdScheduledSparkApplicationSuspend(k8sClusterName, namespace, name string) error {
+	return s.setScheduledSparkSuspend(k8sClusterName, namespace, name, true)
+}
+
+// CrdScheduledSparkApplicationResume 恢复调度，从下一个调度时间开始运行
+func (s *K8SService) CrdScheduledSparkApplicationResume(k8sClusterName, namespace, name string) error {
+	return s.setScheduledSparkSuspend(k8sClusterName, namespace, name, false)
+}
+
+func (s *K8SService) setScheduledSparkSuspend(k8sClusterName, namespace, name string, suspend bool) error {
+	if io, ok := s.IOs[k8sClusterName]; ok {
+		_, err := io.CrdScheduledSparkApplicationPatch(namespace, name, map[string]any{
+			"spec": map[string]any{"suspend": suspend},
+		})
+		return err
+	}
+	return fmt.Errorf("cluster %s not found, available cluster: %v", k8sClusterName, tea.Prettify(s.GetK8SCluster()))
+}
@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/xops-infra/multi-k8s-client/pkg/model"
+)
+
+func TestCrdScheduledSparkApplication(t *testing.T) {
+	const scheduled = "/apis/sparkoperator.k8s.io/v1beta2/namespaces/spark/scheduledsparkapplications"
+	exists, suspended := false, false
+	requests := map[string]string{}
+	k8s := newFakeK8S(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		requests[r.Method+" "+r.URL.Path] = string(body)
+		item := fmt.Sprintf(`{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"ScheduledSparkApplication","metadata":{"name":"nightly","namespace":"spark","resourceVersion":"1","labels":{"team":"data"}},
+			"spec":{"schedule":"0 2 * * *","concurrencyPolicy":"Forbid","suspend":%t,"template":{"type":"Scala"}},
+			"status":{"scheduleState":"Scheduled","lastRun":"2026-10-01T02:00:00Z","nextRun":"2026-10-02T02:00:00Z","lastRunName":"nightly-1"}}`, suspended)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == scheduled:
+			fmt.Fprintf(w, `{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"ScheduledSparkApplicationList","metadata":{},"items":[%s]}`, item)
+		case r.Method == http.MethodGet && r.URL.Path == scheduled+"/nightly" && exists:
+			fmt.Fprint(w, item)
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/sparkoperator.k8s.io/v1beta2/namespaces/spark/sparkapplications":
+			assert.Contains(t, []string{model.ScheduledSparkAppNameLabel, ""}, r.URL.Query().Get("labelSelector"))
+			fmt.Fprint(w, `{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplicationList","metadata":{},"items":[
+				{"apiVersion":"sparkoperator.k8s.io/v1beta2","kind":"SparkApplication","metadata":{"name":"nightly-1","namespace":"spark"},"status":{"applicationState":{"state":"COMPLETED"}}}]}`)
+		case r.Method == http.MethodPost && r.URL.Path == scheduled,
+			r.Method == http.MethodPut && r.URL.Path == scheduled+"/nightly":
+			w.Write(body)
+		case r.Method == http.MethodPatch && r.URL.Path == scheduled+"/nightly":
+			suspended = strings.Contains(string(body), `"suspend":true`)
+			fmt.Fprint(w, item)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404,"metadata":{}}`)
+		}
+	})
+
+	list, err := k8s.CrdScheduledSparkApplicationList("fake", model.Filter{NameSpace: tea.String("spark")})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "nightly-1", list.Items[0].LastRunName)
+	assert.Equal(t, model.SparkStateCompleted, list.Items[0].LastRunState)
+	assert.Equal(t, "2026-10-02T02:00:00Z", list.Items[0].NextRun.Format("2006-01-02T15:04:05Z07:00"))
+
+	req := model.CreateScheduledSparkApplicationRequest{
+		CreateSparkApplicationRequest: model.CreateSparkApplicationRequest{Name: tea.String("nightly"), Namespace: tea.String("spark")},
+		Schedule:                      tea.String("0 3 * * *"),
+	}
+	_, err = k8s.CrdScheduledSparkApplicationApply("fake", req)
+	assert.NoError(t, err)
+	assert.Contains(t, requests["POST "+scheduled], `"schedule":"0 3 * * *"`)
+
+	// 已经存在时更新 spec，保留 labels 和 resourceVersion
+	exists = true
+	_, err = k8s.CrdScheduledSparkApplicationApply("fake", req)
+	assert.NoError(t, err)
+	assert.Contains(t, requests["PUT "+scheduled+"/nightly"], `"schedule":"0 3 * * *"`)
+	assert.Contains(t, requests["PUT "+scheduled+"/nightly"], `"resourceVersion":"1"`)
+	assert.Contains(t, requests["PUT "+scheduled+"/nightly"], `"team":"data"`)
+
+	app, err := k8s.CrdScheduledSparkApplicationGet("fake", "spark", "nightly")
+	assert.NoError(t, err)
+	assert.Equal(t, "Scala", app.Template["type"])
+	assert.Equal(t, model.SparkStateCompleted, app.LastRunState)
+
+	assert.NoError(t, k8s.CrdScheduledSparkApplicationSuspend("fake", "spark", "nightly"))
+	assert.JSONEq(t, `{"spec":{"suspend":true}}`, requests["PATCH "+scheduled+"/nightly"])
+	// 暂停后更新模板仍然保持暂停
+	_, err = k8s.CrdScheduledSparkApplicationApply("fake", req)
+	assert.NoError(t, err)
+	assert.Contains(t, requests["PUT "+scheduled+"/nightly"], `"suspend":true`)
+	assert.NoError(t, k8s.CrdScheduledSparkApplicationResume("fake", "spark", "nightly"))
+	assert.JSONEq(t, `{"spec":{"suspend":false}}`, requests["PATCH "+scheduled+"/nightly"])
+
+	_, err = k8s.CrdScheduledSparkApplicationApply("fake", model.CreateScheduledSparkApplicationRequest{CreateSparkApplicationRequest: req.CreateSparkApplicationRequest})
+	assert.ErrorContains(t, err, "schedule is required")
+}